@@ -0,0 +1,210 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// AlertTarget selects the value of a Sample an AlertRule watches.
+type AlertTarget struct {
+	kind int
+	cpu  string
+	pid  int32
+}
+
+const (
+	alertTotal = iota
+	alertCPU
+	alertProcess
+)
+
+// AlertTotal watches the busy percent of the whole machine.
+func AlertTotal() AlertTarget {
+	return AlertTarget{kind: alertTotal}
+}
+
+// AlertCPU watches the busy percent of the cpu named name in Sample.PerCPU, e.g. "cpu3".
+func AlertCPU(name string) AlertTarget {
+	return AlertTarget{kind: alertCPU, cpu: name}
+}
+
+// AlertProcess watches the cpu usage of pid, it must be sampled with WithProcesses.
+func AlertProcess(pid int32) AlertTarget {
+	return AlertTarget{kind: alertProcess, pid: pid}
+}
+
+func (t AlertTarget) value(s Sample) (float64, bool) {
+	switch t.kind {
+	case alertCPU:
+		return s.CPU(t.cpu)
+	case alertProcess:
+		v, ok := s.Processes[t.pid]
+		return v, ok
+	}
+	return s.Total, true
+}
+
+func (t AlertTarget) String() string {
+	switch t.kind {
+	case alertCPU:
+		return t.cpu
+	case alertProcess:
+		return fmt.Sprintf("pid %d", t.pid)
+	}
+	return "total"
+}
+
+// AlertRule raises an alert when the value of Target stays above Raise for Sustain, and
+// clears it when the value then stays below Clear for Sustain. Clear must not be above Raise,
+// the gap between them keeps the alert from flapping. With Below the comparisons are
+// reversed, to alert on a value that stays too low, and Clear must not be below Raise.
+type AlertRule struct {
+	Name    string
+	Target  AlertTarget
+	Raise   float64
+	Clear   float64
+	Sustain time.Duration
+	Below   bool
+	OnRaise func(AlertEvent)
+	OnClear func(AlertEvent)
+}
+
+// AlertEvent is passed to the callbacks of an AlertRule. Since is when the value first
+// crossed the threshold, Value is the value of the sample that triggered the callback.
+type AlertEvent struct {
+	Rule   string    `json:"rule"`
+	Target string    `json:"target"`
+	Raised bool      `json:"raised"`
+	Value  float64   `json:"value"`
+	Since  time.Time `json:"since"`
+	Time   time.Time `json:"time"`
+}
+
+type alertState struct {
+	rule    AlertRule
+	raised  bool
+	pending time.Time
+}
+
+// Alerter evaluates AlertRules against the samples of a Monitor or Watch.
+type Alerter struct {
+	mu    sync.Mutex
+	rules []*alertState
+}
+
+// NewAlerter checks the rules and returns an Alerter for them.
+func NewAlerter(rules ...AlertRule) (*Alerter, error) {
+	a := &Alerter{}
+	for _, r := range rules {
+		if r.Sustain < 0 {
+			return nil, fmt.Errorf("rule %q: sustain must not be negative", r.Name)
+		}
+		if (!r.Below && r.Clear > r.Raise) || (r.Below && r.Clear < r.Raise) {
+			return nil, fmt.Errorf("rule %q: clear threshold is beyond the raise threshold", r.Name)
+		}
+		a.rules = append(a.rules, &alertState{rule: r})
+	}
+	if len(a.rules) == 0 {
+		return nil, errors.New("no alert rule")
+	}
+	return a, nil
+}
+
+// Observe evaluates the rules against s and runs the callbacks of the alerts it raises or
+// clears. Samples must be observed in order.
+func (a *Alerter) Observe(s Sample) {
+	var fire []func()
+	a.mu.Lock()
+	for _, st := range a.rules {
+		if ev, ok := st.observe(s); ok {
+			cb := st.rule.OnClear
+			if ev.Raised {
+				cb = st.rule.OnRaise
+			}
+			if cb != nil {
+				fire = append(fire, func() { cb(ev) })
+			}
+		}
+	}
+	a.mu.Unlock()
+
+	for _, f := range fire {
+		f()
+	}
+}
+
+// Run observes the samples of ch until it is closed or ctx is done.
+func (a *Alerter) Run(ctx context.Context, ch <-chan Sample) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case s, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			a.Observe(s)
+		}
+	}
+}
+
+// Active returns the names of the rules whose alert is raised.
+func (a *Alerter) Active() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var ret []string
+	for _, st := range a.rules {
+		if st.raised {
+			ret = append(ret, st.rule.Name)
+		}
+	}
+	return ret
+}
+
+// observe updates the state of the rule, ok is true when the alert was raised or cleared.
+func (st *alertState) observe(s Sample) (ev AlertEvent, ok bool) {
+	v, found := st.rule.Target.value(s)
+	if !found {
+		// the process exited or the cpu went offline, the value is unknown
+		st.pending = time.Time{}
+		return ev, false
+	}
+
+	crossed := st.beyond(v, st.rule.Raise)
+	if st.raised {
+		crossed = !st.beyond(v, st.rule.Clear) && v != st.rule.Clear
+	}
+	if !crossed {
+		st.pending = time.Time{}
+		return ev, false
+	}
+	if st.pending.IsZero() {
+		st.pending = s.Time
+	}
+	if s.Time.Sub(st.pending) < st.rule.Sustain {
+		return ev, false
+	}
+
+	st.raised = !st.raised
+	ev = AlertEvent{
+		Rule:   st.rule.Name,
+		Target: st.rule.Target.String(),
+		Raised: st.raised,
+		Value:  v,
+		Since:  st.pending,
+		Time:   s.Time,
+	}
+	st.pending = time.Time{}
+	return ev, true
+}
+
+// beyond tells whether v is past threshold in the direction of the rule.
+func (st *alertState) beyond(v, threshold float64) bool {
+	if st.rule.Below {
+		return v < threshold
+	}
+	return v > threshold
+}
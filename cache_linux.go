@@ -0,0 +1,121 @@
+package cpuproc
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CacheStat describes a cache seen by a cpu. Size and LineSize are in bytes,
+// ID is -1 on kernels that do not report it.
+// It is based on linux /sys/devices/system/cpu/cpu*/cache/index*.
+type CacheStat struct {
+	CPU        int    `json:"cpu"`
+	ID         int    `json:"id"`
+	Level      int    `json:"level"`
+	Type       string `json:"type"`
+	Size       uint64 `json:"size"`
+	LineSize   int    `json:"lineSize"`
+	Ways       int    `json:"ways"`
+	SharedCPUs []int  `json:"sharedCPUs"`
+}
+
+func Caches() ([]CacheStat, error) {
+	return CachesWithContext(context.Background())
+}
+
+// CachesWithContext returns the caches of every cpu, sorted by cpu and level.
+func CachesWithContext(ctx context.Context) ([]CacheStat, error) {
+	dirs, err := filepath.Glob(HostSysWithContext(ctx, "devices/system/cpu/cpu[0-9]*/cache/index[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]CacheStat, 0, len(dirs))
+	for _, dir := range dirs {
+		cpu, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(filepath.Dir(filepath.Dir(dir))), "cpu"))
+		if err != nil {
+			continue
+		}
+
+		c := CacheStat{CPU: cpu, ID: -1}
+		if c.Level, err = readInt(filepath.Join(dir, "level")); err != nil {
+			return nil, err
+		}
+		if c.Type, err = readTrimmed(filepath.Join(dir, "type")); err != nil {
+			return nil, err
+		}
+		size, err := readTrimmed(filepath.Join(dir, "size"))
+		if err != nil {
+			return nil, err
+		}
+		if c.Size, err = parseCacheSize(size); err != nil {
+			return nil, err
+		}
+		if c.SharedCPUs, err = readCPUListFile(filepath.Join(dir, "shared_cpu_list")); err != nil {
+			return nil, err
+		}
+		if id, err := readInt(filepath.Join(dir, "id")); err == nil {
+			c.ID = id
+		}
+		c.LineSize, _ = readInt(filepath.Join(dir, "coherency_line_size"))
+		c.Ways, _ = readInt(filepath.Join(dir, "ways_of_associativity"))
+		ret = append(ret, c)
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].CPU != ret[j].CPU {
+			return ret[i].CPU < ret[j].CPU
+		}
+		return ret[i].Level < ret[j].Level
+	})
+	return ret, nil
+}
+
+func CacheDomains(level int) ([][]int, error) {
+	return CacheDomainsWithContext(context.Background(), level)
+}
+
+// CacheDomainsWithContext returns the groups of cpus sharing a data or unified cache of level,
+// e.g. level 3 gives the L3 domains that per-cpu utilisation can be grouped by.
+func CacheDomainsWithContext(ctx context.Context, level int) ([][]int, error) {
+	caches, err := CachesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret [][]int
+	for _, c := range caches {
+		// shared_cpu_list is empty for the caches of offline cpus
+		if c.Level != level || c.Type == "Instruction" || len(c.SharedCPUs) == 0 {
+			continue
+		}
+		if !slices.ContainsFunc(ret, func(d []int) bool { return slices.Equal(d, c.SharedCPUs) }) {
+			ret = append(ret, c.SharedCPUs)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i][0] < ret[j][0] })
+	return ret, nil
+}
+
+// parseCacheSize parses the sysfs cache size, e.g. "32K" or "36M".
+func parseCacheSize(s string) (uint64, error) {
+	mult := uint64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		mult = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		mult = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		mult = 1 << 30
+	}
+	v, err := strconv.ParseUint(strings.TrimRight(s, "KMG"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("wrong cache size format: %q", s)
+	}
+	return v * mult, nil
+}
@@ -0,0 +1,35 @@
+package cpuproc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_CacheDomains(t *testing.T) {
+	files := map[string]string{}
+	cache := func(cpu, index, level, typ, size, shared string) {
+		dir := "sys/devices/system/cpu/cpu" + cpu + "/cache/index" + index + "/"
+		files[dir+"level"] = level + "\n"
+		files[dir+"type"] = typ + "\n"
+		files[dir+"size"] = size + "\n"
+		files[dir+"shared_cpu_list"] = shared + "\n"
+	}
+	cache("0", "0", "1", "Instruction", "32K", "0")
+	cache("0", "1", "3", "Unified", "16M", "0-1")
+	cache("1", "0", "3", "Unified", "16M", "0-1")
+	cache("2", "0", "3", "Unified", "16M", "2")
+	// offline cpu
+	cache("3", "0", "3", "Unified", "16M", "")
+	ctx := newTestContext(t, files)
+
+	domains, err := CacheDomainsWithContext(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]int{{0, 1}, {2}}; !reflect.DeepEqual(domains, want) {
+		t.Errorf("got %v, want %v", domains, want)
+	}
+	if domains, err := CacheDomainsWithContext(ctx, 1); err != nil || len(domains) != 0 {
+		t.Errorf("level 1: got %v, %v, want no data cache", domains, err)
+	}
+}
@@ -0,0 +1,341 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cgroup is a cgroup of either hierarchy. On cgroup v2 it is a single directory, e.g.
+// /sys/fs/cgroup/system.slice/nginx.service, on cgroup v1 it is the same path under every
+// controller hierarchy, e.g. /sys/fs/cgroup/cpu,cpuacct/docker/<id>.
+type Cgroup struct {
+	version int
+	path    string
+	// v1 only, the directory of every controller
+	dirs map[string]string
+}
+
+// CgroupCPUStat contains the cpu usage of a cgroup since it was created, in microseconds.
+// It is based on the cpu.stat file of cgroup v2, or cpuacct.usage, cpuacct.stat and
+// cpu.stat of cgroup v1.
+type CgroupCPUStat struct {
+	Usage     uint64 `json:"usage"`
+	User      uint64 `json:"user"`
+	System    uint64 `json:"system"`
+	Throttled uint64 `json:"throttled"`
+}
+
+// ErrNotSupported is returned for statistics the cgroup hierarchy does not provide.
+var ErrNotSupported = errors.New("not supported by this cgroup version")
+
+// the cgroup v1 controllers cpuproc reads from
+var cgroupV1Controllers = []string{"cpu", "cpuacct", "cpuset", "memory"}
+
+// NewCgroup returns the cgroup of path, which is either a directory in the cgroup v2
+// hierarchy or in any cgroup v1 controller hierarchy, or a cgroup path as found in
+// /proc/<pid>/cgroup, e.g. "/system.slice/nginx.service".
+func NewCgroup(path string) *Cgroup {
+	return newCgroup(context.Background(), path)
+}
+
+func newCgroup(ctx context.Context, path string) *Cgroup {
+	root := HostSysWithContext(ctx, "fs/cgroup")
+	if !underDir(root, path) {
+		path = resolveCgroupPath(ctx, path)
+	}
+	if PathExists(filepath.Join(path, "cgroup.controllers")) {
+		return &Cgroup{version: 2, path: path}
+	}
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return &Cgroup{version: 2, path: path}
+	}
+	// strip the hierarchy, e.g. "cpu,cpuacct/docker/<id>" -> "docker/<id>"
+	hierarchy, cgroupPath, _ := strings.Cut(rel, string(filepath.Separator))
+	if hierarchy == "unified" {
+		return &Cgroup{version: 2, path: path}
+	}
+
+	c := &Cgroup{version: 1, path: path, dirs: make(map[string]string)}
+	for _, controller := range cgroupV1Controllers {
+		if dir := cgroupV1Root(ctx, controller); dir != "" {
+			c.dirs[controller] = filepath.Join(dir, cgroupPath)
+		}
+	}
+	return c
+}
+
+// underDir tells whether path is dir or under it. Only paths under the cgroup mount are
+// directories, any other path is a cgroup path even when it exists on the host, e.g. "/" or
+// "/system.slice" in a chroot.
+func underDir(dir, path string) bool {
+	if !filepath.IsAbs(path) {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resolveCgroupPath turns a cgroup path into a directory, the cpu controller hierarchy is
+// preferred on hybrid systems as it is where the cpu statistics are.
+func resolveCgroupPath(ctx context.Context, path string) string {
+	if root := cgroupV1Root(ctx, "cpu"); root != "" {
+		if dir := filepath.Join(root, path); PathExists(dir) {
+			return dir
+		}
+	}
+	return filepath.Join(cgroup2Root(ctx), path)
+}
+
+// SelfCgroup returns the cgroup of the current process.
+func SelfCgroup() (*Cgroup, error) {
+	return SelfCgroupWithContext(context.Background())
+}
+
+func SelfCgroupWithContext(ctx context.Context) (*Cgroup, error) {
+	return pidCgroup(ctx, "self")
+}
+
+// pidCgroup resolves the cgroup of pid from /proc/<pid>/cgroup. On hybrid systems the
+// cpu controller lives in cgroup v1, which is then preferred over the unified hierarchy.
+func pidCgroup(ctx context.Context, pid string) (*Cgroup, error) {
+	paths, err := readProcCgroup(ctx, pid)
+	if err != nil {
+		return nil, err
+	}
+	if mode, err := DetectCgroupModeWithContext(ctx); err == nil && mode == CgroupModeUnified {
+		return pidCgroupV2(ctx, paths)
+	}
+
+	c := &Cgroup{version: 1, dirs: make(map[string]string)}
+	for controllers, path := range paths {
+		for _, controller := range strings.Split(controllers, ",") {
+			if !slices.Contains(cgroupV1Controllers, controller) {
+				continue
+			}
+			root := cgroupV1Root(ctx, controller)
+			if root == "" {
+				continue
+			}
+			dir := filepath.Join(root, path)
+			if !PathExists(dir) {
+				// in a cgroup namespace the path may not match the mount, the namespace root is mounted instead
+				dir = root
+			}
+			c.dirs[controller] = dir
+		}
+	}
+	if dir, ok := c.dirs["cpu"]; ok {
+		c.path = dir
+		return c, nil
+	}
+	if dir, ok := c.dirs["cpuacct"]; ok {
+		c.path = dir
+		return c, nil
+	}
+	return pidCgroupV2(ctx, paths)
+}
+
+func pidCgroupV2(ctx context.Context, paths map[string]string) (*Cgroup, error) {
+	path, ok := paths[""]
+	if !ok {
+		return nil, errors.New("no cgroup with a cpu controller found")
+	}
+	root := cgroup2Root(ctx)
+	dir := filepath.Join(root, path)
+	if !PathExists(dir) {
+		dir = root
+	}
+	return &Cgroup{version: 2, path: dir}, nil
+}
+
+// readProcCgroup parses /proc/<pid>/cgroup into cgroup paths keyed by controller list,
+// e.g. "cpu,cpuacct" for cgroup v1. The cgroup v2 hierarchy has the empty key.
+func readProcCgroup(ctx context.Context, pid string) (map[string]string, error) {
+	lines, err := ReadLines(HostProcWithContext(ctx, pid, "cgroup"))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]string, len(lines))
+	for _, line := range lines {
+		// hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		ret[parts[1]] = parts[2]
+	}
+	return ret, nil
+}
+
+// cgroup2Root returns the mount point of the cgroup v2 hierarchy, hybrid
+// systems mount it at /sys/fs/cgroup/unified.
+func cgroup2Root(ctx context.Context) string {
+	root := HostSysWithContext(ctx, "fs/cgroup")
+	if !PathExists(filepath.Join(root, "cgroup.controllers")) && PathExists(filepath.Join(root, "unified", "cgroup.controllers")) {
+		return filepath.Join(root, "unified")
+	}
+	return root
+}
+
+// cgroupV1Root returns the mount point of the cgroup v1 hierarchy of controller,
+// which may be shared with other controllers, e.g. /sys/fs/cgroup/cpu,cpuacct.
+func cgroupV1Root(ctx context.Context, controller string) string {
+	root := HostSysWithContext(ctx, "fs/cgroup")
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return ""
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			// skip the cpu -> cpu,cpuacct symlinks, the real directory is listed too
+			continue
+		}
+		if slices.Contains(strings.Split(e.Name(), ","), controller) {
+			return filepath.Join(root, e.Name())
+		}
+	}
+	return ""
+}
+
+// Version returns 1 or 2 depending on the cgroup hierarchy.
+func (c *Cgroup) Version() int {
+	return c.version
+}
+
+// file returns the path of a cgroup file, controller selects the cgroup v1 hierarchy.
+func (c *Cgroup) file(controller, name string) string {
+	if c.version == 1 {
+		if dir, ok := c.dirs[controller]; ok {
+			return filepath.Join(dir, name)
+		}
+	}
+	return filepath.Join(c.path, name)
+}
+
+// Path returns the directory of the cgroup.
+func (c *Cgroup) Path() string {
+	return c.path
+}
+
+func (c *Cgroup) CPUStat() (*CgroupCPUStat, error) {
+	return c.CPUStatWithContext(context.Background())
+}
+
+func (c *Cgroup) CPUStatWithContext(ctx context.Context) (*CgroupCPUStat, error) {
+	if c.version == 1 {
+		return c.cpuStatV1()
+	}
+
+	values, err := readKeyValues(filepath.Join(c.path, "cpu.stat"))
+	if err != nil {
+		return nil, err
+	}
+	usage, ok := values["usage_usec"]
+	if !ok {
+		return nil, fmt.Errorf("usage_usec not found in %s", filepath.Join(c.path, "cpu.stat"))
+	}
+	return &CgroupCPUStat{
+		Usage:     usage,
+		User:      values["user_usec"],
+		System:    values["system_usec"],
+		Throttled: values["throttled_usec"],
+	}, nil
+}
+
+func (c *Cgroup) cpuStatV1() (*CgroupCPUStat, error) {
+	usage, err := readUint(c.file("cpuacct", "cpuacct.usage"))
+	if err != nil {
+		return nil, err
+	}
+	ret := &CgroupCPUStat{Usage: usage / 1000}
+
+	// user and system are in USER_HZ ticks
+	if values, err := readKeyValues(c.file("cpuacct", "cpuacct.stat")); err == nil {
+		ret.User = values["user"] * 1e6 / uint64(clockTicks)
+		ret.System = values["system"] * 1e6 / uint64(clockTicks)
+	}
+	if values, err := readKeyValues(c.file("cpu", "cpu.stat")); err == nil {
+		ret.Throttled = values["throttled_time"] / 1000
+	}
+	return ret, nil
+}
+
+func (c *Cgroup) PerCPUUsage() ([]uint64, error) {
+	return c.PerCPUUsageWithContext(context.Background())
+}
+
+// PerCPUUsageWithContext returns the usage of the cgroup on every cpu in microseconds.
+// It is based on cpuacct.usage_percpu, cgroup v2 does not account per cpu.
+func (c *Cgroup) PerCPUUsageWithContext(ctx context.Context) ([]uint64, error) {
+	if c.version != 1 {
+		return nil, ErrNotSupported
+	}
+
+	line, err := readTrimmed(c.file("cpuacct", "cpuacct.usage_percpu"))
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(line)
+	ret := make([]uint64, len(fields))
+	for i, f := range fields {
+		ns, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = ns / 1000
+	}
+	return ret, nil
+}
+
+func (c *Cgroup) PercentWithContext(ctx context.Context, interval time.Duration) (float64, error) {
+	s1, err := c.CPUStatWithContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+
+	if err := Sleep(ctx, interval); err != nil {
+		return 0, err
+	}
+
+	s2, err := c.CPUStatWithContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	elapsed := time.Since(start).Seconds()
+
+	// usage is in microseconds, 100 means one cpu was fully used
+	return counterRate(s1.Usage, s2.Usage, elapsed) / 1e6 * 100, nil
+}
+
+// readKeyValues parses files made of "key value" lines such as cpu.stat and memory.stat.
+func readKeyValues(filename string) (map[string]uint64, error) {
+	lines, err := ReadLines(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]uint64, len(lines))
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		ret[fields[0]] = v
+	}
+	return ret, nil
+}
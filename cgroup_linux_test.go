@@ -0,0 +1,251 @@
+package cpuproc
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_SelfCgroup_v2(t *testing.T) {
+	ctx := newTestContext(t, map[string]string{
+		"proc/self/cgroup":                                "0::/system.slice/app.service\n",
+		"sys/fs/cgroup/cgroup.controllers":                "cpuset cpu io memory pids\n",
+		"sys/fs/cgroup/system.slice/app.service/cpu.stat": "usage_usec 1500\nuser_usec 1000\nsystem_usec 500\nnr_periods 0\n",
+	})
+
+	c, err := SelfCgroupWithContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !filepath.IsAbs(c.Path()) || filepath.Base(c.Path()) != "app.service" {
+		t.Errorf("unexpected path %s", c.Path())
+	}
+
+	stat, err := c.CPUStatWithContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (CgroupCPUStat{Usage: 1500, User: 1000, System: 500}); *stat != want {
+		t.Errorf("got %+v, want %+v", *stat, want)
+	}
+}
+
+func Test_SelfCgroup_v1(t *testing.T) {
+	ctx := newTestContext(t, map[string]string{
+		"proc/self/cgroup": "4:memory:/docker/abc\n3:cpu,cpuacct:/docker/abc\n1:name=systemd:/docker/abc\n0::/\n",
+		"sys/fs/cgroup/cpu,cpuacct/docker/abc/cpuacct.usage":        "3000000\n",
+		"sys/fs/cgroup/cpu,cpuacct/docker/abc/cpuacct.stat":         "user 200\nsystem 100\n",
+		"sys/fs/cgroup/cpu,cpuacct/docker/abc/cpuacct.usage_percpu": "1000000 2000000\n",
+		"sys/fs/cgroup/cpu,cpuacct/docker/abc/cpu.stat":             "nr_periods 10\nnr_throttled 2\nthrottled_time 5000\n",
+	})
+
+	c, err := SelfCgroupWithContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Version() != 1 {
+		t.Fatalf("version = %d, want 1", c.Version())
+	}
+
+	stat, err := c.CPUStatWithContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (CgroupCPUStat{Usage: 3000, User: 2000000, System: 1000000, Throttled: 5}); *stat != want {
+		t.Errorf("got %+v, want %+v", *stat, want)
+	}
+
+	perCPU, err := c.PerCPUUsageWithContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(perCPU) != 2 || perCPU[0] != 1000 || perCPU[1] != 2000 {
+		t.Errorf("unexpected per cpu usage %v", perCPU)
+	}
+}
+
+func Test_parseContainerCgroup(t *testing.T) {
+	id := strings.Repeat("ab12", 16)
+	tests := []struct {
+		path    string
+		runtime string
+		id      string
+		ok      bool
+	}{
+		{"/docker/" + id, ContainerRuntimeDocker, id, true},
+		{"/system.slice/docker-" + id + ".scope", ContainerRuntimeDocker, id, true},
+		{"/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod1.slice/cri-containerd-" + id + ".scope", ContainerRuntimeContainerd, id, true},
+		{"/kubepods.slice/kubepods-pod1.slice/crio-" + id + ".scope", ContainerRuntimeCRIO, id, true},
+		{"/kubepods.slice/kubepods-pod1.slice/crio-conmon-" + id + ".scope", "", "", false},
+		{"/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-" + id + ".scope/container", ContainerRuntimePodman, id, true},
+		{"/kubepods/besteffort/pod1/" + id, "", id, true},
+		{"/system.slice/sshd.service", "", "", false},
+		{"/", "", "", false},
+	}
+	for _, tt := range tests {
+		runtime, got, ok := parseContainerCgroup(tt.path)
+		if runtime != tt.runtime || got != tt.id || ok != tt.ok {
+			t.Errorf("parseContainerCgroup(%q) = %q, %q, %v, want %q, %q, %v", tt.path, runtime, got, ok, tt.runtime, tt.id, tt.ok)
+		}
+	}
+}
+
+func Test_KubePods(t *testing.T) {
+	id := strings.Repeat("ab12", 16)
+	ctx := newTestContext(t, map[string]string{
+		"sys/fs/cgroup/cgroup.controllers":                                                                                                 "cpuset cpu io memory pids\n",
+		"sys/fs/cgroup/kubepods.slice/cgroup.controllers":                                                                                  "cpuset cpu io memory pids\n",
+		"sys/fs/cgroup/kubepods.slice/kubepods-pod11_22.slice/cpu.stat":                                                                    "usage_usec 100\nnr_periods 4\nnr_throttled 1\nthrottled_usec 7\n",
+		"sys/fs/cgroup/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod33_44.slice/cpu.stat":                                 "usage_usec 200\n",
+		"sys/fs/cgroup/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod33_44.slice/cri-containerd-" + id + ".scope/cpu.stat": "usage_usec 150\n",
+		"sys/fs/cgroup/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod55_66.slice/cpu.stat":                               "usage_usec 300\n",
+		"etc/podinfo/uid":       "33-44\n",
+		"etc/podinfo/name":      "agent\n",
+		"etc/podinfo/namespace": "monitoring\n",
+	})
+
+	pods, err := KubePodsWithContext(ctx, WithDownwardAPI(HostEtcWithContext(ctx, "podinfo")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 3 {
+		t.Fatalf("got %d pods, want 3", len(pods))
+	}
+	byUID := make(map[string]KubePodStat)
+	for _, p := range pods {
+		byUID[p.UID] = p
+	}
+	if p := byUID["11-22"]; p.QoS != QoSGuaranteed || p.CPU.Usage != 100 || p.Throttling == nil || p.Throttling.ThrottledTime != 7 {
+		t.Errorf("unexpected guaranteed pod %+v", p)
+	}
+	if p := byUID["33-44"]; p.QoS != QoSBurstable || p.Name != "agent" || p.Namespace != "monitoring" || len(p.Containers) != 1 || p.Containers[0] != id {
+		t.Errorf("unexpected burstable pod %+v", p)
+	}
+	if usage := KubeQoSUsage(pods); usage[QoSBestEffort].Usage != 300 {
+		t.Errorf("unexpected besteffort usage %+v", usage)
+	}
+}
+
+func Test_SystemdUnits(t *testing.T) {
+	ctx := newTestContext(t, map[string]string{
+		"sys/fs/cgroup/cgroup.controllers":                                                             "cpu\n",
+		"sys/fs/cgroup/system.slice/cgroup.controllers":                                                "cpu\n",
+		"sys/fs/cgroup/system.slice/nginx.service/cpu.stat":                                            "usage_usec 100\n",
+		"sys/fs/cgroup/user.slice/cgroup.controllers":                                                  "cpu\n",
+		"sys/fs/cgroup/user.slice/user-1000.slice/session-2.scope/cpu.stat":                            "usage_usec 200\n",
+		"sys/fs/cgroup/user.slice/user-1000.slice/user@1000.service/cpu.stat":                          "usage_usec 300\n",
+		"sys/fs/cgroup/user.slice/user-1000.slice/user@1000.service/app.slice/editor.service/cpu.stat": "usage_usec 50\n",
+		"proc/42/cgroup": "0::/user.slice/user-1000.slice/user@1000.service/app.slice/editor.service\n",
+	})
+
+	units, err := SystemdUnitsWithContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage := make(map[string]uint64)
+	for _, u := range units {
+		usage[u.Unit] = u.CPU.Usage
+	}
+	want := map[string]uint64{"nginx.service": 100, "session-2.scope": 200, "user@1000.service": 300, "editor.service": 50}
+	if !reflect.DeepEqual(usage, want) {
+		t.Errorf("got %v, want %v", usage, want)
+	}
+
+	unit, err := UnitOfWithContext(ctx, 42)
+	if err != nil || unit != "editor.service" {
+		t.Errorf("UnitOf = %q, %v", unit, err)
+	}
+}
+
+func Test_CgroupWrite_v2(t *testing.T) {
+	ctx := newTestContext(t, map[string]string{
+		"sys/fs/cgroup/cgroup.controllers":         "cpuset cpu\n",
+		"sys/fs/cgroup/app/cgroup.controllers":     "cpuset cpu\n",
+		"sys/fs/cgroup/app/cgroup.procs":           "",
+		"sys/fs/cgroup/app/cpu.max":                "max 100000\n",
+		"sys/fs/cgroup/app/cpuset.cpus":            "\n",
+		"sys/fs/cgroup/app/cgroup.subtree_control": "",
+	})
+	c := newCgroup(ctx, HostSysWithContext(ctx, "fs/cgroup/app"))
+
+	if err := c.SetCPULimit(CgroupCPULimit{Quota: 50000, Period: 100000}); err != nil {
+		t.Fatal(err)
+	}
+	limit, err := c.CPULimitWithContext(ctx)
+	if err != nil || limit.CPUs() != 0.5 {
+		t.Errorf("got %+v, %v, want 0.5 cpus", limit, err)
+	}
+	if err := c.SetCpuset([]int{3, 0, 1, 2, 6}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := readTrimmed(filepath.Join(c.Path(), "cpuset.cpus")); got != "0-3,6" {
+		t.Errorf("cpuset.cpus = %q, want 0-3,6", got)
+	}
+	if err := c.AddProcess(42); err != nil {
+		t.Fatal(err)
+	}
+
+	child, err := c.NewChild("job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := readTrimmed(filepath.Join(c.Path(), "cgroup.subtree_control")); got != "+cpu +cpuset" {
+		t.Errorf("subtree_control = %q", got)
+	}
+	if err := child.Remove(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the controllers can't be enabled, e.g. the cgroup is not delegated
+	if err := os.Remove(filepath.Join(c.Path(), "cgroup.subtree_control")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.NewChild("job"); err == nil {
+		t.Error("NewChild succeeded without cgroup.subtree_control")
+	}
+}
+
+func Test_effectiveCPUs(t *testing.T) {
+	ctx := newTestContext(t, map[string]string{
+		"proc/self/cgroup":                      "0::/self.slice\n",
+		"proc/42/cgroup":                        "0::/other.slice\n",
+		"sys/fs/cgroup/cgroup.controllers":      "cpuset cpu\n",
+		"sys/fs/cgroup/self.slice/cpu.max":      "100000 100000\n",
+		"sys/fs/cgroup/other.slice/cpu.max":     "250000 100000\n",
+		"sys/fs/cgroup/other.slice/cpuset.cpus": "0-5\n",
+		"sys/fs/cgroup/self.slice/cpuset.cpus":  "0-1\n",
+	})
+	if got := effectiveCPUs(ctx, "self", 8); got != 1 {
+		t.Errorf("self: got %v, want 1", got)
+	}
+	// the cgroup of the target, not the caller's, caps its cpus
+	if got := effectiveCPUs(ctx, "42", 8); got != 2.5 {
+		t.Errorf("pid 42: got %v, want 2.5", got)
+	}
+}
+
+func Test_NewCgroup(t *testing.T) {
+	ctx := newTestContext(t, map[string]string{
+		"sys/fs/cgroup/cgroup.controllers":              "cpuset cpu io memory pids\n",
+		"sys/fs/cgroup/system.slice/cgroup.controllers": "cpu\n",
+	})
+	root := HostSysWithContext(ctx, "fs/cgroup")
+
+	for _, tc := range []struct {
+		path string
+		want string
+	}{
+		// "/" exists on the host but is the root cgroup
+		{"/", root},
+		{"/system.slice", filepath.Join(root, "system.slice")},
+		{filepath.Join(root, "system.slice"), filepath.Join(root, "system.slice")},
+		{root, root},
+	} {
+		c := newCgroup(ctx, tc.path)
+		if c.Path() != tc.want || c.Version() != 2 {
+			t.Errorf("newCgroup(%q) = %s v%d, want %s v2", tc.path, c.Path(), c.Version(), tc.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package cpuproc
+
+import (
+	"context"
+	"strconv"
+)
+
+// CgroupMemoryStat contains the memory usage of a cgroup in bytes. Limit is 0 when the cgroup
+// is not limited, UsedPercent is then relative to the host memory. WorkingSet is the usage
+// without the inactive page cache, the figure the OOM killer and kubelet care about.
+// It is based on memory.current, memory.max and memory.stat of cgroup v2 or
+// memory.usage_in_bytes, memory.limit_in_bytes and memory.stat of cgroup v1.
+type CgroupMemoryStat struct {
+	Usage       uint64            `json:"usage"`
+	Limit       uint64            `json:"limit"`
+	WorkingSet  uint64            `json:"workingSet"`
+	UsedPercent float64           `json:"usedPercent"`
+	Stat        map[string]uint64 `json:"stat"`
+}
+
+// cgroup v1 reports no limit as a huge page aligned number close to math.MaxInt64
+const cgroupV1Unlimited = 1 << 62
+
+func (c *Cgroup) Memory() (*CgroupMemoryStat, error) {
+	return c.MemoryWithContext(context.Background())
+}
+
+func (c *Cgroup) MemoryWithContext(ctx context.Context) (*CgroupMemoryStat, error) {
+	usageFile, limitFile, inactiveKey := "memory.current", "memory.max", "inactive_file"
+	if c.version == 1 {
+		usageFile, limitFile, inactiveKey = "memory.usage_in_bytes", "memory.limit_in_bytes", "total_inactive_file"
+	}
+
+	usage, err := readUint(c.file("memory", usageFile))
+	if err != nil {
+		return nil, err
+	}
+	ret := &CgroupMemoryStat{Usage: usage}
+
+	limit, err := readTrimmed(c.file("memory", limitFile))
+	if err != nil {
+		return nil, err
+	}
+	if limit != "max" {
+		v, err := strconv.ParseUint(limit, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if v < cgroupV1Unlimited {
+			ret.Limit = v
+		}
+	}
+
+	if ret.Stat, err = readKeyValues(c.file("memory", "memory.stat")); err != nil {
+		return nil, err
+	}
+	ret.WorkingSet = ret.Usage
+	if inactive := ret.Stat[inactiveKey]; inactive < ret.WorkingSet {
+		ret.WorkingSet -= inactive
+	} else {
+		ret.WorkingSet = 0
+	}
+
+	total := ret.Limit
+	if total == 0 {
+		if mem, err := MemoryWithContext(ctx); err == nil {
+			total = mem.Total
+		}
+	}
+	if total > 0 {
+		ret.UsedPercent = float64(ret.WorkingSet) / float64(total) * 100
+	}
+	return ret, nil
+}
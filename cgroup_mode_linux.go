@@ -0,0 +1,63 @@
+package cpuproc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// CgroupMode is how the cgroup hierarchies are mounted on the host.
+type CgroupMode string
+
+const (
+	// CgroupModeLegacy only mounts cgroup v1 hierarchies.
+	CgroupModeLegacy CgroupMode = "legacy"
+	// CgroupModeHybrid mounts the cgroup v1 controllers and an empty cgroup v2 hierarchy at
+	// /sys/fs/cgroup/unified, cpu statistics are then in cgroup v1.
+	CgroupModeHybrid CgroupMode = "hybrid"
+	// CgroupModeUnified only mounts cgroup v2 at /sys/fs/cgroup.
+	CgroupModeUnified CgroupMode = "unified"
+)
+
+// the inode of the initial cgroup namespace, PROC_CGROUP_INIT_INO in the kernel
+const cgroupInitNamespace = 0xEFFFFFFB
+
+func DetectCgroupMode() (CgroupMode, error) {
+	return DetectCgroupModeWithContext(context.Background())
+}
+
+// DetectCgroupModeWithContext tells whether the host uses cgroup v1, v2 or both,
+// as systemd calls it legacy, unified or hybrid.
+func DetectCgroupModeWithContext(ctx context.Context) (CgroupMode, error) {
+	root := HostSysWithContext(ctx, "fs/cgroup")
+	if PathExists(filepath.Join(root, "cgroup.controllers")) {
+		return CgroupModeUnified, nil
+	}
+	v1 := cgroupV1Root(ctx, "cpu") != "" || cgroupV1Root(ctx, "cpuacct") != ""
+	switch {
+	case v1 && PathExists(filepath.Join(root, "unified", "cgroup.controllers")):
+		return CgroupModeHybrid, nil
+	case v1:
+		return CgroupModeLegacy, nil
+	}
+	return "", fmt.Errorf("no cgroup hierarchy found in %s", root)
+}
+
+func InCgroupNamespace() (bool, error) {
+	return InCgroupNamespaceWithContext(context.Background())
+}
+
+// InCgroupNamespaceWithContext tells whether the current process runs in a cgroup namespace
+// other than the initial one, in which case /proc/self/cgroup is relative to the namespace root.
+func InCgroupNamespaceWithContext(ctx context.Context) (bool, error) {
+	link, err := os.Readlink(HostProcWithContext(ctx, "self/ns/cgroup"))
+	if err != nil {
+		return false, err
+	}
+	var ino uint64
+	if _, err := fmt.Sscanf(link, "cgroup:[%d]", &ino); err != nil {
+		return false, fmt.Errorf("wrong cgroup namespace link %q", link)
+	}
+	return ino != cgroupInitNamespace, nil
+}
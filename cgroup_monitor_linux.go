@@ -0,0 +1,100 @@
+package cpuproc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Times returns the cpu time used by the cgroup, in seconds.
+func (c *Cgroup) Times() (*TimesStat, error) {
+	return c.TimesWithContext(context.Background())
+}
+
+func (c *Cgroup) TimesWithContext(ctx context.Context) (*TimesStat, error) {
+	s, err := c.CPUStatWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &TimesStat{
+		CPU:    "cgroup",
+		User:   float64(s.User) / 1e6,
+		System: float64(s.System) / 1e6,
+	}, nil
+}
+
+// Percent returns the cpu usage of the cgroup over interval, 100 means one cpu was fully used.
+func (c *Cgroup) Percent(interval time.Duration) (float64, error) {
+	return c.PercentWithContext(context.Background(), interval)
+}
+
+// Name returns the last element of the cgroup path, e.g. "nginx.service".
+func (c *Cgroup) Name() string {
+	return filepath.Base(c.path)
+}
+
+// Parent returns the parent cgroup.
+func (c *Cgroup) Parent() *Cgroup {
+	parent := &Cgroup{version: c.version, path: filepath.Dir(c.path)}
+	if c.dirs != nil {
+		parent.dirs = make(map[string]string, len(c.dirs))
+		for controller, dir := range c.dirs {
+			parent.dirs[controller] = filepath.Dir(dir)
+		}
+	}
+	return parent
+}
+
+func (c *Cgroup) Children() ([]*Cgroup, error) {
+	return c.ChildrenWithContext(context.Background())
+}
+
+// ChildrenWithContext returns the direct sub-cgroups, e.g. the services of system.slice.
+// Siblings of a cgroup are the children of its Parent.
+func (c *Cgroup) ChildrenWithContext(ctx context.Context) ([]*Cgroup, error) {
+	entries, err := os.ReadDir(c.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*Cgroup
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		child := &Cgroup{version: c.version, path: filepath.Join(c.path, e.Name())}
+		if c.dirs != nil {
+			child.dirs = make(map[string]string, len(c.dirs))
+			for controller, dir := range c.dirs {
+				child.dirs[controller] = filepath.Join(dir, e.Name())
+			}
+		}
+		ret = append(ret, child)
+	}
+	return ret, nil
+}
+
+func (c *Cgroup) Pids() ([]int32, error) {
+	return c.PidsWithContext(context.Background())
+}
+
+// PidsWithContext returns the processes of the cgroup, not those of its sub-cgroups.
+// On cgroup v1 they are read from the cpu hierarchy.
+func (c *Cgroup) PidsWithContext(ctx context.Context) ([]int32, error) {
+	lines, err := ReadLines(c.file("cpu", "cgroup.procs"))
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]int32, 0, len(lines))
+	for _, line := range lines {
+		pid, err := strconv.ParseInt(strings.TrimSpace(line), 10, 32)
+		if err != nil {
+			continue
+		}
+		ret = append(ret, int32(pid))
+	}
+	return ret, nil
+}
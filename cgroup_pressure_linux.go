@@ -0,0 +1,26 @@
+package cpuproc
+
+import (
+	"context"
+)
+
+func (c *Cgroup) Pressure(resource string) (*PressureStat, error) {
+	return c.PressureWithContext(context.Background(), resource)
+}
+
+// PressureWithContext returns the pressure stall information of the cgroup for resource,
+// one of PressureCPU, PressureMemory or PressureIO. cgroup v1 only provides it in the
+// cpuacct hierarchy when booted with psi_v1=1.
+func (c *Cgroup) PressureWithContext(ctx context.Context, resource string) (*PressureStat, error) {
+	return ReadPressure(c.pressureFile(resource))
+}
+
+// WatchPressure registers a PSI trigger on the cgroup, see WatchPressureFile.
+// The channel is also closed when the cgroup is removed.
+func (c *Cgroup) WatchPressure(ctx context.Context, resource string, trigger PressureTrigger) (<-chan PressureStat, error) {
+	return WatchPressureFile(ctx, c.pressureFile(resource), trigger)
+}
+
+func (c *Cgroup) pressureFile(resource string) string {
+	return c.file("cpuacct", resource+".pressure")
+}
@@ -0,0 +1,175 @@
+package cpuproc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// CgroupCPULimit is the CFS bandwidth limit of a cgroup in microseconds: the cgroup may run
+// Quota every Period. Quota is -1 when the cgroup is not limited.
+// It is based on cpu.max of cgroup v2 or cpu.cfs_quota_us and cpu.cfs_period_us of cgroup v1.
+type CgroupCPULimit struct {
+	Quota  int64  `json:"quota"`
+	Period uint64 `json:"period"`
+}
+
+// CPUs returns the limit as a number of cpus, e.g. 1.5, or 0 when the cgroup is not limited.
+func (l CgroupCPULimit) CPUs() float64 {
+	if l.Quota <= 0 || l.Period == 0 {
+		return 0
+	}
+	return float64(l.Quota) / float64(l.Period)
+}
+
+func (c *Cgroup) CPULimit() (*CgroupCPULimit, error) {
+	return c.CPULimitWithContext(context.Background())
+}
+
+func (c *Cgroup) CPULimitWithContext(ctx context.Context) (*CgroupCPULimit, error) {
+	if c.version == 1 {
+		quota, err := readTrimmed(c.file("cpu", "cpu.cfs_quota_us"))
+		if err != nil {
+			return nil, err
+		}
+		period, err := readUint(c.file("cpu", "cpu.cfs_period_us"))
+		if err != nil {
+			return nil, err
+		}
+		q, err := strconv.ParseInt(quota, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return &CgroupCPULimit{Quota: q, Period: period}, nil
+	}
+
+	line, err := readTrimmed(c.file("cpu", "cpu.max"))
+	if err != nil {
+		return nil, err
+	}
+	// "$MAX $PERIOD", $MAX is "max" when not limited
+	fields := strings.Fields(line)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("wrong cpu.max format: %q", line)
+	}
+	period, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	if fields[0] == "max" {
+		return &CgroupCPULimit{Quota: -1, Period: period}, nil
+	}
+	quota, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &CgroupCPULimit{Quota: quota, Period: period}, nil
+}
+
+func (c *Cgroup) QuotaPercent(interval time.Duration) (float64, error) {
+	return c.QuotaPercentWithContext(context.Background(), interval)
+}
+
+// QuotaPercentWithContext returns the usage of the cgroup over interval relative to its cpu limit,
+// 100 means the quota is fully consumed. Cgroups without a limit are relative to the online cpus.
+func (c *Cgroup) QuotaPercentWithContext(ctx context.Context, interval time.Duration) (float64, error) {
+	limit, err := c.CPULimitWithContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	cpus := limit.CPUs()
+	if cpus == 0 {
+		cpus = float64(onlineCPUCount(ctx))
+	}
+
+	percent, err := c.PercentWithContext(ctx, interval)
+	if err != nil {
+		return 0, err
+	}
+	return percent / cpus, nil
+}
+
+func ContainerPercent(interval time.Duration) (float64, error) {
+	return ContainerPercentWithContext(context.Background(), interval)
+}
+
+// ContainerPercentWithContext returns the usage of the cgroup of the current process relative
+// to its cpu limit, see (*Cgroup).QuotaPercentWithContext.
+func ContainerPercentWithContext(ctx context.Context, interval time.Duration) (float64, error) {
+	c, err := SelfCgroupWithContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return c.QuotaPercentWithContext(ctx, interval)
+}
+
+func (c *Cgroup) Cpuset() ([]int, error) {
+	return c.CpusetWithContext(context.Background())
+}
+
+// CpusetWithContext returns the ids of the cpus the cgroup may run on, from cpuset.cpus.effective
+// of cgroup v2 or cpuset.effective_cpus and cpuset.cpus of cgroup v1.
+func (c *Cgroup) CpusetWithContext(ctx context.Context) ([]int, error) {
+	names := []string{"cpuset.cpus.effective", "cpuset.cpus"}
+	if c.version == 1 {
+		names = []string{"cpuset.effective_cpus", "cpuset.cpus"}
+	}
+
+	var err error
+	for _, name := range names {
+		var cpus []int
+		if cpus, err = readCPUListFile(c.file("cpuset", name)); err == nil && len(cpus) > 0 {
+			return cpus, nil
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("empty cpuset in %s", c.path)
+	}
+	return nil, err
+}
+
+// EffectiveCPUs returns how many cpus the current process can actually use: the smallest of
+// its affinity set, the cpuset of its cgroup and the cgroup cpu quota. It can be fractional,
+// e.g. 1.5 for a container limited to 150ms every 100ms.
+func EffectiveCPUs() (float64, error) {
+	return EffectiveCPUsWithContext(context.Background())
+}
+
+func EffectiveCPUsWithContext(ctx context.Context) (float64, error) {
+	var set unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &set); err != nil {
+		return 0, err
+	}
+	return effectiveCPUs(ctx, "self", set.Count()), nil
+}
+
+// effectiveCPUs caps the number of cpus of the affinity set of pid by the cgroup of pid,
+// pid is "self" for the current process.
+func effectiveCPUs(ctx context.Context, pid string, affinity int) float64 {
+	ret := float64(affinity)
+
+	c, err := pidCgroup(ctx, pid)
+	if err != nil {
+		return ret
+	}
+	if cpus, err := c.CpusetWithContext(ctx); err == nil {
+		ret = math.Min(ret, float64(len(cpus)))
+	}
+	if limit, err := c.CPULimitWithContext(ctx); err == nil && limit.CPUs() > 0 {
+		ret = math.Min(ret, limit.CPUs())
+	}
+	return ret
+}
+
+func onlineCPUCount(ctx context.Context) int {
+	if cpus, err := OnlineCPUsWithContext(ctx); err == nil && len(cpus) > 0 {
+		return len(cpus)
+	}
+	return runtime.NumCPU()
+}
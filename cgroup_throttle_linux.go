@@ -0,0 +1,121 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// CgroupThrottlingStat contains the CFS bandwidth throttling of a cgroup since it was created.
+// ThrottledTime is in microseconds. It is based on the cpu.stat file of the cgroup.
+type CgroupThrottlingStat struct {
+	NrPeriods     uint64 `json:"nrPeriods"`
+	NrThrottled   uint64 `json:"nrThrottled"`
+	ThrottledTime uint64 `json:"throttledTime"`
+}
+
+// CgroupThrottlingRateStat contains the throttling of a cgroup over an interval. Periods and
+// Throttled are per second, ThrottledFraction is the part of the periods that were throttled
+// (0 to 1) and ThrottledTime the seconds spent throttled per second.
+type CgroupThrottlingRateStat struct {
+	Periods           float64 `json:"periods"`
+	Throttled         float64 `json:"throttled"`
+	ThrottledFraction float64 `json:"throttledFraction"`
+	ThrottledTime     float64 `json:"throttledTime"`
+}
+
+func (c *Cgroup) Throttling() (*CgroupThrottlingStat, error) {
+	return c.ThrottlingWithContext(context.Background())
+}
+
+func (c *Cgroup) ThrottlingWithContext(ctx context.Context) (*CgroupThrottlingStat, error) {
+	values, err := readKeyValues(c.file("cpu", "cpu.stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &CgroupThrottlingStat{
+		NrPeriods:   values["nr_periods"],
+		NrThrottled: values["nr_throttled"],
+	}
+	if c.version == 1 {
+		ret.ThrottledTime = values["throttled_time"] / 1000
+	} else {
+		ret.ThrottledTime = values["throttled_usec"]
+	}
+	return ret, nil
+}
+
+// CgroupThrottlingDelta returns the throttling rates between two samples taken elapsed apart.
+func CgroupThrottlingDelta(before, after *CgroupThrottlingStat, elapsed time.Duration) *CgroupThrottlingRateStat {
+	seconds := elapsed.Seconds()
+	ret := &CgroupThrottlingRateStat{
+		Periods:       counterRate(before.NrPeriods, after.NrPeriods, seconds),
+		Throttled:     counterRate(before.NrThrottled, after.NrThrottled, seconds),
+		ThrottledTime: counterRate(before.ThrottledTime, after.ThrottledTime, seconds) / 1e6,
+	}
+	if periods := counterDelta(before.NrPeriods, after.NrPeriods); periods > 0 {
+		ret.ThrottledFraction = float64(counterDelta(before.NrThrottled, after.NrThrottled)) / float64(periods)
+	}
+	return ret
+}
+
+func (c *Cgroup) ThrottlingRate(interval time.Duration) (*CgroupThrottlingRateStat, error) {
+	return c.ThrottlingRateWithContext(context.Background(), interval)
+}
+
+func (c *Cgroup) ThrottlingRateWithContext(ctx context.Context, interval time.Duration) (*CgroupThrottlingRateStat, error) {
+	t1, err := c.ThrottlingWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	if err := Sleep(ctx, interval); err != nil {
+		return nil, err
+	}
+
+	t2, err := c.ThrottlingWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return CgroupThrottlingDelta(t1, t2, time.Since(start)), nil
+}
+
+// WatchThrottling samples the throttling every interval and calls fn when the throttled
+// fraction of the periods exceeds threshold (0 to 1). It returns once the first sample is
+// taken, fn is called from a background goroutine until ctx is done.
+func (c *Cgroup) WatchThrottling(ctx context.Context, interval time.Duration, threshold float64, fn func(*CgroupThrottlingRateStat)) error {
+	if interval <= 0 {
+		return errors.New("interval must be positive")
+	}
+	last, err := c.ThrottlingWithContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		lastTime := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			cur, err := c.ThrottlingWithContext(ctx)
+			if err != nil {
+				continue
+			}
+			now := time.Now()
+			rate := CgroupThrottlingDelta(last, cur, now.Sub(lastTime))
+			last, lastTime = cur, now
+			if rate.ThrottledFraction > threshold {
+				fn(rate)
+			}
+		}
+	}()
+	return nil
+}
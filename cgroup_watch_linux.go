@@ -0,0 +1,138 @@
+package cpuproc
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// limitsPollInterval is how often WatchLimits re-reads the limits when inotify misses a change,
+// e.g. cpuset.cpus.effective changed by a parent cgroup does not generate an event.
+var limitsPollInterval = 10 * time.Second
+
+// CgroupLimitsEvent contains the cpu limits of a cgroup, it is sent by WatchLimits when they change.
+// Limit has a Quota of -1 when the cgroup is not limited or has no cpu controller.
+type CgroupLimitsEvent struct {
+	Limit  CgroupCPULimit `json:"limit"`
+	Cpuset []int          `json:"cpuset"`
+}
+
+// CPUs returns how many cpus the cgroup can use, the smallest of its cpuset and its quota.
+func (e CgroupLimitsEvent) CPUs() float64 {
+	cpus := float64(len(e.Cpuset))
+	if limit := e.Limit.CPUs(); limit > 0 && (cpus == 0 || limit < cpus) {
+		cpus = limit
+	}
+	return cpus
+}
+
+func (c *Cgroup) limitsWithContext(ctx context.Context) (*CgroupLimitsEvent, error) {
+	limit, limitErr := c.CPULimitWithContext(ctx)
+	cpuset, cpusetErr := c.CpusetWithContext(ctx)
+	if limitErr != nil && cpusetErr != nil {
+		return nil, limitErr
+	}
+
+	ret := &CgroupLimitsEvent{Limit: CgroupCPULimit{Quota: -1}, Cpuset: cpuset}
+	if limitErr == nil {
+		ret.Limit = *limit
+	}
+	return ret, nil
+}
+
+// limitFiles returns the files holding the cpu quota and cpuset of the cgroup.
+func (c *Cgroup) limitFiles() []string {
+	if c.version == 1 {
+		return []string{
+			c.file("cpu", "cpu.cfs_quota_us"),
+			c.file("cpu", "cpu.cfs_period_us"),
+			c.file("cpuset", "cpuset.cpus"),
+			c.file("cpuset", "cpuset.effective_cpus"),
+		}
+	}
+	return []string{
+		c.file("cpu", "cpu.max"),
+		c.file("cpuset", "cpuset.cpus"),
+		c.file("cpuset", "cpuset.cpus.effective"),
+	}
+}
+
+// WatchLimits sends the cpu quota and cpuset of the cgroup on the returned channel every time
+// they change, so samplers can re-normalize their percents. Changes are picked up with inotify on
+// the cgroup files and by polling every 10 seconds when inotify can't see them.
+// The channel is closed when ctx is done.
+func (c *Cgroup) WatchLimits(ctx context.Context) (<-chan CgroupLimitsEvent, error) {
+	last, err := c.limitsWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// without inotify the limits are only polled
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err == nil {
+		watched := 0
+		for _, name := range c.limitFiles() {
+			if _, err := unix.InotifyAddWatch(fd, name, unix.IN_MODIFY); err == nil {
+				watched++
+			}
+		}
+		if watched == 0 {
+			unix.Close(fd)
+			fd = -1
+		}
+	}
+
+	wakeup, stop, err := newCtxWakeup(ctx)
+	if err != nil {
+		if fd >= 0 {
+			unix.Close(fd)
+		}
+		return nil, err
+	}
+
+	ch := make(chan CgroupLimitsEvent)
+	go func() {
+		defer close(ch)
+		defer stop()
+		if fd >= 0 {
+			defer unix.Close(fd)
+		}
+
+		fds := []unix.PollFd{{Fd: int32(wakeup), Events: unix.POLLIN}}
+		if fd >= 0 {
+			fds = append(fds, unix.PollFd{Fd: int32(fd), Events: unix.POLLIN})
+		}
+		buf := make([]byte, 4096)
+		for {
+			_, err := unix.Poll(fds, int(limitsPollInterval.Milliseconds()))
+			if err != nil && err != unix.EINTR {
+				return
+			}
+			if fds[0].Revents != 0 {
+				return
+			}
+			if fd >= 0 && fds[1].Revents != 0 {
+				// only the wakeup matters, drain the queued events
+				for {
+					if n, err := unix.Read(fd, buf); n <= 0 || err != nil {
+						break
+					}
+				}
+			}
+
+			cur, err := c.limitsWithContext(ctx)
+			if err != nil || (cur.Limit == last.Limit && slices.Equal(cur.Cpuset, last.Cpuset)) {
+				continue
+			}
+			last = cur
+			select {
+			case ch <- *cur:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
@@ -0,0 +1,135 @@
+package cpuproc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// NewChild creates the sub-cgroup name, in every controller hierarchy on cgroup v1.
+// On cgroup v2 the cpu and cpuset controllers are enabled in the subtree of c first.
+// The cpus and memory nodes of a cgroup v1 cpuset are inherited from c, a new cpuset
+// is empty otherwise and can't hold any process.
+func (c *Cgroup) NewChild(name string) (*Cgroup, error) {
+	if name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("wrong cgroup name %q", name)
+	}
+
+	if c.version != 1 {
+		if err := c.enableControllers("cpu", "cpuset"); err != nil {
+			return nil, err
+		}
+		path := filepath.Join(c.path, name)
+		if err := os.Mkdir(path, 0o755); err != nil && !os.IsExist(err) {
+			return nil, err
+		}
+		return &Cgroup{version: 2, path: path}, nil
+	}
+
+	child := &Cgroup{version: 1, path: filepath.Join(c.path, name), dirs: make(map[string]string, len(c.dirs))}
+	for controller, dir := range c.dirs {
+		path := filepath.Join(dir, name)
+		if err := os.Mkdir(path, 0o755); err != nil && !os.IsExist(err) {
+			return nil, err
+		}
+		child.dirs[controller] = path
+	}
+	if dir, ok := c.dirs["cpuset"]; ok {
+		for _, name := range []string{"cpuset.cpus", "cpuset.mems"} {
+			v, err := readTrimmed(filepath.Join(dir, name))
+			if err != nil {
+				return nil, err
+			}
+			if err := writeExistingFile(child.file("cpuset", name), v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return child, nil
+}
+
+// enableControllers enables the controllers among names that are available in c for its
+// children. Enabling one that is already enabled is a no-op, one that is not available is
+// left to the limit setters to report.
+func (c *Cgroup) enableControllers(names ...string) error {
+	available, err := readTrimmed(filepath.Join(c.path, "cgroup.controllers"))
+	if err != nil {
+		return err
+	}
+	var enable []string
+	for _, name := range names {
+		for _, controller := range strings.Fields(available) {
+			if controller == name {
+				enable = append(enable, "+"+name)
+				break
+			}
+		}
+	}
+	if len(enable) == 0 {
+		return nil
+	}
+	return writeExistingFile(filepath.Join(c.path, "cgroup.subtree_control"), strings.Join(enable, " "))
+}
+
+// AddProcess moves pid and all its threads into the cgroup.
+func (c *Cgroup) AddProcess(pid int32) error {
+	if c.version != 1 {
+		return writeExistingFile(filepath.Join(c.path, "cgroup.procs"), strconv.Itoa(int(pid)))
+	}
+	if len(c.dirs) == 0 {
+		return errors.New("no cgroup v1 controller found")
+	}
+	for _, dir := range c.dirs {
+		if err := writeExistingFile(filepath.Join(dir, "cgroup.procs"), strconv.Itoa(int(pid))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetCPULimit sets the CFS bandwidth limit of the cgroup, a Quota of -1 removes the limit.
+// A Period of 0 keeps the current one.
+func (c *Cgroup) SetCPULimit(limit CgroupCPULimit) error {
+	if c.version == 1 {
+		if limit.Period != 0 {
+			if err := writeExistingFile(c.file("cpu", "cpu.cfs_period_us"), strconv.FormatUint(limit.Period, 10)); err != nil {
+				return err
+			}
+		}
+		return writeExistingFile(c.file("cpu", "cpu.cfs_quota_us"), strconv.FormatInt(limit.Quota, 10))
+	}
+
+	quota := "max"
+	if limit.Quota >= 0 {
+		quota = strconv.FormatInt(limit.Quota, 10)
+	}
+	if limit.Period != 0 {
+		quota += " " + strconv.FormatUint(limit.Period, 10)
+	}
+	return writeExistingFile(c.file("cpu", "cpu.max"), quota)
+}
+
+// SetCpuset restricts the cgroup to cpus.
+func (c *Cgroup) SetCpuset(cpus []int) error {
+	if len(cpus) == 0 {
+		return errors.New("empty cpuset")
+	}
+	return writeExistingFile(c.file("cpuset", "cpuset.cpus"), formatCPUList(cpus))
+}
+
+// Remove deletes the cgroup, it must not hold any process or sub-cgroup.
+func (c *Cgroup) Remove() error {
+	if c.version != 1 {
+		return os.Remove(c.path)
+	}
+	var ret error
+	for _, dir := range c.dirs {
+		if err := os.Remove(dir); err != nil && !os.IsNotExist(err) {
+			ret = err
+		}
+	}
+	return ret
+}
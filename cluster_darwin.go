@@ -0,0 +1,113 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ClusterPerformance = "performance"
+	ClusterEfficiency  = "efficiency"
+)
+
+// ClusterStat contains the summed times of a cpu cluster of Apple Silicon machines.
+// The CPU field of Times is the cluster name.
+type ClusterStat struct {
+	Cluster string    `json:"cluster"`
+	CPUs    []int     `json:"cpus"`
+	Times   TimesStat `json:"times"`
+}
+
+func TimesByCluster() ([]ClusterStat, error) {
+	return TimesByClusterWithContext(context.Background())
+}
+
+// TimesByClusterWithContext groups the per-cpu times into performance and efficiency clusters.
+// Machines with a single kind of core report one performance cluster.
+func TimesByClusterWithContext(ctx context.Context) ([]ClusterStat, error) {
+	cpuTimes, err := TimesWithContext(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(cpuTimes) == 0 {
+		return nil, errors.New("cpu times are not available")
+	}
+
+	perf := ClusterStat{Cluster: ClusterPerformance, CPUs: []int{}, Times: TimesStat{CPU: ClusterPerformance}}
+	eff := ClusterStat{Cluster: ClusterEfficiency, CPUs: []int{}, Times: TimesStat{CPU: ClusterEfficiency}}
+
+	types := clusterTypes(len(cpuTimes))
+	for i, t := range cpuTimes {
+		c := &perf
+		if types[i] == 'E' {
+			c = &eff
+		}
+		c.CPUs = append(c.CPUs, i)
+		c.Times = c.Times.add(t)
+	}
+
+	if len(eff.CPUs) == 0 {
+		return []ClusterStat{perf}, nil
+	}
+	return []ClusterStat{perf, eff}, nil
+}
+
+func PercentByCluster(interval time.Duration) ([]float64, error) {
+	return PercentByClusterWithContext(context.Background(), interval)
+}
+
+// PercentByClusterWithContext returns the busy percent of every cluster over interval,
+// in the same order as TimesByClusterWithContext.
+func PercentByClusterWithContext(ctx context.Context, interval time.Duration) ([]float64, error) {
+	c1, err := TimesByClusterWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Sleep(ctx, interval); err != nil {
+		return nil, err
+	}
+
+	c2, err := TimesByClusterWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return calculateAllBusy(clusterTimes(c1), clusterTimes(c2))
+}
+
+func clusterTimes(clusters []ClusterStat) []TimesStat {
+	ret := make([]TimesStat, len(clusters))
+	for i, c := range clusters {
+		ret[i] = c.Times
+	}
+	return ret
+}
+
+// clusterTypes returns 'P' or 'E' for each of the n logical cpus. IOKit is asked first,
+// otherwise the perflevel sysctls are used: macOS numbers the efficiency cores first.
+func clusterTypes(n int) []byte {
+	if types := cpuClusterTypes(n); types != nil {
+		return types
+	}
+
+	types := make([]byte, n)
+	for i := range types {
+		types[i] = 'P'
+	}
+	levels, err := unix.SysctlUint32("hw.nperflevels")
+	if err != nil || levels < 2 {
+		return types
+	}
+	effCount, err := unix.SysctlUint32("hw.perflevel1.logicalcpu")
+	if err != nil {
+		return types
+	}
+	for i := 0; i < int(effCount) && i < n; i++ {
+		types[i] = 'E'
+	}
+	return types
+}
@@ -2,8 +2,13 @@ package cpuproc
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 func HostProcWithContext(ctx context.Context, combineWith ...string) string {
@@ -17,6 +22,10 @@ func HostRootWithContext(ctx context.Context, combineWith ...string) string {
 	return GetEnvWithContext(ctx, "HOST_ROOT", "/", combineWith...)
 }
 
+func HostSysWithContext(ctx context.Context, combineWith ...string) string {
+	return GetEnvWithContext(ctx, "HOST_SYS", "/sys", combineWith...)
+}
+
 // GetEnvWithContext retrieves the environment variable key. If it does not exist it returns the default.
 // The context may optionally contain a map superseding os.EnvKey.
 func GetEnvWithContext(ctx context.Context, key string, dfault string, combineWith ...string) string {
@@ -57,3 +66,109 @@ func ReadFile(filename string) (string, error) {
 
 	return string(content), nil
 }
+
+// readTrimmed reads a single value file such as the ones found in sysfs
+func readTrimmed(filename string) (string, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+func readInt(filename string) (int, error) {
+	s, err := readTrimmed(filename)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(s)
+}
+
+func readUint(filename string) (uint64, error) {
+	s, err := readTrimmed(filename)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// parseCPUList parses the kernel cpu list format, e.g. "0-3,8,10-11".
+// The result is sorted and has no duplicates.
+func parseCPUList(s string) ([]int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return []int{}, nil
+	}
+
+	seen := make(map[int]struct{})
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("wrong cpu list format: %q", s)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(hi)
+			if err != nil || end < start {
+				return nil, fmt.Errorf("wrong cpu list format: %q", s)
+			}
+		}
+		for i := start; i <= end; i++ {
+			seen[i] = struct{}{}
+		}
+	}
+
+	ret := make([]int, 0, len(seen))
+	for cpu := range seen {
+		ret = append(ret, cpu)
+	}
+	sort.Ints(ret)
+	return ret, nil
+}
+
+// formatCPUList is the inverse of parseCPUList, e.g. []int{0, 1, 2, 5} -> "0-2,5".
+func formatCPUList(cpus []int) string {
+	sorted := slices.Clone(cpus)
+	sort.Ints(sorted)
+	sorted = slices.Compact(sorted)
+
+	var b strings.Builder
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[j]+1 {
+			j++
+		}
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(sorted[i]))
+		if j > i {
+			b.WriteByte('-')
+			b.WriteString(strconv.Itoa(sorted[j]))
+		}
+		i = j + 1
+	}
+	return b.String()
+}
+
+// counterRate returns the per second rate of a monotonic counter between two samples.
+// A counter that went backwards (reset or wrapped) is reported as 0.
+func counterRate(before, after uint64, seconds float64) float64 {
+	if after < before || seconds <= 0 {
+		return 0
+	}
+	return float64(after-before) / seconds
+}
+
+// counterDelta returns the increase of a monotonic counter, 0 if it went backwards.
+func counterDelta(before, after uint64) uint64 {
+	if after < before {
+		return 0
+	}
+	return after - before
+}
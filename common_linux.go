@@ -93,6 +93,19 @@ func ReadLine(filename string, prefix string) (string, error) {
 	return "", nil
 }
 
+// writeExistingFile writes v to an existing file, procfs and cgroupfs don't allow creating files.
+func writeExistingFile(filename, v string) error {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(v); err != nil {
+		f.Close()
+		return fmt.Errorf("write %q to %s: %w", v, filename, err)
+	}
+	return f.Close()
+}
+
 func handleBootTimeFileReadErr(err error) (uint64, error) {
 	if os.IsPermission(err) {
 		var info syscall.Sysinfo_t
@@ -159,6 +172,7 @@ func BootTimeWithContext(ctx context.Context, enableCache bool) (uint64, error)
 		if enableCache {
 			atomic.StoreUint64(&cachedBootTime, t)
 		}
+		return t, nil
 	}
 
 	filename := HostProcWithContext(ctx, "uptime")
@@ -257,6 +271,23 @@ func VirtualizationWithContext(ctx context.Context) (string, string, error) {
 		}
 	}
 
+	if product, ok := dmiVirtualization(ctx); ok {
+		system = product
+		role = "guest"
+	}
+
+	// firecracker has neither dmi nor pci, its devices are virtio over mmio
+	if cmdline, err := ReadFile(HostProcWithContext(ctx, "cmdline")); err == nil && strings.Contains(cmdline, "virtio_mmio.device=") && !PathExists(HostSysWithContext(ctx, "bus/pci/devices")) {
+		system = "firecracker"
+		role = "guest"
+	}
+
+	// WSL1 reports "Microsoft", WSL2 "microsoft-standard-WSL2"
+	if osrelease, err := ReadFile(HostProcWithContext(ctx, "sys/kernel/osrelease")); err == nil && strings.Contains(strings.ToLower(osrelease), "microsoft") {
+		system = "wsl"
+		role = "guest"
+	}
+
 	filename = HostProcWithContext(ctx)
 	if PathExists(filepath.Join(filename, "bc", "0")) {
 		system = "openvz"
@@ -289,17 +320,17 @@ func VirtualizationWithContext(ctx context.Context) (string, string, error) {
 		}
 	}
 
+	// systemd sets $container of pid 1, which only root may read, and copies it in /run/systemd/container
+	if contents, err := readTrimmed(HostRootWithContext(ctx, "run/systemd/container")); err == nil && contents != "" {
+		system = contents
+		role = "guest"
+	}
+
 	if PathExists(filepath.Join(filename, "self", "cgroup")) {
 		contents, err := ReadLines(filepath.Join(filename, "self", "cgroup"))
 		if err == nil {
-			if slices.Contains(contents, "lxc") {
-				system = "lxc"
-				role = "guest"
-			} else if slices.Contains(contents, "docker") {
-				system = "docker"
-				role = "guest"
-			} else if slices.Contains(contents, "machine-rkt") {
-				system = "rkt"
+			if guest := cgroupVirtualization(contents); guest != "" {
+				system = guest
 				role = "guest"
 			} else if PathExists("/usr/bin/lxc-version") {
 				system = "lxc"
@@ -321,6 +352,11 @@ func VirtualizationWithContext(ctx context.Context) (string, string, error) {
 		role = "guest"
 	}
 
+	if PathExists(HostRootWithContext(ctx, "run/.containerenv")) {
+		system = "podman"
+		role = "guest"
+	}
+
 	// before returning for the first time, cache the system and role
 	cachedVirtOnce.Do(func() {
 		cachedVirtMutex.Lock()
@@ -334,6 +370,69 @@ func VirtualizationWithContext(ctx context.Context) (string, string, error) {
 	return system, role, nil
 }
 
+// cgroupVirtualization recognizes the container system from the lines of /proc/self/cgroup,
+// by the shapes of the paths the runtimes create: /docker/<id>, docker-<id>.scope,
+// /lxc/<name>, /lxc.payload.<name>, machine-rkt-*.scope and the kubepods hierarchy.
+func cgroupVirtualization(lines []string) string {
+	for _, line := range lines {
+		// hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		path := parts[2]
+		names := strings.Split(strings.Trim(path, "/"), "/")
+		for i, name := range names {
+			switch {
+			case name == "lxc" && i+1 < len(names),
+				strings.HasPrefix(name, "lxc.payload."):
+				return "lxc"
+			case strings.HasPrefix(name, "machine-rkt"):
+				return "rkt"
+			}
+		}
+
+		runtime, _, ok := parseContainerCgroup(path)
+		if !ok {
+			continue
+		}
+		switch {
+		case runtime == ContainerRuntimePodman:
+			return "podman"
+		case slices.ContainsFunc(names, func(name string) bool { return strings.HasPrefix(name, "kubepods") }):
+			return "kubernetes"
+		case runtime == ContainerRuntimeDocker:
+			return "docker"
+		}
+	}
+	return ""
+}
+
+// dmiVirtualization recognizes the hypervisor from the dmi product name and vendor,
+// which unlike dmidecode do not need root.
+func dmiVirtualization(ctx context.Context) (string, bool) {
+	product, _ := readTrimmed(HostSysWithContext(ctx, "class/dmi/id/product_name"))
+	vendor, _ := readTrimmed(HostSysWithContext(ctx, "class/dmi/id/sys_vendor"))
+	switch {
+	case product == "":
+		return "", false
+	case strings.Contains(product, "KVM"), strings.Contains(vendor, "QEMU"), strings.Contains(product, "Google Compute Engine"):
+		return "kvm", true
+	case strings.Contains(product, "VMware"):
+		return "vmware", true
+	case strings.Contains(product, "VirtualBox"):
+		return "vbox", true
+	case product == "Virtual Machine" && strings.Contains(vendor, "Microsoft"):
+		return "hyperv", true
+	case strings.HasPrefix(product, "HVM domU"):
+		return "xen", true
+	case strings.Contains(vendor, "Amazon EC2") && !strings.HasSuffix(product, ".metal"):
+		// the product is the instance type, .metal ones are bare metal
+		return "kvm", true
+	}
+	return "", false
+}
+
 // Remove quotes of the source string
 func trimQuotes(s string) string {
 	if len(s) >= 2 {
@@ -369,3 +468,29 @@ func GetOSReleaseWithContext(ctx context.Context) (platform string, version stri
 
 	return platform, version, nil
 }
+
+// newCtxWakeup returns a file descriptor that becomes readable when ctx is done, to poll along
+// the ones waited for. stop releases it once the polling is over, it can be called more than
+// once.
+func newCtxWakeup(ctx context.Context) (fd int, stop func(), err error) {
+	// closing the write end wakes poll up when ctx is done
+	r, w, err := os.Pipe()
+	if err != nil {
+		return -1, nil, err
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		w.Close()
+	}()
+	var once sync.Once
+	return int(r.Fd()), func() {
+		once.Do(func() {
+			close(done)
+			r.Close()
+		})
+	}, nil
+}
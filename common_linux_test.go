@@ -0,0 +1,79 @@
+package cpuproc
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func Test_cgroupVirtualization(t *testing.T) {
+	id := strings.Repeat("ab12", 16)
+	for _, tc := range []struct {
+		cgroup string
+		want   string
+	}{
+		{"0::/docker/" + id, "docker"},
+		{"0::/system.slice/docker-" + id + ".scope", "docker"},
+		{"0::/user.slice/libpod-" + id + ".scope/container", "podman"},
+		{"0::/kubepods.slice/kubepods-pod1.slice/cri-containerd-" + id + ".scope", "kubernetes"},
+		{"0::/kubepods/besteffort/pod1/" + id, "kubernetes"},
+		{"0::/lxc/web", "lxc"},
+		{"0::/lxc.payload.web", "lxc"},
+		{"1:name=systemd:/machine.slice/machine-rkt\\x2d1.scope", "rkt"},
+		// names that merely contain a runtime
+		{"0::/system.slice/docker.service", ""},
+		{"0::/system.slice/dockerd-helper.service", ""},
+		{"0::/user.slice/user-1000.slice/session-2.scope/lxc-monitor", ""},
+		{"0::/docker/not-an-id", ""},
+		{"0::/", ""},
+		{"malformed", ""},
+	} {
+		if got := cgroupVirtualization([]string{tc.cgroup}); got != tc.want {
+			t.Errorf("cgroupVirtualization(%q) = %q, want %q", tc.cgroup, got, tc.want)
+		}
+	}
+}
+
+func Test_dmiVirtualization(t *testing.T) {
+	for _, tc := range []struct {
+		product, vendor string
+		want            string
+		ok              bool
+	}{
+		{"m5.large", "Amazon EC2", "kvm", true},
+		{"m5.metal", "Amazon EC2", "", false},
+		{"KVM", "Red Hat", "kvm", true},
+		{"Virtual Machine", "Microsoft Corporation", "hyperv", true},
+		{"PowerEdge R640", "Dell Inc.", "", false},
+	} {
+		ctx := newTestContext(t, map[string]string{
+			"sys/class/dmi/id/product_name": tc.product + "\n",
+			"sys/class/dmi/id/sys_vendor":   tc.vendor + "\n",
+		})
+		if got, ok := dmiVirtualization(ctx); got != tc.want || ok != tc.ok {
+			t.Errorf("dmiVirtualization(%q, %q) = %q, %v, want %q, %v", tc.product, tc.vendor, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func Test_newCtxWakeup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fd, stop, err := newCtxWakeup(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+	if n, err := unix.Poll(fds, 10); err != nil || n != 0 {
+		t.Fatalf("poll = %d, %v before cancel, want a timeout", n, err)
+	}
+	cancel()
+	if n, err := unix.Poll(fds, 1000); err != nil || n != 1 {
+		t.Errorf("poll = %d, %v after cancel, want the wakeup", n, err)
+	}
+	// stop is idempotent
+	stop()
+}
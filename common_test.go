@@ -0,0 +1,37 @@
+package cpuproc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseCPUList(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want []int
+		err  bool
+	}{
+		{in: "", want: []int{}},
+		{in: "0", want: []int{0}},
+		{in: "0-3", want: []int{0, 1, 2, 3}},
+		{in: "0-1,4,6-7\n", want: []int{0, 1, 4, 6, 7}},
+		{in: "3,1,1-2", want: []int{1, 2, 3}},
+		{in: "a-b", err: true},
+		{in: "3-1", err: true},
+	} {
+		got, err := parseCPUList(tc.in)
+		if tc.err {
+			if err == nil {
+				t.Errorf("parseCPUList(%q) expected error", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseCPUList(%q): %v", tc.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parseCPUList(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// container runtimes recognized by ContainerOf
+const (
+	ContainerRuntimeDocker     = "docker"
+	ContainerRuntimeContainerd = "containerd"
+	ContainerRuntimeCRIO       = "cri-o"
+	ContainerRuntimePodman     = "podman"
+)
+
+// ContainerStat identifies the container a process runs in. Runtime is empty when the
+// cgroup path has a container id but does not tell the runtime, e.g. kubepods with the
+// cgroupfs driver. CgroupPath can be passed to NewCgroup.
+type ContainerStat struct {
+	Runtime    string `json:"runtime"`
+	ID         string `json:"id"`
+	CgroupPath string `json:"cgroupPath"`
+}
+
+// ErrNoContainer is returned by ContainerOf for processes that do not run in a container.
+var ErrNoContainer = errors.New("process is not in a container")
+
+// container id prefixes of the systemd scopes and cgroupfs directories of the runtimes
+var containerPrefixes = []struct {
+	prefix  string
+	runtime string
+}{
+	{"docker-", ContainerRuntimeDocker},
+	{"cri-containerd-", ContainerRuntimeContainerd},
+	{"crio-", ContainerRuntimeCRIO},
+	{"libpod-", ContainerRuntimePodman},
+}
+
+// ContainerOf returns the container of pid from /proc/<pid>/cgroup.
+func ContainerOf(pid int32) (*ContainerStat, error) {
+	return ContainerOfWithContext(context.Background(), pid)
+}
+
+func ContainerOfWithContext(ctx context.Context, pid int32) (*ContainerStat, error) {
+	paths, err := readProcCgroup(ctx, strconv.Itoa(int(pid)))
+	if err != nil {
+		return nil, err
+	}
+
+	// look at the hierarchies in a stable order, the cgroup v2 one first
+	keys := make([]string, 0, len(paths))
+	for k := range paths {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if runtime, id, ok := parseContainerCgroup(paths[k]); ok {
+			return &ContainerStat{Runtime: runtime, ID: id, CgroupPath: paths[k]}, nil
+		}
+	}
+	return nil, ErrNoContainer
+}
+
+// parseContainerCgroup finds the container id in a cgroup path such as
+// /system.slice/docker-<id>.scope or /kubepods/burstable/pod<uid>/<id>.
+// The innermost component wins, podman nests a "container" cgroup in its scope.
+func parseContainerCgroup(cgroupPath string) (runtime, id string, ok bool) {
+	parts := strings.Split(strings.Trim(cgroupPath, "/"), "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		name := strings.TrimSuffix(parts[i], ".scope")
+		for _, p := range containerPrefixes {
+			if id := strings.TrimPrefix(name, p.prefix); id != name && isContainerID(id) {
+				return p.runtime, id, true
+			}
+		}
+		if !isContainerID(name) {
+			continue
+		}
+		// cgroupfs driver, the parent tells the runtime if anything
+		if i > 0 {
+			switch parts[i-1] {
+			case "docker":
+				return ContainerRuntimeDocker, name, true
+			case "libpod_parent":
+				return ContainerRuntimePodman, name, true
+			}
+		}
+		return "", name, true
+	}
+	return "", "", false
+}
+
+// isContainerID reports whether s is a 64 characters hex id as used by all the runtimes.
+func isContainerID(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
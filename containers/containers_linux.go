@@ -0,0 +1,229 @@
+// Package containers resolves the containers found by cpuproc.ContainerOf to their
+// human readable names and labels, asking the Docker (or Podman) API or reading the
+// OCI bundles of containerd and CRI-O.
+package containers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/antlabs/cpuproc"
+)
+
+// Info is a resolved container.
+type Info struct {
+	ID      string            `json:"id"`
+	Runtime string            `json:"runtime"`
+	Name    string            `json:"name"`
+	Image   string            `json:"image"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// ErrNotFound is returned when no runtime knows the container.
+var ErrNotFound = errors.New("container not found")
+
+// kubernetes annotations of the OCI bundles written by containerd and CRI-O
+var nameAnnotations = []string{
+	"io.kubernetes.cri.container-name",
+	"io.kubernetes.container.name",
+}
+
+var imageAnnotations = []string{
+	"io.kubernetes.cri.image-name",
+	"io.kubernetes.cri-o.ImageName",
+}
+
+// Resolver resolves container ids, results are cached as names and labels don't change
+// during the life of a container. The zero value uses the default socket and state paths.
+type Resolver struct {
+	// DockerSocket is the Docker API socket, /var/run/docker.sock by default.
+	DockerSocket string
+	// PodmanSocket is the Docker compatible API socket of Podman, /run/podman/podman.sock by default.
+	PodmanSocket string
+	// ContainerdState is where containerd keeps the bundles of its tasks, one directory per
+	// namespace, /run/containerd/io.containerd.runtime.v2.task by default.
+	ContainerdState string
+	// CRIOState is where CRI-O keeps the bundles of its containers,
+	// /run/containers/storage/overlay-containers by default.
+	CRIOState string
+
+	mu    sync.Mutex
+	cache map[string]*Info
+}
+
+var defaultResolver Resolver
+
+// Resolve resolves the container of pid with a shared Resolver.
+func Resolve(ctx context.Context, pid int32) (*Info, error) {
+	return defaultResolver.ResolvePid(ctx, pid)
+}
+
+// ResolvePid resolves the container of pid, cpuproc.ErrNoContainer is returned
+// when it doesn't run in one.
+func (r *Resolver) ResolvePid(ctx context.Context, pid int32) (*Info, error) {
+	c, err := cpuproc.ContainerOfWithContext(ctx, pid)
+	if err != nil {
+		return nil, err
+	}
+	return r.Resolve(ctx, c)
+}
+
+// Resolve asks the runtime of c for its name and labels. When the runtime is unknown
+// every runtime is tried in turn.
+func (r *Resolver) Resolve(ctx context.Context, c *cpuproc.ContainerStat) (*Info, error) {
+	r.mu.Lock()
+	info, ok := r.cache[c.ID]
+	r.mu.Unlock()
+	if ok {
+		return info, nil
+	}
+
+	info, err := r.resolve(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	if r.cache == nil {
+		r.cache = make(map[string]*Info)
+	}
+	r.cache[c.ID] = info
+	r.mu.Unlock()
+	return info, nil
+}
+
+// Forget drops id from the cache, e.g. once its container exited.
+func (r *Resolver) Forget(id string) {
+	r.mu.Lock()
+	delete(r.cache, id)
+	r.mu.Unlock()
+}
+
+func (r *Resolver) resolve(ctx context.Context, c *cpuproc.ContainerStat) (*Info, error) {
+	switch c.Runtime {
+	case cpuproc.ContainerRuntimeDocker:
+		return inspect(ctx, or(r.DockerSocket, "/var/run/docker.sock"), c.ID, c.Runtime)
+	case cpuproc.ContainerRuntimePodman:
+		return inspect(ctx, or(r.PodmanSocket, "/run/podman/podman.sock"), c.ID, c.Runtime)
+	case cpuproc.ContainerRuntimeContainerd:
+		return r.containerdBundle(c.ID)
+	case cpuproc.ContainerRuntimeCRIO:
+		return bundle(filepath.Join(or(r.CRIOState, "/run/containers/storage/overlay-containers"), c.ID, "userdata", "config.json"), c.ID, c.Runtime)
+	}
+
+	for _, runtime := range []string{cpuproc.ContainerRuntimeContainerd, cpuproc.ContainerRuntimeCRIO, cpuproc.ContainerRuntimeDocker, cpuproc.ContainerRuntimePodman} {
+		if info, err := r.resolve(ctx, &cpuproc.ContainerStat{Runtime: runtime, ID: c.ID}); err == nil {
+			return info, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// inspect calls GET /containers/<id>/json of the Docker API, Podman serves the same endpoint.
+func inspect(ctx context.Context, socket, id, runtime string) (*Info, error) {
+	client := http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost/containers/"+id+"/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, fmt.Errorf("inspect %s: %s", id, resp.Status)
+	}
+
+	var body struct {
+		Name   string
+		Config struct {
+			Image  string
+			Labels map[string]string
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	return &Info{
+		ID:      id,
+		Runtime: runtime,
+		Name:    strings.TrimPrefix(body.Name, "/"),
+		Image:   body.Config.Image,
+		Labels:  body.Config.Labels,
+	}, nil
+}
+
+// containerdBundle looks for the task of id in every containerd namespace, e.g. "k8s.io" or "moby".
+func (r *Resolver) containerdBundle(id string) (*Info, error) {
+	matches, err := filepath.Glob(filepath.Join(or(r.ContainerdState, "/run/containerd/io.containerd.runtime.v2.task"), "*", id, "config.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, ErrNotFound
+	}
+	return bundle(matches[0], id, cpuproc.ContainerRuntimeContainerd)
+}
+
+// bundle reads the annotations of the OCI runtime spec of a container, the CRI
+// plugins of containerd and CRI-O store the kubernetes metadata there.
+func bundle(filename, id, runtime string) (*Info, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	var spec struct {
+		Annotations map[string]string `json:"annotations"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+
+	info := &Info{ID: id, Runtime: runtime, Labels: spec.Annotations}
+	for _, k := range nameAnnotations {
+		if v, ok := spec.Annotations[k]; ok {
+			info.Name = v
+			break
+		}
+	}
+	for _, k := range imageAnnotations {
+		if v, ok := spec.Annotations[k]; ok {
+			info.Image = v
+			break
+		}
+	}
+	return info, nil
+}
+
+func or(s, def string) string {
+	if s != "" {
+		return s
+	}
+	return def
+}
@@ -0,0 +1,68 @@
+package containers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antlabs/cpuproc"
+)
+
+func TestResolveDocker(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skip(err)
+	}
+	id := strings.Repeat("a", 64)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/containers/"+id+"/json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"Name":"/payments-api","Config":{"Image":"payments:1.2","Labels":{"team":"billing"}}}`))
+	})}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	r := &Resolver{DockerSocket: socket}
+	info, err := r.Resolve(context.Background(), &cpuproc.ContainerStat{Runtime: cpuproc.ContainerRuntimeDocker, ID: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "payments-api" || info.Image != "payments:1.2" || info.Labels["team"] != "billing" {
+		t.Errorf("unexpected %+v", info)
+	}
+
+	_, err = r.Resolve(context.Background(), &cpuproc.ContainerStat{Runtime: cpuproc.ContainerRuntimeDocker, ID: strings.Repeat("b", 64)})
+	if err != ErrNotFound {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestResolveContainerd(t *testing.T) {
+	state := t.TempDir()
+	id := strings.Repeat("c", 64)
+	dir := filepath.Join(state, "k8s.io", id)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	spec := `{"annotations":{"io.kubernetes.cri.container-name":"api","io.kubernetes.cri.sandbox-namespace":"prod"}}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(spec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Resolver{ContainerdState: state, CRIOState: t.TempDir(), DockerSocket: "/nonexistent", PodmanSocket: "/nonexistent"}
+	// the runtime is unknown with the cgroupfs driver
+	info, err := r.Resolve(context.Background(), &cpuproc.ContainerStat{ID: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "api" || info.Runtime != cpuproc.ContainerRuntimeContainerd || info.Labels["io.kubernetes.cri.sandbox-namespace"] != "prod" {
+		t.Errorf("unexpected %+v", info)
+	}
+}
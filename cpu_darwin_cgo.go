@@ -0,0 +1,115 @@
+//go:build darwin && cgo
+
+package cpuproc
+
+/*
+#cgo LDFLAGS: -framework IOKit -framework CoreFoundation
+#include <unistd.h>
+#include <mach/mach.h>
+#include <mach/mach_host.h>
+#include <mach/processor_info.h>
+#include <IOKit/IOKitLib.h>
+#include <CoreFoundation/CoreFoundation.h>
+
+// cpu_cluster_types fills types[logical cpu id] with the cluster type ('P' or 'E')
+// found in IODeviceTree:/cpus and returns how many cpus were found.
+static int cpu_cluster_types(char *types, int n) {
+	io_registry_entry_t cpus = IORegistryEntryFromPath(MACH_PORT_NULL, "IODeviceTree:/cpus");
+	if (cpus == MACH_PORT_NULL) {
+		return 0;
+	}
+
+	io_iterator_t it;
+	if (IORegistryEntryGetChildIterator(cpus, kIODeviceTreePlane, &it) != KERN_SUCCESS) {
+		IOObjectRelease(cpus);
+		return 0;
+	}
+
+	int found = 0;
+	io_registry_entry_t cpu;
+	while ((cpu = IOIteratorNext(it)) != 0) {
+		CFTypeRef typ = IORegistryEntryCreateCFProperty(cpu, CFSTR("cluster-type"), kCFAllocatorDefault, 0);
+		CFTypeRef id = IORegistryEntryCreateCFProperty(cpu, CFSTR("logical-cpu-id"), kCFAllocatorDefault, 0);
+		if (typ != NULL && id != NULL &&
+			CFGetTypeID(typ) == CFDataGetTypeID() && CFGetTypeID(id) == CFDataGetTypeID() &&
+			CFDataGetLength((CFDataRef)typ) >= 1 && CFDataGetLength((CFDataRef)id) >= 4) {
+			uint32_t cpuid;
+			CFDataGetBytes((CFDataRef)id, CFRangeMake(0, 4), (UInt8 *)&cpuid);
+			if (cpuid < (uint32_t)n) {
+				types[cpuid] = (char)CFDataGetBytePtr((CFDataRef)typ)[0];
+				found++;
+			}
+		}
+		if (typ != NULL) {
+			CFRelease(typ);
+		}
+		if (id != NULL) {
+			CFRelease(id);
+		}
+		IOObjectRelease(cpu);
+	}
+
+	IOObjectRelease(it);
+	IOObjectRelease(cpus);
+	return found;
+}
+*/
+import "C"
+
+import (
+	"context"
+	"fmt"
+	"unsafe"
+)
+
+// TimesWithContext reads the cpu ticks from host_processor_info.
+func TimesWithContext(ctx context.Context, percpu bool, opts ...Option) ([]TimesStat, error) {
+	var count C.natural_t
+	var info C.processor_info_array_t
+	var infoCount C.mach_msg_type_number_t
+
+	kr := C.host_processor_info(C.mach_host_self(), C.PROCESSOR_CPU_LOAD_INFO, &count, &info, &infoCount)
+	if kr != C.KERN_SUCCESS {
+		return nil, fmt.Errorf("host_processor_info error=%d", kr)
+	}
+	defer C.vm_deallocate(C.mach_task_self_, C.vm_address_t(uintptr(unsafe.Pointer(info))), C.vm_size_t(infoCount)*C.sizeof_integer_t)
+
+	clocksPerSec := float64(C.sysconf(C._SC_CLK_TCK))
+	if clocksPerSec <= 0 {
+		clocksPerSec = 100
+	}
+
+	loads := unsafe.Slice((*C.processor_cpu_load_info_data_t)(unsafe.Pointer(info)), int(count))
+	ret := make([]TimesStat, 0, len(loads))
+	for i, load := range loads {
+		ret = append(ret, TimesStat{
+			CPU:    fmt.Sprintf("cpu%d", i),
+			User:   float64(load.cpu_ticks[C.CPU_STATE_USER]) / clocksPerSec,
+			System: float64(load.cpu_ticks[C.CPU_STATE_SYSTEM]) / clocksPerSec,
+			Nice:   float64(load.cpu_ticks[C.CPU_STATE_NICE]) / clocksPerSec,
+			Idle:   float64(load.cpu_ticks[C.CPU_STATE_IDLE]) / clocksPerSec,
+		})
+	}
+
+	if percpu {
+		return ret, nil
+	}
+	total := TimesStat{CPU: "cpu-total"}
+	for _, t := range ret {
+		total = total.add(t)
+	}
+	return []TimesStat{total}, nil
+}
+
+// cpuClusterTypes returns the cluster type ('P' or 'E') of every logical cpu from IOKit,
+// nil when the device tree does not describe them (e.g. Intel Macs).
+func cpuClusterTypes(n int) []byte {
+	if n <= 0 {
+		return nil
+	}
+	types := make([]byte, n)
+	if C.cpu_cluster_types((*C.char)(unsafe.Pointer(&types[0])), C.int(n)) != C.int(n) {
+		return nil
+	}
+	return types
+}
@@ -0,0 +1,14 @@
+//go:build darwin && !cgo
+
+package cpuproc
+
+import "context"
+
+// TimesWithContext needs cgo for host_processor_info on darwin.
+func TimesWithContext(ctx context.Context, percpu bool, opts ...Option) (rv []TimesStat, err error) {
+	return
+}
+
+func cpuClusterTypes(n int) []byte {
+	return nil
+}
@@ -0,0 +1,129 @@
+package cpuproc
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IdleStateStat contains the usage of a cpuidle (C-)state of a cpu since boot.
+// Latency and Time are in microseconds.
+// It is based on linux /sys/devices/system/cpu/cpu*/cpuidle/state*.
+type IdleStateStat struct {
+	CPU      int    `json:"cpu"`
+	State    int    `json:"state"`
+	Name     string `json:"name"`
+	Desc     string `json:"desc"`
+	Latency  uint64 `json:"latency"`
+	Time     uint64 `json:"time"`
+	Usage    uint64 `json:"usage"`
+	Disabled bool   `json:"disabled"`
+}
+
+// IdleResidencyStat contains the percent of an interval a cpu spent in an idle state.
+type IdleResidencyStat struct {
+	CPU       int     `json:"cpu"`
+	State     int     `json:"state"`
+	Name      string  `json:"name"`
+	Residency float64 `json:"residency"`
+	Usage     float64 `json:"usage"`
+}
+
+func IdleStates() ([]IdleStateStat, error) {
+	return IdleStatesWithContext(context.Background())
+}
+
+// IdleStatesWithContext returns the idle states of every cpu, sorted by cpu and state.
+func IdleStatesWithContext(ctx context.Context) ([]IdleStateStat, error) {
+	dirs, err := filepath.Glob(HostSysWithContext(ctx, "devices/system/cpu/cpu[0-9]*/cpuidle/state[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]IdleStateStat, 0, len(dirs))
+	for _, dir := range dirs {
+		cpu, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(filepath.Dir(filepath.Dir(dir))), "cpu"))
+		if err != nil {
+			continue
+		}
+		state, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "state"))
+		if err != nil {
+			continue
+		}
+
+		s := IdleStateStat{CPU: cpu, State: state}
+		if s.Name, err = readTrimmed(filepath.Join(dir, "name")); err != nil {
+			return nil, err
+		}
+		s.Desc, _ = readTrimmed(filepath.Join(dir, "desc"))
+		s.Latency, _ = readUint(filepath.Join(dir, "latency"))
+		if s.Time, err = readUint(filepath.Join(dir, "time")); err != nil {
+			return nil, err
+		}
+		if s.Usage, err = readUint(filepath.Join(dir, "usage")); err != nil {
+			return nil, err
+		}
+		if disabled, err := readUint(filepath.Join(dir, "disable")); err == nil {
+			s.Disabled = disabled != 0
+		}
+		ret = append(ret, s)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].CPU != ret[j].CPU {
+			return ret[i].CPU < ret[j].CPU
+		}
+		return ret[i].State < ret[j].State
+	})
+	return ret, nil
+}
+
+func IdleResidency(interval time.Duration) ([]IdleResidencyStat, error) {
+	return IdleResidencyWithContext(context.Background(), interval)
+}
+
+// IdleResidencyWithContext returns how much of interval every cpu spent in each idle state,
+// Usage is the number of entries per second.
+func IdleResidencyWithContext(ctx context.Context, interval time.Duration) ([]IdleResidencyStat, error) {
+	s1, err := IdleStatesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	if err := Sleep(ctx, interval); err != nil {
+		return nil, err
+	}
+
+	s2, err := IdleStatesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start).Seconds()
+
+	type key struct{ cpu, state int }
+	prev := make(map[key]IdleStateStat, len(s1))
+	for _, s := range s1 {
+		prev[key{s.CPU, s.State}] = s
+	}
+
+	ret := make([]IdleResidencyStat, 0, len(s2))
+	for _, s := range s2 {
+		p, ok := prev[key{s.CPU, s.State}]
+		if !ok {
+			continue
+		}
+		ret = append(ret, IdleResidencyStat{
+			CPU:       s.CPU,
+			State:     s.State,
+			Name:      s.Name,
+			Residency: math.Min(100, counterRate(p.Time, s.Time, elapsed)/1e6*100),
+			Usage:     counterRate(p.Usage, s.Usage, elapsed),
+		})
+	}
+	return ret, nil
+}
@@ -0,0 +1,75 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// throttlePeriod is the duty cycle of Throttle, the process runs part of every period
+// and is stopped for the rest of it.
+var throttlePeriod = 100 * time.Millisecond
+
+// Throttle keeps the cpu usage of pid under target percent, 100 being one cpu, by stopping
+// and continuing it with SIGSTOP and SIGCONT as cpulimit does. It is meant for when cgroups
+// can't be written, see (*Cgroup).SetCPULimit otherwise.
+// Throttle blocks until ctx is done and returns ctx.Err(), or nil when the process exits.
+// The process is always left running.
+func Throttle(ctx context.Context, pid int32, target float64) error {
+	if target <= 0 {
+		return errors.New("target must be positive")
+	}
+	p := NewProcess(pid)
+	last, err := p.TimesWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer unix.Kill(int(pid), unix.SIGCONT)
+
+	// the part of the period the process may run
+	work := 1.0
+	for {
+		start := time.Now()
+		run := time.Duration(work * float64(throttlePeriod))
+		if err := unix.Kill(int(pid), unix.SIGCONT); err != nil {
+			return processGone(err)
+		}
+		if err := Sleep(ctx, run); err != nil {
+			return err
+		}
+		if run < throttlePeriod {
+			if err := unix.Kill(int(pid), unix.SIGSTOP); err != nil {
+				return processGone(err)
+			}
+			if err := Sleep(ctx, throttlePeriod-run); err != nil {
+				return err
+			}
+		}
+
+		cur, err := p.TimesWithContext(ctx)
+		if err != nil {
+			return processGone(err)
+		}
+		elapsed := time.Since(start).Seconds()
+		usage := (cur.User + cur.System - last.User - last.System) / elapsed * 100
+		last = cur
+
+		if usage <= 0 {
+			// idle, let it run freely again
+			work = math.Min(1, work*2)
+			continue
+		}
+		work = math.Max(0.01, math.Min(1, work*target/usage))
+	}
+}
+
+// processGone turns the errors of a process that exited into nil.
+func processGone(err error) error {
+	if errors.Is(err, unix.ESRCH) || errors.Is(err, unix.ENOENT) {
+		return nil
+	}
+	return err
+}
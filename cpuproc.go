@@ -2,10 +2,12 @@ package cpuproc
 
 import (
 	"context"
-	"fmt"
 	"math"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 // TimesStat contains the amounts of time the CPU has spent performing different
@@ -31,22 +33,69 @@ func (c TimesStat) Total() float64 {
 	return total
 }
 
+// add returns the field-wise sum of c and o, the CPU name of c is kept.
+func (c TimesStat) add(o TimesStat) TimesStat {
+	c.User += o.User
+	c.System += o.System
+	c.Idle += o.Idle
+	c.Nice += o.Nice
+	c.Iowait += o.Iowait
+	c.Irq += o.Irq
+	c.Softirq += o.Softirq
+	c.Steal += o.Steal
+	c.Guest += o.Guest
+	c.GuestNice += o.GuestNice
+	return c
+}
+
+// cpuIndex returns the logical cpu number of a per-cpu TimesStat name like "cpu3".
+func cpuIndex(name string) (int, bool) {
+	if !strings.HasPrefix(name, "cpu") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(name[len("cpu"):])
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// calculateAllBusy returns the busy percents in the order of t2.
+// Cpus can go online or offline between the samples, in that case they are matched by name
+// and cpus without a previous sample are reported as 0.
 func calculateAllBusy(t1, t2 []TimesStat) ([]float64, error) {
-	// Make sure the CPU measurements have the same length.
-	if len(t1) != len(t2) {
-		return nil, fmt.Errorf(
-			"received two CPU counts: %d != %d",
-			len(t1), len(t2),
-		)
+	ret := make([]float64, len(t2))
+	if sameCPUs(t1, t2) {
+		for i, t := range t2 {
+			ret[i] = calculateBusy(t1[i], t)
+		}
+		return ret, nil
 	}
 
-	ret := make([]float64, len(t1))
+	prev := make(map[string]TimesStat, len(t1))
+	for _, t := range t1 {
+		prev[t.CPU] = t
+	}
 	for i, t := range t2 {
-		ret[i] = calculateBusy(t1[i], t)
+		if p, ok := prev[t.CPU]; ok {
+			ret[i] = calculateBusy(p, t)
+		}
 	}
 	return ret, nil
 }
 
+func sameCPUs(t1, t2 []TimesStat) bool {
+	if len(t1) != len(t2) {
+		return false
+	}
+	for i := range t1 {
+		if t1[i].CPU != t2[i].CPU {
+			return false
+		}
+	}
+	return true
+}
+
 func calculateBusy(t1, t2 TimesStat) float64 {
 	t1All, t1Busy := getAllBusy(t1)
 	t2All, t2Busy := getAllBusy(t2)
@@ -72,6 +121,120 @@ func getAllBusy(t TimesStat) (float64, float64) {
 	return tot, busy
 }
 
+// Option configures how Times and Percent sample the cpus.
+type Option func(*options)
+
+type options struct {
+	frequencyInvariant bool
+	excludeIsolated    bool
+	cpusetOnly         bool
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithFrequencyInvariance scales the busy time of every cpu by its current/max
+// frequency, so a core that is 100% busy at half of its max clock is reported
+// as 50%. Cpus without frequency information are left unscaled.
+func WithFrequencyInvariance() Option {
+	return func(o *options) {
+		o.frequencyInvariant = true
+	}
+}
+
+// WithoutIsolatedCPUs leaves the cpus isolated by isolcpus or nohz_full out of the
+// result, these usually run dedicated busy-polling workloads that would dominate
+// the aggregate utilisation.
+func WithoutIsolatedCPUs() Option {
+	return func(o *options) {
+		o.excludeIsolated = true
+	}
+}
+
+// Normalization is what the cpu usage of a process is divided by.
+type Normalization int
+
+const (
+	// NormalizeLogical divides by the cpus in the affinity set, the default
+	NormalizeLogical Normalization = iota
+	// NormalizePhysical divides by the physical cores behind the affinity set
+	NormalizePhysical
+	// NormalizeEffective divides by the affinity set capped by the cgroup cpuset and quota
+	NormalizeEffective
+	// NormalizeNone does not divide, 100% is one cpu fully used as in top
+	NormalizeNone
+)
+
+type processOptions struct {
+	normalization Normalization
+	// nil follows EnableBootTimeCache
+	bootTimeCache *bool
+}
+
+// ProcessOption configures the process returned by NewProcess.
+type ProcessOption func(*processOptions)
+
+func newProcessOptions(opts []ProcessOption) processOptions {
+	var o processOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithPhysicalCoreNormalization makes CPUPercent divide by the physical cores of the
+// affinity set instead of the logical cpus, so hyperthreads are not counted twice.
+func WithPhysicalCoreNormalization() ProcessOption {
+	return func(o *processOptions) {
+		o.normalization = NormalizePhysical
+	}
+}
+
+// WithEffectiveCPUNormalization makes CPUPercent divide by the cpus the process can
+// actually use, see EffectiveCPUs, so 100% means a container used its whole quota.
+func WithEffectiveCPUNormalization() ProcessOption {
+	return func(o *processOptions) {
+		o.normalization = NormalizeEffective
+	}
+}
+
+// WithNormalization sets what CPUPercent and Percent divide the usage of the process by.
+func WithNormalization(n Normalization) ProcessOption {
+	return func(o *processOptions) {
+		o.normalization = n
+	}
+}
+
+// Normalization returns what the cpu usage of the process is divided by.
+func (p *proc) Normalization() Normalization {
+	return p.opts.normalization
+}
+
+// WithBootTimeCache overrides EnableBootTimeCache for the create time of this process.
+func WithBootTimeCache(enable bool) ProcessOption {
+	return func(o *processOptions) {
+		o.bootTimeCache = &enable
+	}
+}
+
+// WithCpusetFilter keeps only the cpus of the cpuset of the current process's cgroup,
+// so per-cpu results inside a container are not full of unrelated host cpus.
+func WithCpusetFilter() Option {
+	return func(o *options) {
+		o.cpusetOnly = true
+	}
+}
+
+// Pid returns the process id.
+func (p *proc) Pid() int32 {
+	return p.pid
+}
+
 var (
 	lastCPUPercent lastPercent
 	// invoke         common.Invoker = common.Invoke{}
@@ -88,8 +251,32 @@ type lastPercent struct {
 	sync.Mutex
 	lastCPUTimes    []TimesStat
 	lastPerCPUTimes []TimesStat
+	// times of the calls filtering cpus, they only compare with calls using the same filters
+	filtered map[timesKey][]TimesStat
+}
+
+// timesKey is the set of options changing which cpus the times are summed over.
+type timesKey struct {
+	percpu          bool
+	excludeIsolated bool
+	cpusetOnly      bool
+}
+
+// Sleep awaits for provided interval.
+// Can be interrupted by context cancellation.
+func Sleep(ctx context.Context, interval time.Duration) error {
+	timer := time.NewTimer(interval)
+	select {
+	case <-ctx.Done():
+		if !timer.Stop() {
+			<-timer.C
+		}
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
 
-func Times(percpu bool) ([]TimesStat, error) {
-	return TimesWithContext(context.Background(), percpu)
+func Times(percpu bool, opts ...Option) ([]TimesStat, error) {
+	return TimesWithContext(context.Background(), percpu, opts...)
 }
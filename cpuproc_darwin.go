@@ -1,13 +1,13 @@
 package cpuproc
 
 import (
-	"context"
 	"time"
 )
 
 type proc struct {
 	// set unix.CPUSet
-	pid int32
+	pid  int32
+	opts processOptions
 }
 
 func (p *proc) CPUPercent() (float64, error) {
@@ -15,8 +15,8 @@ func (p *proc) CPUPercent() (float64, error) {
 }
 
 // 空函数
-func NewProcess(pid int32) *proc {
-	var p proc
+func NewProcess(pid int32, opts ...ProcessOption) *proc {
+	p := proc{opts: newProcessOptions(opts)}
 	// if err := unix.SchedGetaffinity(0, &p.set); err != nil {
 	// 	return nil
 	// }
@@ -27,7 +27,3 @@ func NewProcess(pid int32) *proc {
 func PercentTotal(interval time.Duration) (float64, error) {
 	return 0.0, nil
 }
-
-func TimesWithContext(ctx context.Context, percpu bool) (rv []TimesStat, err error) {
-	return
-}
@@ -7,7 +7,8 @@ import (
 
 type proc struct {
 	// set unix.CPUSet
-	pid int32
+	pid  int32
+	opts processOptions
 }
 
 func (p *proc) CPUPercent() (float64, error) {
@@ -15,8 +16,8 @@ func (p *proc) CPUPercent() (float64, error) {
 }
 
 // 空函数
-func NewProcess(pid int32) *proc {
-	var p proc
+func NewProcess(pid int32, opts ...ProcessOption) *proc {
+	p := proc{opts: newProcessOptions(opts)}
 	// if err := unix.SchedGetaffinity(0, &p.set); err != nil {
 	// 	return nil
 	// }
@@ -28,6 +29,6 @@ func PercentTotal(interval time.Duration) (float64, error) {
 	return 0.0, nil
 }
 
-func TimesWithContext(ctx context.Context, percpu bool) (rv []TimesStat, err error) {
+func TimesWithContext(ctx context.Context, percpu bool, opts ...Option) (rv []TimesStat, err error) {
 	return
 }
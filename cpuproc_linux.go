@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -17,8 +18,6 @@ const prioProcess = 0 // linux/resource.h
 
 var clockTicks = 100 // default value
 
-var enableBootTimeCache bool
-
 var ClocksPerSec = float64(100)
 
 type PageFaultsStat struct {
@@ -29,19 +28,64 @@ type PageFaultsStat struct {
 }
 
 type proc struct {
-	set unix.CPUSet
-	pid int32
+	set  unix.CPUSet
+	pid  int32
+	opts processOptions
+	// start time in clock ticks since boot, to tell a recycled pid apart
+	startTime uint64
+	// pidfd of the process when it was created by NewProcessFD
+	pidfd *os.File
 }
 
-func NewProcess(pid int32) *proc {
+func NewProcess(pid int32, opts ...ProcessOption) *proc {
 	var p proc
 	if err := unix.SchedGetaffinity(0, &p.set); err != nil {
 		return nil
 	}
 	p.pid = pid
+	p.opts = newProcessOptions(opts)
+	p.startTime, _ = p.startTimeWithContext(context.Background())
 	return &p
 }
 
+// startTimeWithContext returns the start time of the process in clock ticks since boot,
+// unlike the create time it does not depend on the boot time and can be compared exactly.
+func (p *proc) startTimeWithContext(ctx context.Context) (uint64, error) {
+	contents, err := os.ReadFile(p.procPath(ctx, "stat"))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(splitProcStat(contents)[22], 10, 64)
+}
+
+// IsRunning tells whether the process still exists and is the one NewProcess was given,
+// not another process that got the same pid after it exited.
+func (p *proc) IsRunning() (bool, error) {
+	return p.IsRunningWithContext(context.Background())
+}
+
+func (p *proc) IsRunningWithContext(ctx context.Context) (bool, error) {
+	if p.pidfd != nil {
+		return !p.pidfdExited(), nil
+	}
+	startTime, err := p.startTimeWithContext(ctx)
+	if err != nil {
+		if os.IsNotExist(err) || errors.Is(err, unix.ESRCH) {
+			return false, nil
+		}
+		return false, err
+	}
+	if p.startTime != 0 && startTime != p.startTime {
+		return false, nil
+	}
+	return true, nil
+}
+
+// procPath returns the path of a file of the process in /proc/<pid>.
+func (p *proc) procPath(ctx context.Context, elem ...string) string {
+	return HostProcWithContext(ctx, append([]string{strconv.Itoa(int(p.pid))}, elem...)...)
+}
+
 func parseStatLine(line string) (*TimesStat, error) {
 	fields := strings.Fields(line)
 
@@ -121,7 +165,13 @@ func parseStatLine(line string) (*TimesStat, error) {
 	return ct, nil
 }
 
-func TimesWithContext(ctx context.Context, percpu bool) ([]TimesStat, error) {
+// TimesWithContext returns the cpu times of /proc/stat, opts can leave some cpus out,
+// see WithoutIsolatedCPUs and WithCpusetFilter.
+func TimesWithContext(ctx context.Context, percpu bool, opts ...Option) ([]TimesStat, error) {
+	return newOptions(opts).times(ctx, percpu)
+}
+
+func readStatTimes(ctx context.Context, percpu bool) ([]TimesStat, error) {
 	filename := HostProcWithContext(ctx, "stat")
 	lines := []string{}
 	if percpu {
@@ -208,7 +258,7 @@ func (p *proc) fillFromTIDStatWithContext(ctx context.Context, tid int32) (uint6
 		Iowait: iotime / float64(clockTicks),
 	}
 
-	bootTime, _ := BootTimeWithContext(ctx, enableBootTimeCache)
+	bootTime, _ := p.bootTimeWithContext(ctx)
 	t, err := strconv.ParseUint(fields[22], 10, 64)
 	if err != nil {
 		return 0, 0, nil, 0, 0, 0, nil, err
@@ -229,7 +279,7 @@ func (p *proc) fillFromTIDStatWithContext(ctx context.Context, tid int32) (uint6
 	//	p.Nice = mustParseInt32(fields[18])
 	// use syscall instead of parse Stat file
 	snice, _ := unix.Getpriority(prioProcess, int(pid))
-	nice := int32(snice) // FIXME: is this true?
+	nice := int32(20 - snice) // see NiceWithContext
 
 	minFault, err := strconv.ParseUint(fields[10], 10, 64)
 	if err != nil {
@@ -270,22 +320,8 @@ func (p *proc) TimesWithContext(ctx context.Context) (*TimesStat, error) {
 	return cpuTimes, nil
 }
 
-// Sleep awaits for provided interval.
-// Can be interrupted by context cancellation.
-func Sleep(ctx context.Context, interval time.Duration) error {
-	timer := time.NewTimer(interval)
-	select {
-	case <-ctx.Done():
-		if !timer.Stop() {
-			<-timer.C
-		}
-		return ctx.Err()
-	case <-timer.C:
-		return nil
-	}
-}
-func Percent(interval time.Duration, percpu bool) ([]float64, error) {
-	return PercentWithContext(context.Background(), interval, percpu)
+func Percent(interval time.Duration, percpu bool, opts ...Option) ([]float64, error) {
+	return PercentWithContext(context.Background(), interval, percpu, opts...)
 }
 
 func PercentTotal(interval time.Duration) (float64, error) {
@@ -296,18 +332,26 @@ func PercentTotal(interval time.Duration) (float64, error) {
 	return rv[0], nil
 }
 
-func percentUsedFromLastCallWithContext(ctx context.Context, percpu bool) ([]float64, error) {
-	cpuTimes, err := TimesWithContext(ctx, percpu)
+func percentUsedFromLastCallWithContext(ctx context.Context, percpu bool, o *options) ([]float64, error) {
+	cpuTimes, err := o.times(ctx, percpu)
 	if err != nil {
 		return nil, err
 	}
 	lastCPUPercent.Lock()
 	defer lastCPUPercent.Unlock()
 	var lastTimes []TimesStat
-	if percpu {
+	switch {
+	case o.excludeIsolated || o.cpusetOnly:
+		key := timesKey{percpu: percpu, excludeIsolated: o.excludeIsolated, cpusetOnly: o.cpusetOnly}
+		if lastCPUPercent.filtered == nil {
+			lastCPUPercent.filtered = make(map[timesKey][]TimesStat)
+		}
+		lastTimes = lastCPUPercent.filtered[key]
+		lastCPUPercent.filtered[key] = cpuTimes
+	case percpu:
 		lastTimes = lastCPUPercent.lastPerCPUTimes
 		lastCPUPercent.lastPerCPUTimes = cpuTimes
-	} else {
+	default:
 		lastTimes = lastCPUPercent.lastCPUTimes
 		lastCPUPercent.lastCPUTimes = cpuTimes
 	}
@@ -315,16 +359,17 @@ func percentUsedFromLastCallWithContext(ctx context.Context, percpu bool) ([]flo
 	if lastTimes == nil {
 		return nil, fmt.Errorf("error getting times for cpu percent. lastTimes was nil")
 	}
-	return calculateAllBusy(lastTimes, cpuTimes)
+	return o.busy(ctx, lastTimes, cpuTimes)
 }
 
-func PercentWithContext(ctx context.Context, interval time.Duration, percpu bool) ([]float64, error) {
+func PercentWithContext(ctx context.Context, interval time.Duration, percpu bool, opts ...Option) ([]float64, error) {
+	o := newOptions(opts)
 	if interval <= 0 {
-		return percentUsedFromLastCallWithContext(ctx, percpu)
+		return percentUsedFromLastCallWithContext(ctx, percpu, o)
 	}
 
 	// Get CPU usage at the start of the interval.
-	cpuTimes1, err := TimesWithContext(ctx, percpu)
+	cpuTimes1, err := o.times(ctx, percpu)
 	if err != nil {
 		return nil, err
 	}
@@ -334,12 +379,87 @@ func PercentWithContext(ctx context.Context, interval time.Duration, percpu bool
 	}
 
 	// And at the end of the interval.
-	cpuTimes2, err := TimesWithContext(ctx, percpu)
+	cpuTimes2, err := o.times(ctx, percpu)
 	if err != nil {
 		return nil, err
 	}
 
-	return calculateAllBusy(cpuTimes1, cpuTimes2)
+	return o.busy(ctx, cpuTimes1, cpuTimes2)
+}
+
+// cpuFilter returns whether a cpu is kept by the options, it is nil when all of them are.
+func (o *options) cpuFilter(ctx context.Context) (func(cpu int) bool, error) {
+	if !o.excludeIsolated && !o.cpusetOnly {
+		return nil, nil
+	}
+
+	var exclude, include []int
+	if o.excludeIsolated {
+		iso, err := IsolatedCPUsWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		exclude = iso.CPUs()
+	}
+	if o.cpusetOnly {
+		c, err := SelfCgroupWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if include, err = c.CpusetWithContext(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return func(cpu int) bool {
+		return !slices.Contains(exclude, cpu) && (!o.cpusetOnly || slices.Contains(include, cpu))
+	}, nil
+}
+
+// times reads the cpu times and leaves out the cpus excluded by the options,
+// the aggregate is then summed from the remaining per-cpu times.
+func (o *options) times(ctx context.Context, percpu bool) ([]TimesStat, error) {
+	keep, err := o.cpuFilter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if keep == nil {
+		return readStatTimes(ctx, percpu)
+	}
+
+	cpuTimes, err := readStatTimes(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	kept := make([]TimesStat, 0, len(cpuTimes))
+	for _, t := range cpuTimes {
+		if cpu, ok := cpuIndex(t.CPU); ok && keep(cpu) {
+			kept = append(kept, t)
+		}
+	}
+	if percpu {
+		return kept, nil
+	}
+
+	total := TimesStat{CPU: "cpu-total"}
+	for _, t := range kept {
+		total = total.add(t)
+	}
+	return []TimesStat{total}, nil
+}
+
+// busy calculates the busy percents between two samples and applies the options to them.
+func (o *options) busy(ctx context.Context, t1, t2 []TimesStat) ([]float64, error) {
+	ret, err := calculateAllBusy(t1, t2)
+	if err != nil {
+		return nil, err
+	}
+	if o.frequencyInvariant {
+		// the percents stay unscaled when the kept cpus can't be read
+		if keep, err := o.cpuFilter(ctx); err == nil {
+			scaleByFrequency(ctx, t2, ret, keep)
+		}
+	}
+	return ret, nil
 }
 
 // CPUPercent returns how many percent of the CPU time this process uses
@@ -347,7 +467,21 @@ func (p *proc) cpuPercent() (float64, error) {
 	return p.CPUPercentWithContext(context.Background())
 }
 
-func (p *proc) createTimeWithContext(ctx context.Context) (int64, error) {
+// bootTimeWithContext returns the boot time the create time of the process is based on.
+func (p *proc) bootTimeWithContext(ctx context.Context) (uint64, error) {
+	enable := enableBootTimeCache.Load()
+	if p.opts.bootTimeCache != nil {
+		enable = *p.opts.bootTimeCache
+	}
+	return BootTimeWithContext(ctx, enable)
+}
+
+// CreateTime returns the start time of the process in milliseconds since the epoch.
+func (p *proc) CreateTime() (int64, error) {
+	return p.CreateTimeWithContext(context.Background())
+}
+
+func (p *proc) CreateTimeWithContext(ctx context.Context) (int64, error) {
 	_, _, _, createTime, _, _, _, err := p.fillFromStatWithContext(ctx)
 	if err != nil {
 		return 0, err
@@ -355,8 +489,21 @@ func (p *proc) createTimeWithContext(ctx context.Context) (int64, error) {
 	return createTime, nil
 }
 
+// Age returns how long the process has been running.
+func (p *proc) Age() (time.Duration, error) {
+	return p.AgeWithContext(context.Background())
+}
+
+func (p *proc) AgeWithContext(ctx context.Context) (time.Duration, error) {
+	createTime, err := p.CreateTimeWithContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return time.Since(time.UnixMilli(createTime)), nil
+}
+
 func (p *proc) CPUPercentWithContext(ctx context.Context) (float64, error) {
-	crt_time, err := p.createTimeWithContext(ctx)
+	crt_time, err := p.CreateTimeWithContext(ctx)
 	if err != nil {
 		return 0, err
 	}
@@ -377,7 +524,10 @@ func (p *proc) CPUPercentWithContext(ctx context.Context) (float64, error) {
 
 func (p *proc) CPUPercent() (float64, error) {
 
-	total := p.set.Count()
+	total, err := p.cpuCount(context.Background())
+	if err != nil {
+		return 0, err
+	}
 	// proc, err := process.NewProcess(p.pid)
 	// if err != nil {
 	// 	return 0, err
@@ -388,7 +538,63 @@ func (p *proc) CPUPercent() (float64, error) {
 	}
 
 	// fmt.Printf("total:%v, cpuPercent:%v\n", total, cpuPercent)
-	return cpuPercent / (float64(total) * float64(100)), nil
+	return cpuPercent / (total * float64(100)), nil
+}
+
+// Percent returns the cpu usage of the process over interval, like top does, divided by
+// the cpus of its Normalization: 100 means all of them were used.
+func (p *proc) Percent(interval time.Duration) (float64, error) {
+	return p.PercentWithContext(context.Background(), interval)
+}
+
+func (p *proc) PercentWithContext(ctx context.Context, interval time.Duration) (float64, error) {
+	t1, err := p.TimesWithContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+
+	if err := Sleep(ctx, interval); err != nil {
+		return 0, err
+	}
+
+	t2, err := p.TimesWithContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	elapsed := time.Since(start).Seconds()
+
+	total, err := p.cpuCount(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if elapsed <= 0 || total <= 0 {
+		return 0, nil
+	}
+	busy := t2.User + t2.System - t1.User - t1.System
+	return max(0, busy/elapsed*100/total), nil
+}
+
+// cpuCount returns the number of cpus CPUPercent and Percent are normalized by.
+func (p *proc) cpuCount(ctx context.Context) (float64, error) {
+	// read the affinity again, the one read by NewProcess may be stale. It is kept local as
+	// the process may be shared between goroutines.
+	set := p.set
+	var current unix.CPUSet
+	if err := unix.SchedGetaffinity(int(p.pid), &current); err == nil {
+		set = current
+	}
+	switch p.opts.normalization {
+	case NormalizePhysical:
+		n, err := physicalCoreCount(ctx, &set)
+		return float64(n), err
+	case NormalizeEffective:
+		return effectiveCPUs(ctx, strconv.Itoa(int(p.pid)), set.Count()), nil
+	case NormalizeNone:
+		return 1, nil
+	default:
+		return float64(set.Count()), nil
+	}
 }
 
 func splitProcStat(content []byte) []string {
@@ -1,9 +1,107 @@
 package cpuproc
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 )
 
+// newTestContext builds a fake root from files, the keys are relative to it
+// (e.g. "proc/stat", "sys/devices/system/cpu/online"), and returns a context
+// that points HOST_PROC, HOST_SYS, HOST_ETC and HOST_ROOT into it.
+func newTestContext(t *testing.T, files map[string]string) context.Context {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return context.WithValue(context.Background(), EnvKey, EnvMap{
+		"HOST_PROC": filepath.Join(root, "proc"),
+		"HOST_SYS":  filepath.Join(root, "sys"),
+		"HOST_ETC":  filepath.Join(root, "etc"),
+		"HOST_ROOT": root,
+	})
+}
+
 func Test_CPU(t *testing.T) {
 
 }
+
+func Test_percentUsedFromLastCall(t *testing.T) {
+	lastCPUPercent.Lock()
+	saved := lastCPUPercent.lastCPUTimes
+	lastCPUPercent.Unlock()
+	defer func() {
+		lastCPUPercent.Lock()
+		lastCPUPercent.lastCPUTimes = saved
+		lastCPUPercent.filtered = nil
+		lastCPUPercent.Unlock()
+	}()
+
+	statFile := func(cpu0, cpu1 int) string {
+		return fmt.Sprintf("cpu  %d 0 0 %d 0 0 0 0 0 0\ncpu0 %d 0 0 100 0 0 0 0 0 0\ncpu1 %d 0 0 100 0 0 0 0 0 0\n",
+			cpu0+cpu1, 200, cpu0, cpu1)
+	}
+	files := map[string]string{
+		"proc/stat":                        statFile(100, 100),
+		"sys/devices/system/cpu/isolated":  "1\n",
+		"sys/devices/system/cpu/nohz_full": "\n",
+	}
+	ctx := newTestContext(t, files)
+	unfiltered := newOptions(nil)
+	filtered := newOptions([]Option{WithoutIsolatedCPUs()})
+
+	if _, err := percentUsedFromLastCallWithContext(ctx, false, unfiltered); err != nil {
+		t.Fatal(err)
+	}
+	// the first filtered call has nothing to compare with, not the unfiltered times
+	if _, err := percentUsedFromLastCallWithContext(ctx, false, filtered); err == nil {
+		t.Error("filtered call compared with the unfiltered times")
+	}
+
+	// cpu0 becomes fully busy, the isolated cpu1 stays idle
+	files["proc/stat"] = statFile(200, 100)
+	ctx = newTestContext(t, files)
+	got, err := percentUsedFromLastCallWithContext(ctx, false, filtered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0] != 100 {
+		t.Errorf("filtered percent = %v, want 100", got[0])
+	}
+	if got, _ := percentUsedFromLastCallWithContext(ctx, false, unfiltered); got[0] != 100 {
+		t.Errorf("unfiltered percent = %v, want 100", got[0])
+	}
+}
+
+func Test_cpuCountShared(t *testing.T) {
+	// run with -race: a process shared between goroutines must not be written by cpuCount
+	p := NewProcess(int32(os.Getpid()))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if n, err := p.cpuCount(context.Background()); err != nil || n <= 0 {
+					t.Errorf("cpuCount() = %v, %v", n, err)
+					return
+				}
+				if _, err := p.Affinity(); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
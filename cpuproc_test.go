@@ -0,0 +1,124 @@
+package cpuproc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_calculateAllBusy(t *testing.T) {
+	t1 := []TimesStat{
+		{CPU: "cpu0", User: 10, Idle: 10},
+		{CPU: "cpu1", User: 10, Idle: 10},
+		{CPU: "cpu2", User: 10, Idle: 10},
+	}
+
+	t.Run("same cpus", func(t *testing.T) {
+		t2 := []TimesStat{
+			{CPU: "cpu0", User: 15, Idle: 15},
+			{CPU: "cpu1", User: 10, Idle: 20},
+			{CPU: "cpu2", User: 20, Idle: 10},
+		}
+		got, err := calculateAllBusy(t1, t2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []float64{50, 0, 100}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("cpu offline and online", func(t *testing.T) {
+		t2 := []TimesStat{
+			{CPU: "cpu0", User: 15, Idle: 15},
+			{CPU: "cpu2", User: 20, Idle: 10},
+			{CPU: "cpu3", User: 1, Idle: 1},
+		}
+		got, err := calculateAllBusy(t1, t2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []float64{50, 100, 0}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+}
+
+func Test_EWMA(t *testing.T) {
+	start := time.Unix(1000, 0)
+	e := NewEWMA(time.Second)
+	if got := e.Update(100, start); got != 100 {
+		t.Errorf("first update = %v, want 100", got)
+	}
+	// one half-life later the previous value weighs half
+	if got := e.Update(0, start.Add(time.Second)); got != 50 {
+		t.Errorf("after one half-life = %v, want 50", got)
+	}
+	// two half-lives weigh a quarter, whatever the number of updates
+	if got := e.Update(0, start.Add(3*time.Second)); got != 12.5 {
+		t.Errorf("after two more half-lives = %v, want 12.5", got)
+	}
+
+	e.Reset()
+	if got := e.Update(40, start); got != 40 {
+		t.Errorf("after reset = %v, want 40", got)
+	}
+	if got := NewEWMA(0).Update(7, start); got != 7 {
+		t.Errorf("without smoothing = %v, want 7", got)
+	}
+}
+
+func Test_History(t *testing.T) {
+	h, err := NewHistory(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := h.AverageOver(time.Minute); ok {
+		t.Error("AverageOver of an empty history is ok")
+	}
+
+	start := time.Unix(1000, 0)
+	for i, v := range []float64{90, 10, 20, 30} {
+		h.Add(Sample{Time: start.Add(time.Duration(i) * time.Second), Total: v})
+	}
+	var got []float64
+	for _, s := range h.Samples() {
+		got = append(got, s.Total)
+	}
+	if want := []float64{10, 20, 30}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Samples() = %v, want %v", got, want)
+	}
+	if avg, _ := h.AverageOver(1500 * time.Millisecond); avg != 25 {
+		t.Errorf("AverageOver() = %v, want 25", avg)
+	}
+	if s, _ := h.Max(); s.Total != 30 {
+		t.Errorf("Max() = %v, want 30", s.Total)
+	}
+	if r := h.Range(start, start.Add(2*time.Second)); len(r) != 2 {
+		t.Errorf("Range() returned %d samples, want 2", len(r))
+	}
+}
+
+func Test_Histogram(t *testing.T) {
+	h, err := NewHistogram(100 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := h.Percentile(50); ok {
+		t.Error("Percentile of an empty histogram is ok")
+	}
+
+	start := time.Unix(1000, 0)
+	// the first 100 values fall out of the window
+	for i := 0; i < 200; i++ {
+		v := float64(i%100) + 0.5
+		if i < 100 {
+			v = 100
+		}
+		h.ObserveValue(v, start.Add(time.Duration(i)*time.Second))
+	}
+	want := HistogramStat{Count: 100, P50: 50, P90: 90, P99: 99, Max: 100}
+	if got := h.Stat(); got != want {
+		t.Errorf("Stat() = %+v, want %+v", got, want)
+	}
+}
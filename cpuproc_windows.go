@@ -0,0 +1,239 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modkernel32        = windows.NewLazySystemDLL("kernel32.dll")
+	procGetSystemTimes = modkernel32.NewProc("GetSystemTimes")
+)
+
+// JOB_OBJECT_CPU_RATE_CONTROL_* flags
+const (
+	jobCPURateControlEnable      = 0x1
+	jobCPURateControlWeightBased = 0x2
+	jobCPURateControlHardCap     = 0x4
+	jobCPURateControlMinMaxRate  = 0x10
+)
+
+// jobCPURateControlInformation is JOBOBJECT_CPU_RATE_CONTROL_INFORMATION, Rate is either
+// CpuRate, Weight or MinRate and MaxRate packed in two words depending on ControlFlags.
+type jobCPURateControlInformation struct {
+	ControlFlags uint32
+	Rate         uint32
+}
+
+// JobCPURateStat is the cpu rate control of the job object of the current process.
+// Rate is the percent of the whole machine the job may use, 0 when it is not capped.
+// Weight is the relative share of weight based control, from 1 to 9.
+type JobCPURateStat struct {
+	Enabled bool    `json:"enabled"`
+	HardCap bool    `json:"hardCap"`
+	Rate    float64 `json:"rate"`
+	Weight  uint32  `json:"weight"`
+}
+
+// CPUs returns the cap as a number of cpus out of n, or 0 when the job is not capped.
+func (j JobCPURateStat) CPUs(n int) float64 {
+	if !j.Enabled || j.Rate <= 0 {
+		return 0
+	}
+	return j.Rate / 100 * float64(n)
+}
+
+type proc struct {
+	pid  int32
+	opts processOptions
+}
+
+func NewProcess(pid int32, opts ...ProcessOption) *proc {
+	return &proc{pid: pid, opts: newProcessOptions(opts)}
+}
+
+func JobCPURate() (*JobCPURateStat, error) {
+	return JobCPURateWithContext(context.Background())
+}
+
+// JobCPURateWithContext queries the cpu rate control of the job object the current process
+// belongs to, JobCPURateStat.Enabled is false outside of a job or when the job is not limited.
+func JobCPURateWithContext(ctx context.Context) (*JobCPURateStat, error) {
+	var info jobCPURateControlInformation
+	// a nil job handle is the job of the calling process
+	err := windows.QueryInformationJobObject(0, windows.JobObjectCpuRateControlInformation,
+		uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &JobCPURateStat{
+		Enabled: info.ControlFlags&jobCPURateControlEnable != 0,
+		HardCap: info.ControlFlags&jobCPURateControlHardCap != 0,
+	}
+	switch {
+	case info.ControlFlags&jobCPURateControlWeightBased != 0:
+		ret.Weight = info.Rate
+	case info.ControlFlags&jobCPURateControlMinMaxRate != 0:
+		// MaxRate is the high word, in 1/100 of a percent
+		ret.Rate = float64(info.Rate>>16) / 100
+	default:
+		ret.Rate = float64(info.Rate) / 100
+	}
+	return ret, nil
+}
+
+func (p *proc) open() (windows.Handle, error) {
+	return windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(p.pid))
+}
+
+func (p *proc) CPUPercentWithContext(ctx context.Context) (float64, error) {
+	h, err := p.open()
+	if err != nil {
+		return 0, err
+	}
+	defer windows.CloseHandle(h)
+
+	var creation, exit, kernel, user windows.Filetime
+	if err := windows.GetProcessTimes(h, &creation, &exit, &kernel, &user); err != nil {
+		return 0, err
+	}
+
+	totalTime := time.Since(time.Unix(0, creation.Nanoseconds())).Seconds()
+	if totalTime <= 0 {
+		return 0, nil
+	}
+	// kernel and user are durations in 100ns units, not dates
+	cpu := float64(filetimeTicks(kernel)+filetimeTicks(user)) / 1e7
+	return 100 * cpu / totalTime, nil
+}
+
+func (p *proc) CPUPercent() (float64, error) {
+	total, err := p.cpuCount(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	cpuPercent, err := p.CPUPercentWithContext(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	return cpuPercent / (total * float64(100)), nil
+}
+
+// cpuCount returns the number of cpus CPUPercent is normalized by, with effective
+// normalization it is capped by the cpu rate of the job object, like the cgroup quota on linux.
+func (p *proc) cpuCount(ctx context.Context) (float64, error) {
+	if p.opts.normalization == NormalizeNone {
+		return 1, nil
+	}
+	n := int(windows.GetActiveProcessorCount(windows.ALL_PROCESSOR_GROUPS))
+	if n == 0 {
+		return 0, errors.New("could not get the number of processors")
+	}
+	ret := float64(n)
+	if p.opts.normalization != NormalizeEffective || !p.sharesJob() {
+		return ret, nil
+	}
+	if rate, err := JobCPURateWithContext(ctx); err == nil && rate.CPUs(n) > 0 {
+		ret = math.Min(ret, rate.CPUs(n))
+	}
+	return ret, nil
+}
+
+// sharesJob tells whether the process is in the job of the current process, the only job
+// whose limits JobCPURate can query without a handle. IsProcessInJob can't tell it as it only
+// knows about any job when given no job handle, so the process ids of the job are listed.
+func (p *proc) sharesJob() bool {
+	pids, err := jobPids()
+	if err != nil {
+		return false
+	}
+	for _, pid := range pids {
+		if pid == uintptr(p.pid) {
+			return true
+		}
+	}
+	return false
+}
+
+// jobPids returns the process ids of the job of the current process, it fails outside of a job.
+func jobPids() ([]uintptr, error) {
+	for n := 64; ; n *= 2 {
+		// JOBOBJECT_BASIC_PROCESS_ID_LIST: two DWORD counts, padded to a ULONG_PTR on 64 bit,
+		// then the ids as ULONG_PTR
+		header := 8 / unsafe.Sizeof(uintptr(0))
+		buf := make([]uintptr, int(header)+n)
+		// a nil job handle is the job of the calling process
+		err := windows.QueryInformationJobObject(0, windows.JobObjectBasicProcessIdList,
+			uintptr(unsafe.Pointer(&buf[0])), uint32(uintptr(len(buf))*unsafe.Sizeof(buf[0])), nil)
+		if err == windows.ERROR_MORE_DATA {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		inList := *(*uint32)(unsafe.Add(unsafe.Pointer(&buf[0]), 4))
+		if int(inList) > n {
+			continue
+		}
+		return buf[header : int(header)+int(inList)], nil
+	}
+}
+
+func PercentTotal(interval time.Duration) (float64, error) {
+	ret, err := PercentWithContext(context.Background(), interval, false)
+	if err != nil {
+		return 0, err
+	}
+	return ret[0], nil
+}
+
+func PercentWithContext(ctx context.Context, interval time.Duration, percpu bool, opts ...Option) ([]float64, error) {
+	t1, err := TimesWithContext(ctx, percpu, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Sleep(ctx, interval); err != nil {
+		return nil, err
+	}
+	t2, err := TimesWithContext(ctx, percpu, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return calculateAllBusy(t1, t2)
+}
+
+// TimesWithContext returns the cpu times of the whole machine from GetSystemTimes,
+// per cpu times are not supported.
+func TimesWithContext(ctx context.Context, percpu bool, opts ...Option) ([]TimesStat, error) {
+	if percpu {
+		return nil, errors.New("per cpu times are not supported on windows")
+	}
+
+	var idle, kernel, user windows.Filetime
+	r, _, err := procGetSystemTimes.Call(
+		uintptr(unsafe.Pointer(&idle)),
+		uintptr(unsafe.Pointer(&kernel)),
+		uintptr(unsafe.Pointer(&user)))
+	if r == 0 {
+		return nil, err
+	}
+
+	// kernel time includes the idle time
+	idleSec := float64(filetimeTicks(idle)) / 1e7
+	return []TimesStat{{
+		CPU:    "cpu-total",
+		User:   float64(filetimeTicks(user)) / 1e7,
+		System: float64(filetimeTicks(kernel))/1e7 - idleSec,
+		Idle:   idleSec,
+	}}, nil
+}
+
+func filetimeTicks(ft windows.Filetime) uint64 {
+	return uint64(ft.HighDateTime)<<32 | uint64(ft.LowDateTime)
+}
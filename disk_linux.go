@@ -0,0 +1,127 @@
+package cpuproc
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// diskstats always counts in 512 byte sectors, whatever the device sector size
+const sectorSize = 512
+
+// DiskIOCountersStat contains the I/O counters of a block device since boot.
+// Times are in milliseconds. It is based on linux /proc/diskstats.
+type DiskIOCountersStat struct {
+	Name             string `json:"name"`
+	ReadCount        uint64 `json:"readCount"`
+	MergedReadCount  uint64 `json:"mergedReadCount"`
+	WriteCount       uint64 `json:"writeCount"`
+	MergedWriteCount uint64 `json:"mergedWriteCount"`
+	ReadBytes        uint64 `json:"readBytes"`
+	WriteBytes       uint64 `json:"writeBytes"`
+	ReadTime         uint64 `json:"readTime"`
+	WriteTime        uint64 `json:"writeTime"`
+	IopsInProgress   uint64 `json:"iopsInProgress"`
+	IoTime           uint64 `json:"ioTime"`
+	WeightedIO       uint64 `json:"weightedIO"`
+}
+
+// DiskUtilizationStat contains the activity of a block device over an interval.
+// Util is the percent of the interval the device was busy, the other fields are per second.
+type DiskUtilizationStat struct {
+	Name       string  `json:"name"`
+	Util       float64 `json:"util"`
+	ReadCount  float64 `json:"readCount"`
+	WriteCount float64 `json:"writeCount"`
+	ReadBytes  float64 `json:"readBytes"`
+	WriteBytes float64 `json:"writeBytes"`
+}
+
+// DiskIOCounters returns the counters of the named devices, or of all devices when no name is given.
+func DiskIOCounters(names ...string) (map[string]DiskIOCountersStat, error) {
+	return DiskIOCountersWithContext(context.Background(), names...)
+}
+
+func DiskIOCountersWithContext(ctx context.Context, names ...string) (map[string]DiskIOCountersStat, error) {
+	lines, err := ReadLines(HostProcWithContext(ctx, "diskstats"))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]DiskIOCountersStat, len(lines))
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 14 {
+			continue
+		}
+		name := fields[2]
+		if len(names) > 0 && !slices.Contains(names, name) {
+			continue
+		}
+
+		var v [11]uint64
+		for i := range v {
+			if v[i], err = strconv.ParseUint(fields[i+3], 10, 64); err != nil {
+				return nil, err
+			}
+		}
+		ret[name] = DiskIOCountersStat{
+			Name:             name,
+			ReadCount:        v[0],
+			MergedReadCount:  v[1],
+			ReadBytes:        v[2] * sectorSize,
+			ReadTime:         v[3],
+			WriteCount:       v[4],
+			MergedWriteCount: v[5],
+			WriteBytes:       v[6] * sectorSize,
+			WriteTime:        v[7],
+			IopsInProgress:   v[8],
+			IoTime:           v[9],
+			WeightedIO:       v[10],
+		}
+	}
+	return ret, nil
+}
+
+func DiskUtilization(interval time.Duration, names ...string) (map[string]DiskUtilizationStat, error) {
+	return DiskUtilizationWithContext(context.Background(), interval, names...)
+}
+
+// DiskUtilizationWithContext samples the devices twice, interval apart, like `iostat -x`.
+func DiskUtilizationWithContext(ctx context.Context, interval time.Duration, names ...string) (map[string]DiskUtilizationStat, error) {
+	d1, err := DiskIOCountersWithContext(ctx, names...)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	if err := Sleep(ctx, interval); err != nil {
+		return nil, err
+	}
+
+	d2, err := DiskIOCountersWithContext(ctx, names...)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start).Seconds()
+
+	ret := make(map[string]DiskUtilizationStat, len(d2))
+	for name, c := range d2 {
+		p, ok := d1[name]
+		if !ok {
+			continue
+		}
+		ret[name] = DiskUtilizationStat{
+			Name:       name,
+			Util:       math.Min(100, counterRate(p.IoTime, c.IoTime, elapsed)/1000*100),
+			ReadCount:  counterRate(p.ReadCount, c.ReadCount, elapsed),
+			WriteCount: counterRate(p.WriteCount, c.WriteCount, elapsed),
+			ReadBytes:  counterRate(p.ReadBytes, c.ReadBytes, elapsed),
+			WriteBytes: counterRate(p.WriteBytes, c.WriteBytes, elapsed),
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,93 @@
+package cpuproc
+
+import (
+	"math"
+	"time"
+)
+
+// EWMA is an exponentially weighted moving average of cpu percents. The weight of a value
+// halves every half-life, whatever the interval between the updates. It is not safe for
+// concurrent use.
+type EWMA struct {
+	halfLife time.Duration
+	value    float64
+	at       time.Time
+}
+
+// NewEWMA returns an EWMA with the given half-life, a non positive one disables the smoothing.
+func NewEWMA(halfLife time.Duration) *EWMA {
+	return &EWMA{halfLife: halfLife}
+}
+
+// Update adds v measured at at and returns the smoothed value. The first value is returned as
+// is, values older than the previous one replace it.
+func (e *EWMA) Update(v float64, at time.Time) float64 {
+	if e.at.IsZero() || e.halfLife <= 0 || !at.After(e.at) {
+		e.value, e.at = v, at
+		return v
+	}
+	alpha := 1 - math.Exp2(-float64(at.Sub(e.at))/float64(e.halfLife))
+	e.value += alpha * (v - e.value)
+	e.at = at
+	return e.value
+}
+
+// Value returns the smoothed value, 0 before the first Update.
+func (e *EWMA) Value() float64 {
+	return e.value
+}
+
+// Reset forgets the previous values.
+func (e *EWMA) Reset() {
+	e.value, e.at = 0, time.Time{}
+}
+
+// sampleSmoother smooths every value of consecutive samples.
+type sampleSmoother struct {
+	halfLife time.Duration
+	total    *EWMA
+	perCPU   map[string]*EWMA
+	procs    map[int32]*EWMA
+}
+
+func newSampleSmoother(halfLife time.Duration) *sampleSmoother {
+	return &sampleSmoother{halfLife: halfLife, total: NewEWMA(halfLife), perCPU: make(map[string]*EWMA), procs: make(map[int32]*EWMA)}
+}
+
+func (m *sampleSmoother) reset() {
+	m.total.Reset()
+	m.perCPU = make(map[string]*EWMA)
+	m.procs = make(map[int32]*EWMA)
+}
+
+// smooth replaces the values of s by their smoothed ones, processes that are gone from s are
+// forgotten.
+func (m *sampleSmoother) smooth(s *Sample) {
+	s.Total = m.total.Update(s.Total, s.Time)
+
+	// cpus that went offline are forgotten
+	perCPU := make(map[string]*EWMA, len(s.PerCPU))
+	for i, c := range s.PerCPU {
+		e, ok := m.perCPU[c.CPU]
+		if !ok {
+			e = NewEWMA(m.halfLife)
+		}
+		perCPU[c.CPU] = e
+		s.PerCPU[i].Percent = e.Update(c.Percent, s.Time)
+	}
+	m.perCPU = perCPU
+
+	for pid := range m.procs {
+		if _, ok := s.Processes[pid]; !ok {
+			delete(m.procs, pid)
+		}
+	}
+	for pid, v := range s.Processes {
+		e, ok := m.procs[pid]
+		if !ok {
+			e = NewEWMA(m.halfLife)
+			m.procs[pid] = e
+		}
+		s.Processes[pid] = e.Update(v, s.Time)
+	}
+}
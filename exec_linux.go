@@ -0,0 +1,143 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// CmdStat is a sample of a running command. Percent is its cpu usage since the previous
+// sample, 100 being one cpu, CPU the seconds of cpu it used so far. Memory is in bytes.
+type CmdStat struct {
+	Percent float64 `json:"percent"`
+	CPU     float64 `json:"cpu"`
+	RSS     uint64  `json:"rss"`
+	PeakRSS uint64  `json:"peakRSS"`
+}
+
+// CmdResult is the resource usage of a command once it exited, from its rusage. Children
+// the command waited for are included. Percent is the average cpu usage over Duration.
+type CmdResult struct {
+	Duration time.Duration `json:"duration"`
+	User     float64       `json:"user"`
+	System   float64       `json:"system"`
+	Percent  float64       `json:"percent"`
+	PeakRSS  uint64        `json:"peakRSS"`
+	ExitCode int           `json:"exitCode"`
+}
+
+// MonitoredCmd is a command started by StartMonitored. Stats receives a sample every
+// interval and is closed when the command exits, Wait then returns the final usage.
+type MonitoredCmd struct {
+	Cmd   *exec.Cmd
+	Stats <-chan CmdStat
+
+	start  time.Time
+	done   chan struct{}
+	result *CmdResult
+	err    error
+}
+
+// StartMonitored starts cmd and samples its cpu and memory usage every interval, for per step
+// resource reports of build pipelines. cmd must not be waited for, use (*MonitoredCmd).Wait.
+// Samples are skipped while nobody reads Stats.
+func StartMonitored(cmd *exec.Cmd, interval time.Duration) (*MonitoredCmd, error) {
+	if interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	ch := make(chan CmdStat)
+	m := &MonitoredCmd{Cmd: cmd, Stats: ch, start: start, done: make(chan struct{})}
+	exited := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(ch)
+		m.sample(ch, exited, interval)
+	}()
+	go func() {
+		err := cmd.Wait()
+		close(exited)
+		wg.Wait()
+		m.finish(err)
+		close(m.done)
+	}()
+	return m, nil
+}
+
+// Wait waits for the command to exit and returns its resource usage. The error is the one of
+// (*exec.Cmd).Wait, the usage is still returned when the command failed.
+func (m *MonitoredCmd) Wait() (*CmdResult, error) {
+	<-m.done
+	return m.result, m.err
+}
+
+func (m *MonitoredCmd) sample(ch chan<- CmdStat, exited <-chan struct{}, interval time.Duration) {
+	ctx := context.Background()
+	p := &proc{pid: int32(m.Cmd.Process.Pid)}
+	var lastCPU float64
+	last := m.start
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-exited:
+			return
+		case <-ticker.C:
+		}
+
+		times, err := p.TimesWithContext(ctx)
+		if err != nil {
+			continue
+		}
+		st, err := p.StatusWithContext(ctx)
+		if err != nil {
+			continue
+		}
+		now := time.Now()
+		cpu := times.User + times.System
+		s := CmdStat{CPU: cpu, RSS: st.VmRSS, PeakRSS: st.VmHWM}
+		if elapsed := now.Sub(last).Seconds(); elapsed > 0 && cpu >= lastCPU {
+			s.Percent = (cpu - lastCPU) / elapsed * 100
+		}
+		lastCPU, last = cpu, now
+
+		select {
+		case ch <- s:
+		case <-exited:
+			return
+		default:
+		}
+	}
+}
+
+func (m *MonitoredCmd) finish(err error) {
+	m.err = err
+	state := m.Cmd.ProcessState
+	if state == nil {
+		return
+	}
+	r := &CmdResult{
+		Duration: time.Since(m.start),
+		User:     state.UserTime().Seconds(),
+		System:   state.SystemTime().Seconds(),
+		ExitCode: state.ExitCode(),
+	}
+	if d := r.Duration.Seconds(); d > 0 {
+		r.Percent = (r.User + r.System) / d * 100
+	}
+	if ru, ok := state.SysUsage().(*syscall.Rusage); ok {
+		// ru_maxrss is in kilobytes on linux
+		r.PeakRSS = uint64(ru.Maxrss) * 1024
+	}
+	m.result = r
+}
@@ -0,0 +1,53 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"os"
+	"sync"
+)
+
+// ExpvarName is the name PublishExpvar publishes the cpu metrics under.
+const ExpvarName = "cpuproc"
+
+var expvarMu sync.Mutex
+
+// ExpvarStat is the value published by PublishExpvar. Sample is the latest sample of the
+// machine and the current process, Times the cpu seconds used by the current process.
+type ExpvarStat struct {
+	Sample
+	Self  float64    `json:"self"`
+	Times *TimesStat `json:"times"`
+}
+
+// PublishExpvar publishes the cpu usage of the machine and of the current process under
+// ExpvarName, so /debug/vars shows it. A Monitor samples them in the background with opts,
+// per cpu sampling is on by default. It runs for the life of the process, the metrics can
+// only be published once.
+func PublishExpvar(opts ...MonitorOption) error {
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	if expvar.Get(ExpvarName) != nil {
+		return errors.New("cpuproc expvar already published")
+	}
+
+	self := int32(os.Getpid())
+	m, err := NewMonitor(append([]MonitorOption{
+		WithPerCPU(true),
+		WithProcesses([]int32{self}),
+	}, opts...)...)
+	if err != nil {
+		return err
+	}
+	p := NewProcess(self)
+	expvar.Publish(ExpvarName, expvar.Func(func() any {
+		ret := ExpvarStat{Sample: m.Latest()}
+		ret.Self = ret.Processes[self]
+		if p != nil {
+			ret.Times, _ = p.TimesWithContext(context.Background())
+		}
+		return ret
+	}))
+	return nil
+}
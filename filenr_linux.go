@@ -0,0 +1,73 @@
+package cpuproc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FileNrStat contains the system wide file handle and inode usage.
+// It is based on linux /proc/sys/fs/file-nr and /proc/sys/fs/inode-nr.
+type FileNrStat struct {
+	Allocated   uint64  `json:"allocated"`
+	Free        uint64  `json:"free"`
+	Used        uint64  `json:"used"`
+	Max         uint64  `json:"max"`
+	UsedPercent float64 `json:"usedPercent"`
+	Inodes      uint64  `json:"inodes"`
+	FreeInodes  uint64  `json:"freeInodes"`
+}
+
+func FileNr() (*FileNrStat, error) {
+	return FileNrWithContext(context.Background())
+}
+
+func FileNrWithContext(ctx context.Context) (*FileNrStat, error) {
+	files, err := readUintFields(HostProcWithContext(ctx, "sys/fs/file-nr"), 3)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &FileNrStat{
+		Allocated: files[0],
+		Free:      files[1],
+		Max:       files[2],
+	}
+	// free is always 0 since linux 2.6, allocated handles are in use
+	if ret.Free <= ret.Allocated {
+		ret.Used = ret.Allocated - ret.Free
+	}
+	if ret.Max > 0 {
+		ret.UsedPercent = float64(ret.Used) / float64(ret.Max) * 100
+	}
+
+	inodes, err := readUintFields(HostProcWithContext(ctx, "sys/fs/inode-nr"), 2)
+	if err != nil {
+		return nil, err
+	}
+	ret.Inodes = inodes[0]
+	ret.FreeInodes = inodes[1]
+
+	return ret, nil
+}
+
+// readUintFields reads a single line file made of at least n unsigned numbers.
+func readUintFields(filename string, n int) ([]uint64, error) {
+	line, err := readTrimmed(filename)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(line)
+	if len(fields) < n {
+		return nil, fmt.Errorf("wrong %s format", filename)
+	}
+
+	ret := make([]uint64, n)
+	for i := range ret {
+		if ret[i], err = strconv.ParseUint(fields[i], 10, 64); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
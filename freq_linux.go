@@ -0,0 +1,198 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FrequencyStat contains the clock of a logical CPU. Frequencies are in MHz.
+// It is based on linux /sys/devices/system/cpu/cpu*/cpufreq, falling back
+// to /proc/cpuinfo when cpufreq is not available (e.g. most virtual machines).
+type FrequencyStat struct {
+	CPU      int     `json:"cpu"`
+	Current  float64 `json:"current"`
+	Min      float64 `json:"min"`
+	Max      float64 `json:"max"`
+	Governor string  `json:"governor"`
+}
+
+func Frequency() ([]FrequencyStat, error) {
+	return FrequencyWithContext(context.Background())
+}
+
+// FrequencyWithContext returns the frequency of every logical CPU, sorted by CPU id.
+func FrequencyWithContext(ctx context.Context) ([]FrequencyStat, error) {
+	dirs, err := filepath.Glob(HostSysWithContext(ctx, "devices/system/cpu/cpu[0-9]*/cpufreq"))
+	if err != nil {
+		return nil, err
+	}
+
+	var cpuinfoMHz map[int]float64
+	ret := make([]FrequencyStat, 0, len(dirs))
+	for _, dir := range dirs {
+		cpu, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(filepath.Dir(dir)), "cpu"))
+		if err != nil {
+			continue
+		}
+
+		f := FrequencyStat{CPU: cpu}
+		f.Current = readKHz(filepath.Join(dir, "scaling_cur_freq"))
+		f.Min = readKHz(filepath.Join(dir, "scaling_min_freq"))
+		f.Max = readKHz(filepath.Join(dir, "scaling_max_freq"))
+		if f.Max == 0 {
+			f.Max = readKHz(filepath.Join(dir, "cpuinfo_max_freq"))
+		}
+		f.Governor, _ = readTrimmed(filepath.Join(dir, "scaling_governor"))
+
+		if f.Current == 0 {
+			// some drivers (e.g. intel_pstate passive) do not report scaling_cur_freq
+			if cpuinfoMHz == nil {
+				cpuinfoMHz, _ = readCPUInfoMHz(ctx)
+			}
+			f.Current = cpuinfoMHz[cpu]
+		}
+		ret = append(ret, f)
+	}
+
+	if len(ret) == 0 {
+		mhz, err := readCPUInfoMHz(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for cpu, cur := range mhz {
+			ret = append(ret, FrequencyStat{CPU: cpu, Current: cur})
+		}
+	}
+
+	if len(ret) == 0 {
+		return nil, errors.New("could not find cpu frequency")
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].CPU < ret[j].CPU })
+	return ret, nil
+}
+
+// WatchFrequency polls the cpu frequencies every interval and sends them on the returned
+// channel. The channel is closed when ctx is done.
+func WatchFrequency(ctx context.Context, interval time.Duration) (<-chan []FrequencyStat, error) {
+	if interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+	// fail early if the frequencies can't be read at all
+	if _, err := FrequencyWithContext(ctx); err != nil {
+		return nil, err
+	}
+
+	ch := make(chan []FrequencyStat)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			freqs, err := FrequencyWithContext(ctx)
+			if err != nil {
+				continue
+			}
+			select {
+			case ch <- freqs:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// scaleByFrequency multiplies every busy percent by the current/max frequency of its cpu.
+// The aggregate "cpu-total" entry is scaled by the sum of current over the sum of max frequencies
+// of the cpus kept by keep, all of them when it is nil. The percents are left unscaled when the
+// frequencies can't be read.
+func scaleByFrequency(ctx context.Context, times []TimesStat, busy []float64, keep func(cpu int) bool) {
+	freqs, err := FrequencyWithContext(ctx)
+	if err != nil {
+		return
+	}
+
+	ratio := make(map[int]float64, len(freqs))
+	var curSum, maxSum float64
+	for _, f := range freqs {
+		if f.Max <= 0 || f.Current <= 0 {
+			continue
+		}
+		ratio[f.CPU] = math.Min(1, f.Current/f.Max)
+		if keep == nil || keep(f.CPU) {
+			curSum += f.Current
+			maxSum += f.Max
+		}
+	}
+
+	for i, t := range times {
+		if t.CPU == "cpu-total" {
+			if maxSum > 0 {
+				busy[i] *= math.Min(1, curSum/maxSum)
+			}
+			continue
+		}
+		cpu, ok := cpuIndex(t.CPU)
+		if !ok {
+			continue
+		}
+		if r, ok := ratio[cpu]; ok {
+			busy[i] *= r
+		}
+	}
+}
+
+// readKHz reads a cpufreq value in kHz and converts it to MHz, missing files are reported as 0.
+func readKHz(filename string) float64 {
+	v, err := readUint(filename)
+	if err != nil {
+		return 0
+	}
+	return float64(v) / 1000
+}
+
+// readCPUInfoMHz returns the "cpu MHz" entries of /proc/cpuinfo keyed by processor number.
+func readCPUInfoMHz(ctx context.Context) (map[int]float64, error) {
+	lines, err := ReadLines(HostProcWithContext(ctx, "cpuinfo"))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[int]float64)
+	processor := -1
+	for _, line := range lines {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		switch key {
+		case "processor":
+			if processor, err = strconv.Atoi(value); err != nil {
+				processor = -1
+			}
+		case "cpu MHz":
+			if processor < 0 {
+				continue
+			}
+			if mhz, err := strconv.ParseFloat(value, 64); err == nil {
+				ret[processor] = mhz
+			}
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,39 @@
+package cpuproc
+
+import "testing"
+
+func Test_scaleByFrequency(t *testing.T) {
+	ctx := newTestContext(t, map[string]string{
+		"sys/devices/system/cpu/cpu0/cpufreq/scaling_cur_freq": "1000000\n",
+		"sys/devices/system/cpu/cpu0/cpufreq/scaling_max_freq": "2000000\n",
+		"sys/devices/system/cpu/cpu1/cpufreq/scaling_cur_freq": "2000000\n",
+		"sys/devices/system/cpu/cpu1/cpufreq/scaling_max_freq": "2000000\n",
+	})
+	times := []TimesStat{{CPU: "cpu-total"}}
+
+	busy := []float64{100}
+	scaleByFrequency(ctx, times, busy, nil)
+	if busy[0] != 75 {
+		t.Errorf("all cpus: got %v, want 75", busy[0])
+	}
+
+	// cpu0 is filtered out, the total only runs on cpu1
+	busy = []float64{100}
+	scaleByFrequency(ctx, times, busy, func(cpu int) bool { return cpu == 1 })
+	if busy[0] != 100 {
+		t.Errorf("cpu1 only: got %v, want 100", busy[0])
+	}
+
+	busy = []float64{100, 100}
+	scaleByFrequency(ctx, []TimesStat{{CPU: "cpu0"}, {CPU: "cpu1"}}, busy, nil)
+	if busy[0] != 50 || busy[1] != 100 {
+		t.Errorf("per cpu: got %v, want [50 100]", busy)
+	}
+
+	// without frequencies the percents are left as is
+	busy = []float64{100}
+	scaleByFrequency(newTestContext(t, nil), times, busy, nil)
+	if busy[0] != 100 {
+		t.Errorf("no frequency: got %v, want 100", busy[0])
+	}
+}
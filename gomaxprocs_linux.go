@@ -0,0 +1,63 @@
+package cpuproc
+
+import (
+	"context"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// gomaxprocsPollInterval is how often SetGOMAXPROCS looks for a resized cgroup
+var gomaxprocsPollInterval = 10 * time.Second
+
+// SetGOMAXPROCS sets runtime.GOMAXPROCS to the cpus the process can use (see EffectiveCPUs,
+// rounded down, at least 1) and keeps it in sync when the cgroup limit is resized, until ctx
+// is done. The returned function stops watching and restores the previous GOMAXPROCS.
+func SetGOMAXPROCS(ctx context.Context) (func(), error) {
+	procs, err := effectiveGOMAXPROCS(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prev := runtime.GOMAXPROCS(procs)
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(gomaxprocsPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			n, err := effectiveGOMAXPROCS(ctx)
+			if err != nil || n == procs {
+				continue
+			}
+			procs = n
+			runtime.GOMAXPROCS(n)
+		}
+	}()
+
+	var once sync.Once
+	undo := func() {
+		once.Do(func() {
+			cancel()
+			<-done
+			runtime.GOMAXPROCS(prev)
+		})
+	}
+	return undo, nil
+}
+
+func effectiveGOMAXPROCS(ctx context.Context) (int, error) {
+	cpus, err := EffectiveCPUsWithContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return max(1, int(math.Floor(cpus))), nil
+}
@@ -0,0 +1,106 @@
+// Package grpcoverload sheds grpc requests with RESOURCE_EXHAUSTED when the cpu is overloaded,
+// as decided by a cpuproc.OverloadProtector.
+package grpcoverload
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/antlabs/cpuproc"
+)
+
+// Priority of a method, critical methods are never shed.
+type Priority int
+
+const (
+	// Normal methods are shed following the ramp of the OverloadProtector.
+	Normal Priority = iota
+	// Critical methods are never shed, e.g. health checks and admin rpcs.
+	Critical
+	// Sheddable methods are shed as soon as the cpu is above the threshold.
+	Sheddable
+)
+
+// PriorityFunc returns the priority of a call to fullMethod, e.g. "/pkg.Service/Method".
+type PriorityFunc func(ctx context.Context, fullMethod string) Priority
+
+// DefaultPriority makes the methods of the grpc health and reflection services critical and
+// the others normal.
+func DefaultPriority(ctx context.Context, fullMethod string) Priority {
+	for _, prefix := range []string{
+		"/grpc.health.v1.Health/",
+		"/grpc.reflection.v1.ServerReflection/",
+		"/grpc.reflection.v1alpha.ServerReflection/",
+	} {
+		if strings.HasPrefix(fullMethod, prefix) {
+			return Critical
+		}
+	}
+	return Normal
+}
+
+type options struct {
+	priority PriorityFunc
+}
+
+// Option configures the interceptors.
+type Option func(*options)
+
+// WithPriority sets how calls are prioritised, DefaultPriority by default.
+func WithPriority(fn PriorityFunc) Option {
+	return func(o *options) {
+		o.priority = fn
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{priority: DefaultPriority}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// shed returns the error of a shed call, nil when the call may proceed.
+func (o options) shed(ctx context.Context, p *cpuproc.OverloadProtector, fullMethod string) error {
+	var shed bool
+	switch o.priority(ctx, fullMethod) {
+	case Critical:
+		return nil
+	case Sheddable:
+		shed = p.Overloaded()
+	default:
+		shed = p.Shed()
+	}
+	if !shed {
+		return nil
+	}
+	return status.Errorf(codes.ResourceExhausted, "cpu overloaded, retry after %v", p.RetryAfter())
+}
+
+// UnaryServerInterceptor sheds unary calls when p says the cpu is overloaded.
+func UnaryServerInterceptor(p *cpuproc.OverloadProtector, opts ...Option) grpc.UnaryServerInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := o.shed(ctx, p, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor sheds streams when p says the cpu is overloaded, a stream is only
+// checked when it starts.
+func StreamServerInterceptor(p *cpuproc.OverloadProtector, opts ...Option) grpc.StreamServerInterceptor {
+	o := newOptions(opts)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := o.shed(ss.Context(), p, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
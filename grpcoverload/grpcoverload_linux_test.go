@@ -0,0 +1,118 @@
+package grpcoverload
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/antlabs/cpuproc"
+)
+
+func TestDefaultPriority(t *testing.T) {
+	for method, want := range map[string]Priority{
+		"/grpc.health.v1.Health/Check":                    Critical,
+		"/grpc.reflection.v1.ServerReflection/ServerInfo": Critical,
+		"/pkg.Service/Method":                             Normal,
+	} {
+		if got := DefaultPriority(context.Background(), method); got != want {
+			t.Errorf("DefaultPriority(%q) = %v, want %v", method, got, want)
+		}
+	}
+}
+
+// newProtector returns an OverloadProtector reading the cpu from *cpu, shedding everything
+// above 80 percent.
+func newProtector(t *testing.T, cpu *float64) *cpuproc.OverloadProtector {
+	t.Helper()
+	p, err := cpuproc.NewOverloadProtector(
+		cpuproc.WithOverloadThreshold(80),
+		cpuproc.WithOverloadCPUSource(func() float64 { return *cpu }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Stop)
+	return p
+}
+
+func priorities(ctx context.Context, fullMethod string) Priority {
+	switch fullMethod {
+	case "/pkg.Service/Critical":
+		return Critical
+	case "/pkg.Service/Sheddable":
+		return Sheddable
+	}
+	return Normal
+}
+
+var interceptorTests = []struct {
+	method string
+	cpu    float64
+	shed   bool
+}{
+	{"/pkg.Service/Normal", 50, false},
+	{"/pkg.Service/Sheddable", 50, false},
+	{"/pkg.Service/Critical", 50, false},
+	{"/pkg.Service/Normal", 95, true},
+	{"/pkg.Service/Sheddable", 95, true},
+	{"/pkg.Service/Critical", 95, false},
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	var cpu float64
+	i := UnaryServerInterceptor(newProtector(t, &cpu), WithPriority(priorities))
+
+	for _, tc := range interceptorTests {
+		cpu = tc.cpu
+		called := false
+		handler := func(ctx context.Context, req any) (any, error) {
+			called = true
+			return req, nil
+		}
+		resp, err := i(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: tc.method}, handler)
+		if tc.shed {
+			if status.Code(err) != codes.ResourceExhausted || called {
+				t.Errorf("%s at %v%%: got %v, called %v, want ResourceExhausted", tc.method, tc.cpu, err, called)
+			}
+			continue
+		}
+		if err != nil || resp != "req" || !called {
+			t.Errorf("%s at %v%%: got %v, %v, called %v", tc.method, tc.cpu, resp, err, called)
+		}
+	}
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+}
+
+func (testServerStream) Context() context.Context {
+	return context.Background()
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	var cpu float64
+	i := StreamServerInterceptor(newProtector(t, &cpu), WithPriority(priorities))
+
+	for _, tc := range interceptorTests {
+		cpu = tc.cpu
+		called := false
+		handler := func(srv any, ss grpc.ServerStream) error {
+			called = true
+			return nil
+		}
+		err := i(nil, testServerStream{}, &grpc.StreamServerInfo{FullMethod: tc.method}, handler)
+		if tc.shed {
+			if status.Code(err) != codes.ResourceExhausted || called {
+				t.Errorf("%s at %v%%: got %v, called %v, want ResourceExhausted", tc.method, tc.cpu, err, called)
+			}
+			continue
+		}
+		if err != nil || !called {
+			t.Errorf("%s at %v%%: got %v, called %v", tc.method, tc.cpu, err, called)
+		}
+	}
+}
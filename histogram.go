@@ -0,0 +1,130 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// HistogramStat is the distribution of the cpu usage over the window of a Histogram, in
+// percents rounded up to the next whole percent.
+type HistogramStat struct {
+	Count uint64  `json:"count"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+	Max   float64 `json:"max"`
+}
+
+type histogramValue struct {
+	bucket int
+	at     time.Time
+}
+
+// Histogram is the distribution of the cpu usage over a sliding window, for load shedding on
+// tail utilisation rather than on the latest value. Values are counted in buckets of one
+// percent from 0 to 100, values above 100 are counted as 100. It is safe for concurrent use.
+type Histogram struct {
+	mu      sync.Mutex
+	window  time.Duration
+	buckets [101]uint64
+	values  []histogramValue
+	head    int
+}
+
+// NewHistogram returns a Histogram of the values observed over the last window.
+func NewHistogram(window time.Duration) (*Histogram, error) {
+	if window <= 0 {
+		return nil, errors.New("window must be positive")
+	}
+	return &Histogram{window: window}, nil
+}
+
+// Observe adds the Total of s.
+func (h *Histogram) Observe(s Sample) {
+	h.ObserveValue(s.Total, s.Time)
+}
+
+// ObserveValue adds a cpu percent measured at at, e.g. the usage of a process. Values must
+// be observed in order, the window ends at the newest one. NaN and infinite values, e.g. from
+// an empty interval, are dropped.
+func (h *Histogram) ObserveValue(v float64, at time.Time) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return
+	}
+	bucket := int(math.Ceil(math.Max(0, math.Min(100, v))))
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.buckets[bucket]++
+	h.values = append(h.values, histogramValue{bucket: bucket, at: at})
+	h.expire(at.Add(-h.window))
+}
+
+// expire drops the values observed up to since.
+func (h *Histogram) expire(since time.Time) {
+	for h.head < len(h.values) && !h.values[h.head].at.After(since) {
+		h.buckets[h.values[h.head].bucket]--
+		h.head++
+	}
+	if h.head > len(h.values)/2 {
+		n := copy(h.values, h.values[h.head:])
+		h.values = h.values[:n]
+		h.head = 0
+	}
+}
+
+// Run observes the samples of ch until it is closed or ctx is done.
+func (h *Histogram) Run(ctx context.Context, ch <-chan Sample) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case s, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			h.Observe(s)
+		}
+	}
+}
+
+// Percentile returns the p-th percentile of the values in the window, p in [0, 100]. ok is
+// false when the window is empty.
+func (h *Histogram) Percentile(p float64) (v float64, ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.percentile(p, uint64(len(h.values)-h.head))
+}
+
+func (h *Histogram) percentile(p float64, count uint64) (float64, bool) {
+	if count == 0 {
+		return 0, false
+	}
+	rank := uint64(math.Ceil(math.Max(0, math.Min(100, p)) / 100 * float64(count)))
+	if rank == 0 {
+		rank = 1
+	}
+	var seen uint64
+	for i, n := range h.buckets {
+		seen += n
+		if seen >= rank {
+			return float64(i), true
+		}
+	}
+	return 100, true
+}
+
+// Stat returns the count, p50, p90, p99 and max of the values in the window.
+func (h *Histogram) Stat() HistogramStat {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ret := HistogramStat{Count: uint64(len(h.values) - h.head)}
+	ret.P50, _ = h.percentile(50, ret.Count)
+	ret.P90, _ = h.percentile(90, ret.Count)
+	ret.P99, _ = h.percentile(99, ret.Count)
+	ret.Max, _ = h.percentile(100, ret.Count)
+	return ret
+}
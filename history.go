@@ -0,0 +1,149 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// History keeps the last samples of a Monitor or Watch in a ring buffer, so callers can look
+// back without storing them. It is safe for concurrent use. The samples it returns share their
+// PerCPU and Processes with the History and must not be modified.
+type History struct {
+	mu      sync.RWMutex
+	samples []Sample
+	next    int
+	full    bool
+}
+
+// NewHistory returns a History keeping the last size samples.
+func NewHistory(size int) (*History, error) {
+	if size <= 0 {
+		return nil, errors.New("size must be positive")
+	}
+	return &History{samples: make([]Sample, size)}, nil
+}
+
+// Add appends s, dropping the oldest sample when the History is full. Samples must be added
+// in order.
+func (h *History) Add(s Sample) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.samples[h.next] = s
+	h.next++
+	if h.next == len(h.samples) {
+		h.next, h.full = 0, true
+	}
+}
+
+// Run adds the samples of ch until it is closed or ctx is done.
+func (h *History) Run(ctx context.Context, ch <-chan Sample) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case s, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			h.Add(s)
+		}
+	}
+}
+
+// Len returns the number of samples kept.
+func (h *History) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.len()
+}
+
+func (h *History) len() int {
+	if h.full {
+		return len(h.samples)
+	}
+	return h.next
+}
+
+// at returns the i-th oldest sample.
+func (h *History) at(i int) Sample {
+	if h.full {
+		i = (h.next + i) % len(h.samples)
+	}
+	return h.samples[i]
+}
+
+// Samples returns the samples kept, oldest first.
+func (h *History) Samples() []Sample {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	ret := make([]Sample, h.len())
+	for i := range ret {
+		ret[i] = h.at(i)
+	}
+	return ret
+}
+
+// Latest returns the newest sample, ok is false when there is none.
+func (h *History) Latest() (s Sample, ok bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	n := h.len()
+	if n == 0 {
+		return s, false
+	}
+	return h.at(n - 1), true
+}
+
+// Range returns the samples whose Time is in [from, to], oldest first.
+func (h *History) Range(from, to time.Time) []Sample {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var ret []Sample
+	for i, n := 0, h.len(); i < n; i++ {
+		s := h.at(i)
+		if !s.Time.Before(from) && !s.Time.After(to) {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// AverageOver returns the average Total of the samples of the last d, up to the newest
+// sample. ok is false when there is none.
+func (h *History) AverageOver(d time.Duration) (avg float64, ok bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	n := h.len()
+	if n == 0 {
+		return 0, false
+	}
+	since := h.at(n - 1).Time.Add(-d)
+	var sum float64
+	var count int
+	for i := n - 1; i >= 0; i-- {
+		s := h.at(i)
+		if !s.Time.After(since) {
+			break
+		}
+		sum += s.Total
+		count++
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return sum / float64(count), true
+}
+
+// Max returns the sample with the highest Total, ok is false when there is none.
+func (h *History) Max() (s Sample, ok bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for i, n := 0, h.len(); i < n; i++ {
+		if v := h.at(i); !ok || v.Total > s.Total {
+			s, ok = v, true
+		}
+	}
+	return s, ok
+}
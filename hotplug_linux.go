@@ -0,0 +1,80 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+// CPUChangeEvent is sent when cpus are hot-plugged or taken offline.
+type CPUChangeEvent struct {
+	Online  []int `json:"online"`
+	Added   []int `json:"added"`
+	Removed []int `json:"removed"`
+}
+
+// OnlineCPUs returns the ids of the online cpus.
+// It is based on linux /sys/devices/system/cpu/online.
+func OnlineCPUs() ([]int, error) {
+	return OnlineCPUsWithContext(context.Background())
+}
+
+func OnlineCPUsWithContext(ctx context.Context) ([]int, error) {
+	return readCPUListFile(HostSysWithContext(ctx, "devices/system/cpu/online"))
+}
+
+// WatchCPUChanges polls the online cpus every interval and sends an event on the returned
+// channel whenever the set changes. The channel is closed when ctx is done.
+func WatchCPUChanges(ctx context.Context, interval time.Duration) (<-chan CPUChangeEvent, error) {
+	if interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+	last, err := OnlineCPUsWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan CPUChangeEvent)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			online, err := OnlineCPUsWithContext(ctx)
+			if err != nil || slices.Equal(online, last) {
+				continue
+			}
+
+			ev := CPUChangeEvent{
+				Online:  online,
+				Added:   cpuListDiff(online, last),
+				Removed: cpuListDiff(last, online),
+			}
+			last = online
+			select {
+			case ch <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// cpuListDiff returns the cpus of a that are not in b, both lists must be sorted.
+func cpuListDiff(a, b []int) []int {
+	ret := []int{}
+	for _, cpu := range a {
+		if _, found := slices.BinarySearch(b, cpu); !found {
+			ret = append(ret, cpu)
+		}
+	}
+	return ret
+}
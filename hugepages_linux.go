@@ -0,0 +1,86 @@
+package cpuproc
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HugepageSizeStat contains the pool of one huge page size, Size is in bytes.
+// It is based on linux /sys/kernel/mm/hugepages/hugepages-*kB.
+type HugepageSizeStat struct {
+	Size       uint64 `json:"size"`
+	Total      uint64 `json:"total"`
+	Free       uint64 `json:"free"`
+	Reserved   uint64 `json:"reserved"`
+	Surplus    uint64 `json:"surplus"`
+	Overcommit uint64 `json:"overcommit"`
+}
+
+// HugepagesStat contains the huge page usage. Total, Free, Reserved and Surplus are counts of
+// pages of the default Size, the remaining sizes are in bytes. It is based on linux /proc/meminfo.
+type HugepagesStat struct {
+	Size          uint64             `json:"size"`
+	Total         uint64             `json:"total"`
+	Free          uint64             `json:"free"`
+	Reserved      uint64             `json:"reserved"`
+	Surplus       uint64             `json:"surplus"`
+	Hugetlb       uint64             `json:"hugetlb"`
+	AnonHugePages uint64             `json:"anonHugePages"`
+	Sizes         []HugepageSizeStat `json:"sizes"`
+}
+
+func Hugepages() (*HugepagesStat, error) {
+	return HugepagesWithContext(context.Background())
+}
+
+func HugepagesWithContext(ctx context.Context) (*HugepagesStat, error) {
+	info, err := readMeminfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &HugepagesStat{
+		Size:          info["Hugepagesize"],
+		Total:         info["HugePages_Total"],
+		Free:          info["HugePages_Free"],
+		Reserved:      info["HugePages_Rsvd"],
+		Surplus:       info["HugePages_Surp"],
+		Hugetlb:       info["Hugetlb"],
+		AnonHugePages: info["AnonHugePages"],
+		Sizes:         []HugepageSizeStat{},
+	}
+
+	dirs, err := filepath.Glob(HostSysWithContext(ctx, "kernel/mm/hugepages/hugepages-*kB"))
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		kb, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(dir), "hugepages-"), "kB"), 10, 64)
+		if err != nil {
+			continue
+		}
+		s := HugepageSizeStat{Size: kb * 1024}
+		if s.Total, err = readUint(filepath.Join(dir, "nr_hugepages")); err != nil {
+			return nil, err
+		}
+		if s.Free, err = readUint(filepath.Join(dir, "free_hugepages")); err != nil {
+			return nil, err
+		}
+		if s.Reserved, err = readUint(filepath.Join(dir, "resv_hugepages")); err != nil {
+			return nil, err
+		}
+		if s.Surplus, err = readUint(filepath.Join(dir, "surplus_hugepages")); err != nil {
+			return nil, err
+		}
+		if s.Overcommit, err = readUint(filepath.Join(dir, "nr_overcommit_hugepages")); err != nil {
+			return nil, err
+		}
+		ret.Sizes = append(ret.Sizes, s)
+	}
+	sort.Slice(ret.Sizes, func(i, j int) bool { return ret.Sizes[i].Size < ret.Sizes[j].Size })
+
+	return ret, nil
+}
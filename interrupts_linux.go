@@ -0,0 +1,163 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InterruptStat contains the counters of one line of /proc/interrupts.
+// Counts are aligned with the CPUs of InterruptsStat.
+type InterruptStat struct {
+	IRQ        string   `json:"irq"`
+	Counts     []uint64 `json:"counts"`
+	Total      uint64   `json:"total"`
+	Controller string   `json:"controller"`
+	Device     string   `json:"device"`
+}
+
+type InterruptsStat struct {
+	CPUs       []int           `json:"cpus"`
+	Interrupts []InterruptStat `json:"interrupts"`
+}
+
+// InterruptRateStat contains the per second rates of an interrupt over an interval.
+// PerCPU is aligned with the CPUs of the later sample.
+type InterruptRateStat struct {
+	IRQ    string    `json:"irq"`
+	Device string    `json:"device"`
+	PerCPU []float64 `json:"perCPU"`
+	Total  float64   `json:"total"`
+}
+
+func Interrupts() (*InterruptsStat, error) {
+	return InterruptsWithContext(context.Background())
+}
+
+func InterruptsWithContext(ctx context.Context) (*InterruptsStat, error) {
+	lines, err := ReadLines(HostProcWithContext(ctx, "interrupts"))
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("interrupts is empty")
+	}
+
+	cpus, err := parseCPUHeader(lines[0])
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &InterruptsStat{CPUs: cpus}
+	for _, line := range lines[1:] {
+		irq, rest, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		irq = strings.TrimSpace(irq)
+		counts, desc := parseCounts(strings.Fields(rest), len(cpus))
+
+		it := InterruptStat{IRQ: irq, Counts: counts}
+		for _, c := range counts {
+			it.Total += c
+		}
+		if _, err := strconv.Atoi(irq); err == nil && len(desc) >= 2 {
+			// numbered irqs are followed by the chip, the hwirq and the devices
+			it.Controller = desc[0]
+			it.Device = strings.Join(desc[2:], " ")
+		} else {
+			it.Device = strings.Join(desc, " ")
+		}
+		ret.Interrupts = append(ret.Interrupts, it)
+	}
+	return ret, nil
+}
+
+func InterruptRates(interval time.Duration) ([]InterruptRateStat, error) {
+	return InterruptRatesWithContext(context.Background(), interval)
+}
+
+func InterruptRatesWithContext(ctx context.Context, interval time.Duration) ([]InterruptRateStat, error) {
+	s1, err := InterruptsWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	if err := Sleep(ctx, interval); err != nil {
+		return nil, err
+	}
+
+	s2, err := InterruptsWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start).Seconds()
+
+	prev := make(map[string]InterruptStat, len(s1.Interrupts))
+	for _, it := range s1.Interrupts {
+		prev[it.IRQ] = it
+	}
+
+	ret := make([]InterruptRateStat, 0, len(s2.Interrupts))
+	for _, it := range s2.Interrupts {
+		r := InterruptRateStat{IRQ: it.IRQ, Device: it.Device}
+		r.PerCPU, r.Total = countRates(s1.CPUs, prev[it.IRQ].Counts, s2.CPUs, it.Counts, elapsed)
+		ret = append(ret, r)
+	}
+	return ret, nil
+}
+
+// parseCPUHeader parses the "CPU0 CPU1 ..." header of /proc/interrupts and /proc/softirqs.
+// Offline cpus are left out of the header so the ids are not necessarily contiguous.
+func parseCPUHeader(line string) ([]int, error) {
+	fields := strings.Fields(line)
+	cpus := make([]int, 0, len(fields))
+	for _, f := range fields {
+		cpu, err := strconv.Atoi(strings.TrimPrefix(f, "CPU"))
+		if err != nil {
+			return nil, errors.New("wrong cpu header format")
+		}
+		cpus = append(cpus, cpu)
+	}
+	return cpus, nil
+}
+
+// parseCounts parses up to n leading counters of fields and returns them with the remaining fields.
+// Some lines (e.g. ERR and MIS) carry a single counter, the missing ones are left as 0.
+func parseCounts(fields []string, n int) ([]uint64, []string) {
+	counts := make([]uint64, n)
+	i := 0
+	for ; i < n && i < len(fields); i++ {
+		c, err := strconv.ParseUint(fields[i], 10, 64)
+		if err != nil {
+			break
+		}
+		counts[i] = c
+	}
+	return counts, fields[i:]
+}
+
+// countRates returns the per cpu and total rates of counters sampled twice, cpus are matched by id.
+func countRates(cpus1 []int, counts1 []uint64, cpus2 []int, counts2 []uint64, seconds float64) ([]float64, float64) {
+	before := make(map[int]uint64, len(cpus1))
+	for i, cpu := range cpus1 {
+		if i < len(counts1) {
+			before[cpu] = counts1[i]
+		}
+	}
+
+	perCPU := make([]float64, len(counts2))
+	var total float64
+	for i, c := range counts2 {
+		b, ok := before[cpus2[i]]
+		if !ok {
+			continue
+		}
+		perCPU[i] = counterRate(b, c, seconds)
+		total += perCPU[i]
+	}
+	return perCPU, total
+}
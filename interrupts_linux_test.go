@@ -0,0 +1,34 @@
+package cpuproc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_Interrupts(t *testing.T) {
+	ctx := newTestContext(t, map[string]string{
+		"proc/interrupts": `           CPU0       CPU2
+  0:         36          0   IO-APIC   2-edge      timer
+ 28:         10         20 PCI-MSIX-0000:00:01.0   0-edge      virtio0-config
+NMI:          1          2   Non-maskable interrupts
+ERR:          0
+`,
+	})
+
+	s, err := InterruptsWithContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{0, 2}; !reflect.DeepEqual(s.CPUs, want) {
+		t.Errorf("cpus = %v, want %v", s.CPUs, want)
+	}
+	want := []InterruptStat{
+		{IRQ: "0", Counts: []uint64{36, 0}, Total: 36, Controller: "IO-APIC", Device: "timer"},
+		{IRQ: "28", Counts: []uint64{10, 20}, Total: 30, Controller: "PCI-MSIX-0000:00:01.0", Device: "virtio0-config"},
+		{IRQ: "NMI", Counts: []uint64{1, 2}, Total: 3, Device: "Non-maskable interrupts"},
+		{IRQ: "ERR", Counts: []uint64{0, 0}},
+	}
+	if !reflect.DeepEqual(s.Interrupts, want) {
+		t.Errorf("got %+v, want %+v", s.Interrupts, want)
+	}
+}
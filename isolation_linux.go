@@ -0,0 +1,91 @@
+package cpuproc
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+)
+
+// IsolationStat contains the cpus taken away from the general scheduler.
+// It is based on linux /sys/devices/system/cpu/{isolated,nohz_full} and the
+// isolcpus= and nohz_full= parameters of /proc/cmdline.
+type IsolationStat struct {
+	Isolated []int `json:"isolated"`
+	NohzFull []int `json:"nohzFull"`
+}
+
+// CPUs returns the sorted union of the isolated and nohz_full cpus.
+func (s IsolationStat) CPUs() []int {
+	return mergeCPUList(s.Isolated, s.NohzFull)
+}
+
+func IsolatedCPUs() (*IsolationStat, error) {
+	return IsolatedCPUsWithContext(context.Background())
+}
+
+func IsolatedCPUsWithContext(ctx context.Context) (*IsolationStat, error) {
+	ret := &IsolationStat{Isolated: []int{}, NohzFull: []int{}}
+
+	if cpus, err := readCPUListFile(HostSysWithContext(ctx, "devices/system/cpu/isolated")); err == nil {
+		ret.Isolated = cpus
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if cpus, err := readCPUListFile(HostSysWithContext(ctx, "devices/system/cpu/nohz_full")); err == nil {
+		ret.NohzFull = cpus
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	cmdline, err := readTrimmed(HostProcWithContext(ctx, "cmdline"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ret, nil
+		}
+		return nil, err
+	}
+	isolcpus, nohzFull := parseIsolationCmdline(cmdline)
+	ret.Isolated = mergeCPUList(ret.Isolated, isolcpus)
+	ret.NohzFull = mergeCPUList(ret.NohzFull, nohzFull)
+
+	return ret, nil
+}
+
+// parseIsolationCmdline returns the cpus of the isolcpus= and nohz_full= kernel parameters.
+// isolcpus may carry flags before the list, e.g. isolcpus=nohz,domain,2-5.
+func parseIsolationCmdline(cmdline string) (isolcpus []int, nohzFull []int) {
+	for _, field := range strings.Fields(cmdline) {
+		if field == "--" {
+			// the rest is passed to init
+			break
+		}
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "isolcpus":
+			var list []string
+			for _, part := range strings.Split(value, ",") {
+				if part == "" || (part[0] >= '0' && part[0] <= '9') {
+					list = append(list, part)
+				}
+			}
+			if cpus, err := parseCPUList(strings.Join(list, ",")); err == nil {
+				isolcpus = cpus
+			}
+		case "nohz_full":
+			if cpus, err := parseCPUList(value); err == nil {
+				nohzFull = cpus
+			}
+		}
+	}
+	return isolcpus, nohzFull
+}
+
+func mergeCPUList(a, b []int) []int {
+	ret := append(slices.Clone(a), b...)
+	slices.Sort(ret)
+	return slices.Compact(ret)
+}
@@ -0,0 +1,16 @@
+package cpuproc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseIsolationCmdline(t *testing.T) {
+	isolcpus, nohzFull := parseIsolationCmdline("quiet isolcpus=nohz,domain,2-3,6 nohz_full=2-3 -- isolcpus=7")
+	if want := []int{2, 3, 6}; !reflect.DeepEqual(isolcpus, want) {
+		t.Errorf("isolcpus = %v, want %v", isolcpus, want)
+	}
+	if want := []int{2, 3}; !reflect.DeepEqual(nohzFull, want) {
+		t.Errorf("nohz_full = %v, want %v", nohzFull, want)
+	}
+}
@@ -0,0 +1,166 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// kubernetes QoS classes
+const (
+	QoSGuaranteed = "Guaranteed"
+	QoSBurstable  = "Burstable"
+	QoSBestEffort = "BestEffort"
+)
+
+// KubePodStat contains the cpu usage of a kubernetes pod, found in the kubepods cgroup
+// hierarchy of the kubelet. Name and Namespace are only known with WithDownwardAPI.
+// Throttling is nil when the pod has no cpu controller, Containers are the ids of its
+// containers, the sandbox included.
+type KubePodStat struct {
+	UID        string                `json:"uid"`
+	QoS        string                `json:"qos"`
+	Name       string                `json:"name"`
+	Namespace  string                `json:"namespace"`
+	CgroupPath string                `json:"cgroupPath"`
+	Containers []string              `json:"containers"`
+	CPU        CgroupCPUStat         `json:"cpu"`
+	Throttling *CgroupThrottlingStat `json:"throttling"`
+}
+
+// KubeOption configures KubePods.
+type KubeOption func(*kubeOptions)
+
+type kubeOptions struct {
+	downwardAPI string
+}
+
+// WithDownwardAPI names the pod whose uid is in the downward API volume mounted at dir,
+// which must expose metadata.uid, metadata.name and metadata.namespace as the files
+// uid, name and namespace. It is how a node agent learns about its own pod.
+func WithDownwardAPI(dir string) KubeOption {
+	return func(o *kubeOptions) {
+		o.downwardAPI = dir
+	}
+}
+
+func KubePods(opts ...KubeOption) ([]KubePodStat, error) {
+	return KubePodsWithContext(context.Background(), opts...)
+}
+
+// KubePodsWithContext walks the kubepods cgroups of both the cgroupfs and the systemd
+// cgroup drivers and returns the usage of every pod on the node.
+func KubePodsWithContext(ctx context.Context, opts ...KubeOption) ([]KubePodStat, error) {
+	var o kubeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	root := kubepodsRoot(ctx)
+	if root == nil {
+		return nil, errors.New("kubepods cgroup not found")
+	}
+	children, err := root.ChildrenWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []KubePodStat
+	for _, child := range children {
+		// guaranteed pods sit directly in kubepods, the others in a cgroup per QoS class
+		if uid, ok := parsePodCgroup(child.Name()); ok {
+			ret = appendPod(ctx, ret, child, uid, QoSGuaranteed)
+			continue
+		}
+		qos := kubeQoS(child.Name())
+		if qos == "" {
+			continue
+		}
+		pods, err := child.ChildrenWithContext(ctx)
+		if err != nil {
+			continue
+		}
+		for _, pod := range pods {
+			if uid, ok := parsePodCgroup(pod.Name()); ok {
+				ret = appendPod(ctx, ret, pod, uid, qos)
+			}
+		}
+	}
+
+	if o.downwardAPI != "" {
+		uid, _ := readTrimmed(filepath.Join(o.downwardAPI, "uid"))
+		for i := range ret {
+			if ret[i].UID == uid {
+				ret[i].Name, _ = readTrimmed(filepath.Join(o.downwardAPI, "name"))
+				ret[i].Namespace, _ = readTrimmed(filepath.Join(o.downwardAPI, "namespace"))
+			}
+		}
+	}
+	return ret, nil
+}
+
+// KubeQoSUsage sums the cpu usage of pods by QoS class.
+func KubeQoSUsage(pods []KubePodStat) map[string]CgroupCPUStat {
+	ret := make(map[string]CgroupCPUStat, 3)
+	for _, p := range pods {
+		s := ret[p.QoS]
+		s.Usage += p.CPU.Usage
+		s.User += p.CPU.User
+		s.System += p.CPU.System
+		s.Throttled += p.CPU.Throttled
+		ret[p.QoS] = s
+	}
+	return ret
+}
+
+func appendPod(ctx context.Context, pods []KubePodStat, c *Cgroup, uid, qos string) []KubePodStat {
+	cpu, err := c.CPUStatWithContext(ctx)
+	if err != nil {
+		// the pod went away
+		return pods
+	}
+	pod := KubePodStat{UID: uid, QoS: qos, CgroupPath: c.Path(), CPU: *cpu}
+	if t, err := c.ThrottlingWithContext(ctx); err == nil {
+		pod.Throttling = t
+	}
+	if containers, err := c.ChildrenWithContext(ctx); err == nil {
+		for _, container := range containers {
+			if _, id, ok := parseContainerCgroup(container.Name()); ok {
+				pod.Containers = append(pod.Containers, id)
+			}
+		}
+	}
+	return append(pods, pod)
+}
+
+// kubepodsRoot returns the kubepods cgroup of the systemd or cgroupfs driver.
+func kubepodsRoot(ctx context.Context) *Cgroup {
+	for _, path := range []string{"/kubepods.slice", "/kubepods"} {
+		if c := newCgroup(ctx, path); PathExists(c.Path()) {
+			return c
+		}
+	}
+	return nil
+}
+
+// parsePodCgroup returns the pod uid of "pod<uid>" (cgroupfs) or
+// "kubepods-burstable-pod<uid>.slice" (systemd, with "_" instead of "-" in the uid).
+func parsePodCgroup(name string) (string, bool) {
+	name = strings.TrimSuffix(name, ".slice")
+	i := strings.LastIndex(name, "pod")
+	if i < 0 || (i > 0 && name[i-1] != '-') || i+3 == len(name) {
+		return "", false
+	}
+	return strings.ReplaceAll(name[i+3:], "_", "-"), true
+}
+
+func kubeQoS(name string) string {
+	switch strings.TrimPrefix(strings.TrimSuffix(name, ".slice"), "kubepods-") {
+	case "burstable":
+		return QoSBurstable
+	case "besteffort":
+		return QoSBestEffort
+	}
+	return ""
+}
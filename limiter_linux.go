@@ -0,0 +1,220 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrLimiterStopped is returned by (*Limiter).Acquire when the Limiter is stopped while it waits.
+var ErrLimiterStopped = errors.New("limiter stopped")
+
+// limiterDecrease is what the limit is multiplied by every sample above the target.
+const limiterDecrease = 0.9
+
+type limiterOptions struct {
+	target      float64
+	monitorOpts []MonitorOption
+	cgroup      *Cgroup
+	// latest replaces the samples of the Monitor, for tests
+	latest func() Sample
+}
+
+// LimiterOption configures a Limiter.
+type LimiterOption func(*limiterOptions)
+
+// WithCPUTarget sets the cpu percent above which work is shed, 80 by default.
+func WithCPUTarget(percent float64) LimiterOption {
+	return func(o *limiterOptions) {
+		o.target = percent
+	}
+}
+
+// WithLimiterMonitorOptions sets the options of the Monitor sampling the cpu, by default it
+// samples every 250ms with a one second WithSmoothing.
+func WithLimiterMonitorOptions(opts ...MonitorOption) LimiterOption {
+	return func(o *limiterOptions) {
+		o.monitorOpts = append(o.monitorOpts, opts...)
+	}
+}
+
+// WithLimiterCgroup makes the Limiter sample the usage of c relative to its cpu limit, see
+// WithCgroup, instead of the busy percent of the machine. Use SelfCgroup for the container of
+// the current process.
+func WithLimiterCgroup(c *Cgroup) LimiterOption {
+	return func(o *limiterOptions) {
+		o.cgroup = c
+	}
+}
+
+// Limiter sheds work when the cpu usage is above a target, à la the system rules of Sentinel.
+// It samples the cpu with a Monitor and keeps a limit on the work in flight: while the usage
+// is above the target the limit starts at the work in flight and decreases every sample,
+// while it is below everything is admitted.
+type Limiter struct {
+	opts    limiterOptions
+	monitor *Monitor
+	cancel  context.CancelFunc
+	done    chan struct{}
+	mu      sync.Mutex
+	cpu     float64
+	over    bool
+	limit   float64
+	flight  int
+	changed chan struct{}
+}
+
+// NewLimiter starts a Limiter, it must be stopped with Stop.
+func NewLimiter(opts ...LimiterOption) (*Limiter, error) {
+	o := limiterOptions{target: 80}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.target <= 0 || o.target > 100 {
+		return nil, errors.New("target must be in (0, 100]")
+	}
+	if o.cgroup != nil {
+		if _, err := o.cgroup.TimesWithContext(context.Background()); err != nil {
+			return nil, err
+		}
+		o.monitorOpts = append(o.monitorOpts, WithCgroup(o.cgroup))
+	}
+
+	m, err := NewMonitor(append([]MonitorOption{
+		WithSampleInterval(250 * time.Millisecond),
+		WithSmoothing(time.Second),
+	}, o.monitorOpts...)...)
+	if err != nil {
+		return nil, err
+	}
+	latest := o.latest
+	if latest == nil {
+		latest = m.Latest
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &Limiter{opts: o, monitor: m, cancel: cancel, done: make(chan struct{}), changed: make(chan struct{})}
+	go l.run(ctx, m.interval, latest)
+	return l, nil
+}
+
+func (l *Limiter) run(ctx context.Context, interval time.Duration, latest func() Sample) {
+	defer close(l.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// the Monitor samples on its own ticker, every sample counts once
+		s := latest()
+		if s.Time.IsZero() || s.Time.Equal(last) {
+			continue
+		}
+		last = s.Time
+		l.update(s.Total)
+	}
+}
+
+// update adjusts the limit to the cpu usage.
+func (l *Limiter) update(cpu float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cpu = cpu
+	switch {
+	case cpu <= l.opts.target:
+		l.over = false
+	case !l.over:
+		l.over = true
+		l.limit = float64(l.flight)
+	default:
+		l.limit *= limiterDecrease
+	}
+	// at least one piece of work runs, else the usage would never be sampled under load
+	l.limit = math.Max(l.limit, 1)
+	l.notify()
+}
+
+// notify wakes up the callers of Acquire, l.mu must be held.
+func (l *Limiter) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// tryAcquire admits a piece of work, l.mu must be held.
+func (l *Limiter) tryAcquire() (release func(), ok bool) {
+	if l.over && float64(l.flight) >= l.limit {
+		return nil, false
+	}
+	l.flight++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.flight--
+			l.notify()
+		})
+	}, true
+}
+
+// Allow admits a piece of work without waiting, release must be called once it is done.
+// ok is false when the cpu usage is above the target and too much work is in flight.
+func (l *Limiter) Allow() (release func(), ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.tryAcquire()
+}
+
+// Acquire waits until a piece of work is admitted, release must be called once it is done.
+// It returns ErrLimiterStopped when the Limiter is stopped meanwhile and ctx.Err() when ctx is done
+// first.
+func (l *Limiter) Acquire(ctx context.Context) (release func(), err error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		l.mu.Lock()
+		release, ok := l.tryAcquire()
+		changed := l.changed
+		l.mu.Unlock()
+		if ok {
+			return release, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-l.done:
+			return nil, ErrLimiterStopped
+		case <-changed:
+		}
+	}
+}
+
+// CPU returns the latest smoothed cpu usage.
+func (l *Limiter) CPU() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.cpu
+}
+
+// InFlight returns the work admitted and not released yet.
+func (l *Limiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.flight
+}
+
+// Stop stops the sampling and waits for it, it can be called more than once.
+// Work already admitted can still be released.
+func (l *Limiter) Stop() {
+	l.cancel()
+	<-l.done
+	l.monitor.Stop()
+}
@@ -0,0 +1,120 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestLimiter returns a Limiter that never samples, the tests drive update.
+func newTestLimiter(t *testing.T) *Limiter {
+	t.Helper()
+	l, err := NewLimiter(WithCPUTarget(80), func(o *limiterOptions) {
+		o.latest = func() Sample { return Sample{} }
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(l.Stop)
+	return l
+}
+
+func Test_Limiter(t *testing.T) {
+	l := newTestLimiter(t)
+
+	// below the target everything is admitted
+	l.update(50)
+	var releases []func()
+	for i := 0; i < 10; i++ {
+		release, ok := l.Allow()
+		if !ok {
+			t.Fatalf("Allow %d refused below the target", i)
+		}
+		releases = append(releases, release)
+	}
+
+	// above the target the limit starts at the work in flight
+	l.update(95)
+	if l.CPU() != 95 {
+		t.Errorf("CPU = %v, want 95", l.CPU())
+	}
+	if _, ok := l.Allow(); ok {
+		t.Error("Allow admitted above the target with the limit reached")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire = %v, want a deadline error", err)
+	}
+
+	// the limit decreases every sample above the target, 10 * 0.9 * 0.9 = 8.1
+	l.update(95)
+	l.update(95)
+	releases[0]()
+	if _, ok := l.Allow(); ok {
+		t.Error("Allow admitted 10 pieces of work with a limit of 8.1")
+	}
+	releases[1]()
+	// released twice, counted once
+	releases[1]()
+	if l.InFlight() != 8 {
+		t.Fatalf("InFlight = %d, want 8", l.InFlight())
+	}
+	release, ok := l.Allow()
+	if !ok {
+		t.Fatal("Allow refused a 9th piece of work with a limit of 8.1")
+	}
+	release()
+
+	// a waiting Acquire is admitted once the usage is back below the target
+	l.update(95)
+	acquired := make(chan error, 1)
+	go func() {
+		release, err := l.Acquire(context.Background())
+		if err == nil {
+			release()
+		}
+		for _, release := range releases[2:] {
+			release()
+		}
+		acquired <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	l.update(60)
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Acquire still waiting below the target")
+	}
+	if l.InFlight() != 0 {
+		t.Errorf("InFlight = %d, want 0", l.InFlight())
+	}
+}
+
+func Test_LimiterMinimum(t *testing.T) {
+	l := newTestLimiter(t)
+
+	// at least one piece of work runs however long the usage stays high
+	for i := 0; i < 50; i++ {
+		l.update(100)
+	}
+	release, ok := l.Allow()
+	if !ok {
+		t.Fatal("Allow refused with nothing in flight")
+	}
+	if _, ok := l.Allow(); ok {
+		t.Error("Allow admitted a second piece of work at the minimum limit")
+	}
+	release()
+
+	l.Stop()
+	release, _ = l.Allow()
+	defer release()
+	if _, err := l.Acquire(context.Background()); err != ErrLimiterStopped {
+		t.Errorf("Acquire = %v, want ErrLimiterStopped", err)
+	}
+}
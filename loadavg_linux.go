@@ -0,0 +1,78 @@
+package cpuproc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// LoadAvgStat contains the load averages and the scheduling entities of the system.
+// It is based on linux /proc/loadavg.
+type LoadAvgStat struct {
+	Load1   float64 `json:"load1"`
+	Load5   float64 `json:"load5"`
+	Load15  float64 `json:"load15"`
+	Running int     `json:"running"`
+	Total   int     `json:"total"`
+	LastPID int     `json:"lastPid"`
+}
+
+func LoadAvg() (*LoadAvgStat, error) {
+	return LoadAvgWithContext(context.Background())
+}
+
+func LoadAvgWithContext(ctx context.Context) (*LoadAvgStat, error) {
+	line, err := readTrimmed(HostProcWithContext(ctx, "loadavg"))
+	if err != nil {
+		return loadAvgFromSysinfo()
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) < 5 {
+		return nil, fmt.Errorf("wrong loadavg format")
+	}
+
+	ret := &LoadAvgStat{}
+	if ret.Load1, err = strconv.ParseFloat(fields[0], 64); err != nil {
+		return nil, err
+	}
+	if ret.Load5, err = strconv.ParseFloat(fields[1], 64); err != nil {
+		return nil, err
+	}
+	if ret.Load15, err = strconv.ParseFloat(fields[2], 64); err != nil {
+		return nil, err
+	}
+	running, total, ok := strings.Cut(fields[3], "/")
+	if !ok {
+		return nil, fmt.Errorf("wrong loadavg format")
+	}
+	if ret.Running, err = strconv.Atoi(running); err != nil {
+		return nil, err
+	}
+	if ret.Total, err = strconv.Atoi(total); err != nil {
+		return nil, err
+	}
+	if ret.LastPID, err = strconv.Atoi(fields[4]); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// loadAvgFromSysinfo is used when /proc is not mounted, it can't tell the running entities.
+func loadAvgFromSysinfo() (*LoadAvgStat, error) {
+	var info syscall.Sysinfo_t
+	if err := syscall.Sysinfo(&info); err != nil {
+		return nil, err
+	}
+
+	// the loads are fixed point numbers, see SI_LOAD_SHIFT in linux/sysinfo.h
+	const scale = float64(1 << 16)
+	return &LoadAvgStat{
+		Load1:  float64(info.Loads[0]) / scale,
+		Load5:  float64(info.Loads[1]) / scale,
+		Load15: float64(info.Loads[2]) / scale,
+		Total:  int(info.Procs),
+	}, nil
+}
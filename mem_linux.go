@@ -0,0 +1,94 @@
+package cpuproc
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// MemoryStat contains the system memory usage, sizes are in bytes.
+// It is based on linux /proc/meminfo.
+type MemoryStat struct {
+	Total        uint64  `json:"total"`
+	Free         uint64  `json:"free"`
+	Available    uint64  `json:"available"`
+	Used         uint64  `json:"used"`
+	UsedPercent  float64 `json:"usedPercent"`
+	Buffers      uint64  `json:"buffers"`
+	Cached       uint64  `json:"cached"`
+	Slab         uint64  `json:"slab"`
+	SReclaimable uint64  `json:"sReclaimable"`
+	Dirty        uint64  `json:"dirty"`
+}
+
+func Memory() (*MemoryStat, error) {
+	return MemoryWithContext(context.Background())
+}
+
+func MemoryWithContext(ctx context.Context) (*MemoryStat, error) {
+	info, err := readMeminfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &MemoryStat{
+		Total:        info["MemTotal"],
+		Free:         info["MemFree"],
+		Buffers:      info["Buffers"],
+		Cached:       info["Cached"],
+		Slab:         info["Slab"],
+		SReclaimable: info["SReclaimable"],
+		Dirty:        info["Dirty"],
+	}
+
+	if available, ok := info["MemAvailable"]; ok {
+		ret.Available = available
+	} else {
+		// Linux < 3.14
+		ret.Available = ret.Free + ret.Buffers + ret.Cached
+	}
+	if ret.Available > ret.Total {
+		ret.Available = ret.Total
+	}
+	ret.Used = ret.Total - ret.Available
+	if ret.Total > 0 {
+		ret.UsedPercent = float64(ret.Used) / float64(ret.Total) * 100
+	}
+	return ret, nil
+}
+
+// readMeminfo returns the fields of /proc/meminfo, the kB values are converted to bytes
+// and unitless values such as HugePages_Total are kept as they are.
+func readMeminfo(ctx context.Context) (map[string]uint64, error) {
+	return readKBValues(HostProcWithContext(ctx, "meminfo"))
+}
+
+// readKBValues parses the "Key:   123 kB" lines of meminfo like files, such as
+// /proc/<pid>/status or smaps_rollup, values in kB are converted to bytes.
+func readKBValues(filename string) (map[string]uint64, error) {
+	lines, err := ReadLines(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]uint64, len(lines))
+	for _, line := range lines {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		if len(fields) > 1 && fields[1] == "kB" {
+			v *= 1024
+		}
+		ret[strings.TrimSpace(key)] = v
+	}
+	return ret, nil
+}
@@ -0,0 +1,103 @@
+package cpuproc
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MiscStat contains the system wide counters of /proc/stat that follow the cpu lines.
+type MiscStat struct {
+	Ctxt         uint64 `json:"ctxt"`
+	Intr         uint64 `json:"intr"`
+	Softirq      uint64 `json:"softirq"`
+	Processes    uint64 `json:"processes"`
+	ProcsRunning uint64 `json:"procsRunning"`
+	ProcsBlocked uint64 `json:"procsBlocked"`
+}
+
+// MiscRateStat contains the per second rates of the MiscStat counters over an interval.
+// ProcsRunning and ProcsBlocked are gauges and are taken from the end of the interval.
+type MiscRateStat struct {
+	Ctxt         float64 `json:"ctxt"`
+	Intr         float64 `json:"intr"`
+	Softirq      float64 `json:"softirq"`
+	Forks        float64 `json:"forks"`
+	ProcsRunning uint64  `json:"procsRunning"`
+	ProcsBlocked uint64  `json:"procsBlocked"`
+}
+
+func Misc() (*MiscStat, error) {
+	return MiscWithContext(context.Background())
+}
+
+func MiscWithContext(ctx context.Context) (*MiscStat, error) {
+	lines, err := ReadLines(HostProcWithContext(ctx, "stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &MiscStat{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		var dst *uint64
+		switch fields[0] {
+		case "ctxt":
+			dst = &ret.Ctxt
+		case "intr":
+			dst = &ret.Intr
+		case "softirq":
+			dst = &ret.Softirq
+		case "processes":
+			dst = &ret.Processes
+		case "procs_running":
+			dst = &ret.ProcsRunning
+		case "procs_blocked":
+			dst = &ret.ProcsBlocked
+		default:
+			continue
+		}
+		// intr and softirq are followed by the per source counts, the first one is the total
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		*dst = v
+	}
+	return ret, nil
+}
+
+func MiscRate(interval time.Duration) (*MiscRateStat, error) {
+	return MiscRateWithContext(context.Background(), interval)
+}
+
+func MiscRateWithContext(ctx context.Context, interval time.Duration) (*MiscRateStat, error) {
+	m1, err := MiscWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	if err := Sleep(ctx, interval); err != nil {
+		return nil, err
+	}
+
+	m2, err := MiscWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start).Seconds()
+
+	return &MiscRateStat{
+		Ctxt:         counterRate(m1.Ctxt, m2.Ctxt, elapsed),
+		Intr:         counterRate(m1.Intr, m2.Intr, elapsed),
+		Softirq:      counterRate(m1.Softirq, m2.Softirq, elapsed),
+		Forks:        counterRate(m1.Processes, m2.Processes, elapsed),
+		ProcsRunning: m2.ProcsRunning,
+		ProcsBlocked: m2.ProcsBlocked,
+	}, nil
+}
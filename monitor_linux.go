@@ -0,0 +1,351 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+type monitorOptions struct {
+	interval  time.Duration
+	percpu    bool
+	pids      []int32
+	procOpts  []ProcessOption
+	timesOpts []Option
+	halfLife  time.Duration
+	cgroup    *Cgroup
+}
+
+// MonitorOption configures a Monitor or Watch.
+type MonitorOption func(*monitorOptions)
+
+// WithSampleInterval sets the time between two samples, one second by default.
+func WithSampleInterval(interval time.Duration) MonitorOption {
+	return func(o *monitorOptions) {
+		o.interval = interval
+	}
+}
+
+// WithPerCPU turns per cpu sampling on or off, it is off by default.
+func WithPerCPU(enable bool) MonitorOption {
+	return func(o *monitorOptions) {
+		o.percpu = enable
+	}
+}
+
+// WithProcesses adds processes to sample, the opts of the last WithProcesses apply to all of
+// them, e.g. WithNormalization.
+func WithProcesses(pids []int32, opts ...ProcessOption) MonitorOption {
+	return func(o *monitorOptions) {
+		o.pids = append(o.pids, pids...)
+		o.procOpts = opts
+	}
+}
+
+// WithTimesOptions sets the options of the machine cpu times, e.g. WithoutIsolatedCPUs.
+func WithTimesOptions(opts ...Option) MonitorOption {
+	return func(o *monitorOptions) {
+		o.timesOpts = opts
+	}
+}
+
+// WithSmoothing smooths the samples with an EWMA of the given half-life, which makes sub-second
+// intervals readable. It is off by default.
+func WithSmoothing(halfLife time.Duration) MonitorOption {
+	return func(o *monitorOptions) {
+		o.halfLife = halfLife
+	}
+}
+
+// WithCgroup makes Total the usage of c relative to its cpu limit, see (*Cgroup).QuotaPercent,
+// instead of the busy percent of the machine. Use SelfCgroup for the container of the current
+// process.
+func WithCgroup(c *Cgroup) MonitorOption {
+	return func(o *monitorOptions) {
+		o.cgroup = c
+	}
+}
+
+func newMonitorOptions(opts []MonitorOption) (monitorOptions, error) {
+	o := monitorOptions{interval: time.Second}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.interval <= 0 {
+		return o, errors.New("interval must be positive")
+	}
+	return o, nil
+}
+
+// sampledProc is a process and its cpu time at the previous sample.
+type sampledProc struct {
+	p    *proc
+	busy float64
+	at   time.Time
+}
+
+// sampler computes samples from the difference with the previous cpu times, it is not safe
+// for concurrent use.
+type sampler struct {
+	opts       monitorOptions
+	prevTotal  []TimesStat
+	prevPerCPU []TimesStat
+	procs      map[int32]*sampledProc
+	smoother   *sampleSmoother
+	// cpu time of the cgroup of WithCgroup at the previous sample
+	cgroupBusy float64
+	cgroupAt   time.Time
+}
+
+func newSampler(opts monitorOptions) *sampler {
+	s := &sampler{opts: opts}
+	if opts.halfLife > 0 {
+		s.smoother = newSampleSmoother(opts.halfLife)
+	}
+	s.reset()
+	return s
+}
+
+// reset forgets the previous cpu times, the next sample is only a baseline.
+func (s *sampler) reset() {
+	s.prevTotal, s.prevPerCPU = nil, nil
+	s.cgroupAt = time.Time{}
+	if s.smoother != nil {
+		s.smoother.reset()
+	}
+	s.procs = make(map[int32]*sampledProc, len(s.opts.pids))
+	for _, pid := range s.opts.pids {
+		if p := NewProcess(pid, s.opts.procOpts...); p != nil {
+			s.procs[pid] = &sampledProc{p: p}
+		}
+	}
+}
+
+// sample takes a sample, ok is false when there was no previous one to compare with.
+func (s *sampler) sample(ctx context.Context) (ret Sample, ok bool) {
+	ret.Time = time.Now()
+	ok = s.prevTotal != nil
+
+	if s.opts.cgroup != nil {
+		ok = !s.cgroupAt.IsZero()
+		ret.Total = s.cgroupTotal(ctx, ret.Time)
+	} else if t, err := TimesWithContext(ctx, false, s.opts.timesOpts...); err == nil && len(t) > 0 {
+		if s.prevTotal != nil {
+			if busy, err := calculateAllBusy(s.prevTotal, t); err == nil && len(busy) > 0 {
+				ret.Total = busy[0]
+			}
+		}
+		s.prevTotal = t
+	}
+	if s.opts.percpu {
+		if t, err := TimesWithContext(ctx, true, s.opts.timesOpts...); err == nil {
+			if s.prevPerCPU != nil {
+				if busy, err := calculateAllBusy(s.prevPerCPU, t); err == nil {
+					ret.PerCPU = make([]CPUPercent, len(busy))
+					for i, v := range busy {
+						ret.PerCPU[i] = CPUPercent{CPU: t[i].CPU, Percent: v}
+					}
+				}
+			}
+			s.prevPerCPU = t
+		}
+	}
+
+	ret.Processes = make(map[int32]float64, len(s.procs))
+	for pid, sp := range s.procs {
+		if running, err := sp.p.IsRunningWithContext(ctx); err == nil && !running {
+			delete(s.procs, pid)
+			continue
+		}
+		t, err := sp.p.TimesWithContext(ctx)
+		if err != nil {
+			continue
+		}
+		busy := t.User + t.System
+		if !sp.at.IsZero() {
+			elapsed := ret.Time.Sub(sp.at).Seconds()
+			n, err := sp.p.cpuCount(ctx)
+			if err == nil && elapsed > 0 && n > 0 && busy >= sp.busy {
+				ret.Processes[pid] = (busy - sp.busy) / elapsed * 100 / n
+			}
+		}
+		sp.busy, sp.at = busy, ret.Time
+	}
+	if ok && s.smoother != nil {
+		s.smoother.smooth(&ret)
+	}
+	return ret, ok
+}
+
+// cgroupTotal returns the usage of the cgroup of WithCgroup since the previous sample, relative
+// to its cpu limit.
+func (s *sampler) cgroupTotal(ctx context.Context, now time.Time) float64 {
+	c := s.opts.cgroup
+	t, err := c.TimesWithContext(ctx)
+	if err != nil {
+		return 0
+	}
+	busy := t.User + t.System
+	lastBusy, lastAt := s.cgroupBusy, s.cgroupAt
+	s.cgroupBusy, s.cgroupAt = busy, now
+	if lastAt.IsZero() || busy < lastBusy {
+		return 0
+	}
+
+	// the limit is read every time, it may be changed at runtime
+	cpus := float64(onlineCPUCount(ctx))
+	if limit, err := c.CPULimitWithContext(ctx); err == nil && limit.CPUs() > 0 {
+		cpus = limit.CPUs()
+	}
+	elapsed := now.Sub(lastAt).Seconds()
+	if elapsed <= 0 || cpus <= 0 {
+		return 0
+	}
+	return (busy - lastBusy) / elapsed * 100 / cpus
+}
+
+// Monitor samples the cpu usage in the background and keeps the latest sample, so callers
+// don't have to run their own Sleep and Percent loop.
+type Monitor struct {
+	mu       sync.RWMutex
+	sampler  *sampler
+	interval time.Duration
+	latest   Sample
+	reset    chan struct{}
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewMonitor starts a Monitor, the first sample is available after one interval.
+// It must be stopped with Stop.
+func NewMonitor(opts ...MonitorOption) (*Monitor, error) {
+	o, err := newMonitorOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Monitor{
+		sampler:  newSampler(o),
+		interval: o.interval,
+		reset:    make(chan struct{}, 1),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	m.sampler.sample(ctx)
+	go m.run(ctx, o.interval)
+	return m, nil
+}
+
+func (m *Monitor) run(ctx context.Context, interval time.Duration) {
+	defer close(m.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.reset:
+			m.sampler.reset()
+			m.sampler.sample(ctx)
+			ticker.Reset(interval)
+			continue
+		case <-ticker.C:
+		}
+
+		s, ok := m.sampler.sample(ctx)
+		if !ok {
+			continue
+		}
+		m.mu.Lock()
+		m.latest = s
+		m.mu.Unlock()
+	}
+}
+
+// Latest returns the latest sample, its Time is zero before the first one.
+func (m *Monitor) Latest() Sample {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ret := m.latest
+	ret.PerCPU = slices.Clone(ret.PerCPU)
+	if ret.Processes != nil {
+		ret.Processes = make(map[int32]float64, len(m.latest.Processes))
+		for pid, percent := range m.latest.Processes {
+			ret.Processes[pid] = percent
+		}
+	}
+	return ret
+}
+
+// Total returns the latest busy percent of the machine.
+func (m *Monitor) Total() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.latest.Total
+}
+
+// Process returns the latest cpu usage of pid, ok is false when it is not sampled or exited.
+func (m *Monitor) Process(pid int32) (percent float64, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	percent, ok = m.latest.Processes[pid]
+	return percent, ok
+}
+
+// Reset drops the latest sample and the previous cpu times, e.g. after the machine was
+// suspended. The pids of WithProcesses are looked up again.
+func (m *Monitor) Reset() {
+	m.mu.Lock()
+	m.latest = Sample{}
+	m.mu.Unlock()
+	select {
+	case m.reset <- struct{}{}:
+	default:
+	}
+}
+
+// Stop stops the sampling goroutine and waits for it, it can be called more than once.
+func (m *Monitor) Stop() {
+	m.cancel()
+	<-m.done
+}
+
+// Watch sends a Sample on the returned channel every interval, which overrides
+// WithSampleInterval, so consumers can select on cpu updates along their other channels.
+// Samples are not dropped, a slow reader delays the next one. The channel is closed when
+// ctx is done.
+func Watch(ctx context.Context, interval time.Duration, opts ...MonitorOption) (<-chan Sample, error) {
+	o, err := newMonitorOptions(append(opts, WithSampleInterval(interval)))
+	if err != nil {
+		return nil, err
+	}
+	s := newSampler(o)
+	s.sample(ctx)
+
+	ch := make(chan Sample)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			sample, ok := s.sample(ctx)
+			if !ok {
+				continue
+			}
+			select {
+			case ch <- sample:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
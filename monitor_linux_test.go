@@ -0,0 +1,77 @@
+package cpuproc
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_samplerPerCPU(t *testing.T) {
+	// cpu1 is offline, the cpus are not indexed by their number
+	ctx := newTestContext(t, map[string]string{
+		"proc/stat": "cpu  200 0 0 200 0 0 0 0 0 0\ncpu0 100 0 0 100 0 0 0 0 0 0\ncpu2 100 0 0 100 0 0 0 0 0 0\n",
+	})
+	o, err := newMonitorOptions([]MonitorOption{WithPerCPU(true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newSampler(o)
+	if _, ok := s.sample(ctx); ok {
+		t.Fatal("the first sample is only a baseline")
+	}
+
+	stat := "cpu  300 0 0 300 0 0 0 0 0 0\ncpu0 100 0 0 200 0 0 0 0 0 0\ncpu2 200 0 0 100 0 0 0 0 0 0\n"
+	if err := os.WriteFile(HostProcWithContext(ctx, "stat"), []byte(stat), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := s.sample(ctx)
+	if !ok {
+		t.Fatal("no sample")
+	}
+	want := []CPUPercent{{CPU: "cpu0", Percent: 0}, {CPU: "cpu2", Percent: 100}}
+	if !reflect.DeepEqual(got.PerCPU, want) {
+		t.Errorf("got %+v, want %+v", got.PerCPU, want)
+	}
+	if v, ok := got.CPU("cpu2"); !ok || v != 100 {
+		t.Errorf("CPU(cpu2) = %v, %v, want 100", v, ok)
+	}
+	if _, ok := got.CPU("cpu1"); ok {
+		t.Error("CPU(cpu1) found an offline cpu")
+	}
+	if v, ok := AlertCPU("cpu2").value(got); !ok || v != 100 {
+		t.Errorf("AlertCPU(cpu2) = %v, %v, want 100", v, ok)
+	}
+}
+
+func Test_samplerCgroup(t *testing.T) {
+	ctx := newTestContext(t, map[string]string{
+		"proc/self/cgroup":                                "0::/system.slice/app.service\n",
+		"sys/fs/cgroup/cgroup.controllers":                "cpuset cpu io memory pids\n",
+		"sys/fs/cgroup/system.slice/app.service/cpu.stat": "usage_usec 1000000\nuser_usec 1000000\nsystem_usec 0\n",
+		"sys/fs/cgroup/system.slice/app.service/cpu.max":  "50000 100000\n",
+	})
+	c, err := SelfCgroupWithContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := newMonitorOptions([]MonitorOption{WithCgroup(c)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newSampler(o)
+	now := time.Now()
+	if got := s.cgroupTotal(ctx, now); got != 0 {
+		t.Errorf("baseline = %v, want 0", got)
+	}
+
+	// half a cpu used over one second with a limit of half a cpu
+	stat := "usage_usec 1500000\nuser_usec 1250000\nsystem_usec 250000\n"
+	if err := os.WriteFile(filepath.Join(c.Path(), "cpu.stat"), []byte(stat), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.cgroupTotal(ctx, now.Add(time.Second)); got != 100 {
+		t.Errorf("got %v, want 100", got)
+	}
+}
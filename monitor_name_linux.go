@@ -0,0 +1,190 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NamedProcessStat is the cpu usage of the processes matching a pattern over an interval,
+// 100 being one cpu. Percent is the sum of Instances, Started and Exited list the pids that
+// appeared and disappeared since the previous event. A process that started during the
+// interval is listed in Started but only counted from the next event.
+type NamedProcessStat struct {
+	Percent   float64   `json:"percent"`
+	Instances []TopStat `json:"instances"`
+	Started   []int32   `json:"started"`
+	Exited    []int32   `json:"exited"`
+}
+
+// matchKey is a process that was checked against the pattern, the start time tells a
+// recycled pid apart so that the cmdline is only read once per process. The comm changes on
+// execve, a worker seen between fork and exec still has the comm of its parent.
+type matchKey struct {
+	pid       int32
+	startTime uint64
+	name      string
+}
+
+type nameMonitorOptions struct {
+	events bool
+}
+
+// MonitorByNameOption configures MonitorByName.
+type MonitorByNameOption func(*nameMonitorOptions)
+
+// WithProcessEvents makes MonitorByName rescan /proc as soon as a process forks, execs or
+// changes its comm, as told by WatchProcessEvents, instead of at the next interval only. A
+// burst of events leads to a single rescan. It falls back to polling when the proc connector
+// is not available, e.g. without CAP_NET_ADMIN.
+func WithProcessEvents(enable bool) MonitorByNameOption {
+	return func(o *nameMonitorOptions) {
+		o.events = enable
+	}
+}
+
+// MonitorByName rescans /proc every interval for the processes whose comm or cmdline match
+// pattern, and sends their cpu usage on the returned channel, so that a monitor survives the
+// restart of the workers it follows. The channel is closed when ctx is done.
+func MonitorByName(ctx context.Context, pattern string, interval time.Duration, opts ...MonitorByNameOption) (<-chan NamedProcessStat, error) {
+	var o nameMonitorOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	matched := make(map[matchKey]bool)
+	last, err := sampleMatching(ctx, re, matched)
+	if err != nil {
+		return nil, err
+	}
+	lastTime := time.Now()
+
+	var events <-chan ProcEvent
+	if o.events {
+		events, _ = WatchProcessEvents(ctx)
+	}
+
+	ch := make(chan NamedProcessStat)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case ev, ok := <-events:
+				if !ok {
+					events = nil
+					continue
+				}
+				if !rescanOn(ev) {
+					continue
+				}
+				events = drainProcEvents(events)
+			}
+
+			cur, err := sampleMatching(ctx, re, matched)
+			if err != nil {
+				continue
+			}
+			now := time.Now()
+			ev := namedProcessEvent(last, cur, now.Sub(lastTime).Seconds())
+			last, lastTime = cur, now
+
+			select {
+			case ch <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// rescanOn tells whether ev may change the processes matching the pattern. Exits wait for
+// the next interval and new threads don't matter.
+func rescanOn(ev ProcEvent) bool {
+	return ev.Type != ProcEventExit && ev.Pid == ev.Tgid
+}
+
+// drainProcEvents discards the events already queued, it returns nil once events is closed.
+func drainProcEvents(events <-chan ProcEvent) <-chan ProcEvent {
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return nil
+			}
+		default:
+			return events
+		}
+	}
+}
+
+func namedProcessEvent(last, cur map[int32]procSample, elapsed float64) NamedProcessStat {
+	ev := NamedProcessStat{Instances: []TopStat{}, Started: []int32{}, Exited: []int32{}}
+	for pid, s := range cur {
+		prev, ok := last[pid]
+		if !ok || prev.startTime != s.startTime {
+			ev.Started = append(ev.Started, pid)
+			continue
+		}
+		percent := counterRate(prev.ticks, s.ticks, elapsed) / ClocksPerSec * 100
+		ev.Instances = append(ev.Instances, TopStat{Pid: pid, Name: s.name, Percent: percent})
+		ev.Percent += percent
+	}
+	for pid, s := range last {
+		if c, ok := cur[pid]; !ok || c.startTime != s.startTime {
+			ev.Exited = append(ev.Exited, pid)
+		}
+	}
+	sort.Slice(ev.Instances, func(i, j int) bool { return ev.Instances[i].Pid < ev.Instances[j].Pid })
+	sort.Slice(ev.Started, func(i, j int) bool { return ev.Started[i] < ev.Started[j] })
+	sort.Slice(ev.Exited, func(i, j int) bool { return ev.Exited[i] < ev.Exited[j] })
+	return ev
+}
+
+// sampleMatching samples the processes matching re, matched caches the result of the
+// match of every process seen so far.
+func sampleMatching(ctx context.Context, re *regexp.Regexp, matched map[matchKey]bool) (map[int32]procSample, error) {
+	samples, err := sampleProcs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// forget the processes that are gone
+	for k := range matched {
+		if s, ok := samples[k.pid]; !ok || s.startTime != k.startTime || s.name != k.name {
+			delete(matched, k)
+		}
+	}
+	for pid, s := range samples {
+		k := matchKey{pid: pid, startTime: s.startTime, name: s.name}
+		ok, seen := matched[k]
+		if !seen {
+			ok = re.MatchString(s.name)
+			if !ok {
+				// kernel threads and zombies have no cmdline
+				if cmdline, err := ReadFile(HostProcWithContext(ctx, strconv.Itoa(int(pid)), "cmdline")); err == nil {
+					ok = re.MatchString(strings.TrimRight(strings.ReplaceAll(cmdline, "\x00", " "), " "))
+				}
+			}
+			matched[k] = ok
+		}
+		if !ok {
+			delete(samples, pid)
+		}
+	}
+	return samples, nil
+}
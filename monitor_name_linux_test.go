@@ -0,0 +1,101 @@
+package cpuproc
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func Test_sampleMatching(t *testing.T) {
+	// forked by the supervisor, not exec'd yet
+	ctx := newTestContext(t, map[string]string{
+		"proc/1/stat":     procStat("1", "init", "1", "1", "0", "0", "1"),
+		"proc/1/cmdline":  "/sbin/init\x00",
+		"proc/42/stat":    procStat("42", "supervisor", "42", "42", "0", "0", "900"),
+		"proc/42/cmdline": "supervisor\x00--workers\x004\x00",
+	})
+	re := regexp.MustCompile("^worker")
+	matched := make(map[matchKey]bool)
+
+	got, err := sampleMatching(ctx, re, matched)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("got %v before exec, want nothing", got)
+	}
+
+	// execve changes the comm and cmdline, not the pid nor the start time
+	for name, content := range map[string]string{
+		"42/stat":    procStat("42", "worker", "42", "42", "10", "0", "900"),
+		"42/cmdline": "worker\x00--id\x001\x00",
+	} {
+		if err := os.WriteFile(HostProcWithContext(ctx, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err = sampleMatching(ctx, re, matched)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got[42]; !ok || len(got) != 1 {
+		t.Errorf("got %v after exec, want pid 42", got)
+	}
+	if len(matched) != 2 {
+		t.Errorf("%d cached matches, want the pre-exec one forgotten", len(matched))
+	}
+}
+
+func Test_rescanOn(t *testing.T) {
+	for _, tc := range []struct {
+		ev   ProcEvent
+		want bool
+	}{
+		{ProcEvent{Type: ProcEventFork, Pid: 43, Tgid: 43, ParentPid: 42, ParentTgid: 42}, true},
+		{ProcEvent{Type: ProcEventExec, Pid: 43, Tgid: 43}, true},
+		{ProcEvent{Type: ProcEventComm, Pid: 43, Tgid: 43}, true},
+		// a new thread or a thread naming itself
+		{ProcEvent{Type: ProcEventFork, Pid: 44, Tgid: 43, ParentPid: 43, ParentTgid: 43}, false},
+		{ProcEvent{Type: ProcEventComm, Pid: 44, Tgid: 43}, false},
+		{ProcEvent{Type: ProcEventExit, Pid: 43, Tgid: 43}, false},
+	} {
+		if got := rescanOn(tc.ev); got != tc.want {
+			t.Errorf("rescanOn(%+v) = %v, want %v", tc.ev, got, tc.want)
+		}
+	}
+
+	events := make(chan ProcEvent, 3)
+	events <- ProcEvent{}
+	events <- ProcEvent{}
+	if got := drainProcEvents(events); got == nil || len(events) != 0 {
+		t.Errorf("drainProcEvents left %d events", len(events))
+	}
+	close(events)
+	if got := drainProcEvents(events); got != nil {
+		t.Error("drainProcEvents of a closed channel is not nil")
+	}
+}
+
+func Test_MonitorByNameEvents(t *testing.T) {
+	ctx := newTestContext(t, map[string]string{
+		"proc/42/stat":    procStat("42", "worker", "42", "42", "10", "0", "900"),
+		"proc/42/cmdline": "worker\x00",
+	})
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// without the proc connector it polls
+	ch, err := MonitorByName(ctx, "^worker", 10*time.Millisecond, WithProcessEvents(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := <-ch
+	if len(ev.Instances) != 1 || ev.Instances[0].Pid != 42 {
+		t.Errorf("got %+v, want pid 42", ev)
+	}
+	cancel()
+	for range ch {
+	}
+}
@@ -0,0 +1,125 @@
+package cpuproc
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NetIOCountersStat contains the counters of a network interface since it was created.
+// It is based on linux /proc/net/dev.
+type NetIOCountersStat struct {
+	Name        string `json:"name"`
+	BytesRecv   uint64 `json:"bytesRecv"`
+	PacketsRecv uint64 `json:"packetsRecv"`
+	Errin       uint64 `json:"errin"`
+	Dropin      uint64 `json:"dropin"`
+	BytesSent   uint64 `json:"bytesSent"`
+	PacketsSent uint64 `json:"packetsSent"`
+	Errout      uint64 `json:"errout"`
+	Dropout     uint64 `json:"dropout"`
+}
+
+// NetIORateStat contains the per second rates of a network interface over an interval.
+type NetIORateStat struct {
+	Name        string  `json:"name"`
+	BytesRecv   float64 `json:"bytesRecv"`
+	PacketsRecv float64 `json:"packetsRecv"`
+	Errin       float64 `json:"errin"`
+	Dropin      float64 `json:"dropin"`
+	BytesSent   float64 `json:"bytesSent"`
+	PacketsSent float64 `json:"packetsSent"`
+	Errout      float64 `json:"errout"`
+	Dropout     float64 `json:"dropout"`
+}
+
+// NetIOCounters returns the counters of the named interfaces, or of all interfaces when no name is given.
+func NetIOCounters(names ...string) (map[string]NetIOCountersStat, error) {
+	return NetIOCountersWithContext(context.Background(), names...)
+}
+
+func NetIOCountersWithContext(ctx context.Context, names ...string) (map[string]NetIOCountersStat, error) {
+	lines, err := ReadLines(HostProcWithContext(ctx, "net/dev"))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]NetIOCountersStat, len(lines))
+	for _, line := range lines {
+		name, rest, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		if len(names) > 0 && !slices.Contains(names, name) {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) < 16 {
+			continue
+		}
+
+		var v [16]uint64
+		for i := range v {
+			if v[i], err = strconv.ParseUint(fields[i], 10, 64); err != nil {
+				return nil, err
+			}
+		}
+		ret[name] = NetIOCountersStat{
+			Name:        name,
+			BytesRecv:   v[0],
+			PacketsRecv: v[1],
+			Errin:       v[2],
+			Dropin:      v[3],
+			BytesSent:   v[8],
+			PacketsSent: v[9],
+			Errout:      v[10],
+			Dropout:     v[11],
+		}
+	}
+	return ret, nil
+}
+
+func NetIORate(interval time.Duration, names ...string) (map[string]NetIORateStat, error) {
+	return NetIORateWithContext(context.Background(), interval, names...)
+}
+
+func NetIORateWithContext(ctx context.Context, interval time.Duration, names ...string) (map[string]NetIORateStat, error) {
+	n1, err := NetIOCountersWithContext(ctx, names...)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	if err := Sleep(ctx, interval); err != nil {
+		return nil, err
+	}
+
+	n2, err := NetIOCountersWithContext(ctx, names...)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start).Seconds()
+
+	ret := make(map[string]NetIORateStat, len(n2))
+	for name, c := range n2 {
+		p, ok := n1[name]
+		if !ok {
+			continue
+		}
+		ret[name] = NetIORateStat{
+			Name:        name,
+			BytesRecv:   counterRate(p.BytesRecv, c.BytesRecv, elapsed),
+			PacketsRecv: counterRate(p.PacketsRecv, c.PacketsRecv, elapsed),
+			Errin:       counterRate(p.Errin, c.Errin, elapsed),
+			Dropin:      counterRate(p.Dropin, c.Dropin, elapsed),
+			BytesSent:   counterRate(p.BytesSent, c.BytesSent, elapsed),
+			PacketsSent: counterRate(p.PacketsSent, c.PacketsSent, elapsed),
+			Errout:      counterRate(p.Errout, c.Errout, elapsed),
+			Dropout:     counterRate(p.Dropout, c.Dropout, elapsed),
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,114 @@
+package cpuproc
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NodeCPUs returns the logical cpus of every NUMA node, keyed by node id.
+// It is based on linux /sys/devices/system/node/node*/cpulist.
+func NodeCPUs() (map[int][]int, error) {
+	return NodeCPUsWithContext(context.Background())
+}
+
+func NodeCPUsWithContext(ctx context.Context) (map[int][]int, error) {
+	files, err := filepath.Glob(HostSysWithContext(ctx, "devices/system/node/node[0-9]*/cpulist"))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[int][]int, len(files))
+	for _, file := range files {
+		node, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(filepath.Dir(file)), "node"))
+		if err != nil {
+			continue
+		}
+		cpus, err := readCPUListFile(file)
+		if err != nil {
+			return nil, err
+		}
+		ret[node] = cpus
+	}
+	return ret, nil
+}
+
+func TimesByNode() ([]TimesStat, error) {
+	return TimesByNodeWithContext(context.Background())
+}
+
+// TimesByNodeWithContext sums the per-cpu times of /proc/stat by NUMA node.
+// The result is sorted by node id and the CPU field is named "node0", "node1"...
+// On kernels without NUMA support all cpus are reported as node0.
+func TimesByNodeWithContext(ctx context.Context) ([]TimesStat, error) {
+	nodes, err := NodeCPUsWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cpuTimes, err := TimesWithContext(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	cpuToNode := make(map[int]int)
+	for node, cpus := range nodes {
+		for _, cpu := range cpus {
+			cpuToNode[cpu] = node
+		}
+	}
+
+	byNode := make(map[int]TimesStat)
+	for _, t := range cpuTimes {
+		cpu, ok := cpuIndex(t.CPU)
+		if !ok {
+			continue
+		}
+		node := cpuToNode[cpu]
+		sum, ok := byNode[node]
+		if !ok {
+			sum.CPU = fmt.Sprintf("node%d", node)
+		}
+		byNode[node] = sum.add(t)
+	}
+
+	ids := make([]int, 0, len(byNode))
+	for node := range byNode {
+		ids = append(ids, node)
+	}
+	sort.Ints(ids)
+
+	ret := make([]TimesStat, 0, len(ids))
+	for _, node := range ids {
+		ret = append(ret, byNode[node])
+	}
+	return ret, nil
+}
+
+func PercentByNode(interval time.Duration) ([]float64, error) {
+	return PercentByNodeWithContext(context.Background(), interval)
+}
+
+// PercentByNodeWithContext returns the busy percent of every NUMA node over interval,
+// in the same order as TimesByNodeWithContext.
+func PercentByNodeWithContext(ctx context.Context, interval time.Duration) ([]float64, error) {
+	nodeTimes1, err := TimesByNodeWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Sleep(ctx, interval); err != nil {
+		return nil, err
+	}
+
+	nodeTimes2, err := TimesByNodeWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return calculateAllBusy(nodeTimes1, nodeTimes2)
+}
@@ -0,0 +1,138 @@
+// Package otelmetrics registers the metrics of cpuproc as OpenTelemetry asynchronous
+// instruments, named after the system and process semantic conventions.
+package otelmetrics
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/antlabs/cpuproc"
+)
+
+// attribute keys of the semantic conventions
+const (
+	cpuModeKey          = attribute.Key("cpu.mode")
+	cpuLogicalNumberKey = attribute.Key("cpu.logical_number")
+	processPidKey       = attribute.Key("process.pid")
+)
+
+type options struct {
+	pids    []int32
+	monitor *cpuproc.Monitor
+}
+
+// Option configures Register.
+type Option func(*options)
+
+// WithProcesses reports process.cpu.time for pids.
+func WithProcesses(pids ...int32) Option {
+	return func(o *options) {
+		o.pids = append(o.pids, pids...)
+	}
+}
+
+// WithMonitor reports system.cpu.utilization and process.cpu.utilization from the latest
+// sample of m. The system utilization is the busy fraction of every cpu, or of the machine
+// without a cpu.logical_number when WithPerCPU is off.
+func WithMonitor(m *cpuproc.Monitor) Option {
+	return func(o *options) {
+		o.monitor = m
+	}
+}
+
+// Register registers the instruments with meter, they are observed on every collection.
+// Unregister the returned registration to stop reporting.
+func Register(meter metric.Meter, opts ...Option) (metric.Registration, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	cpuTime, err := meter.Float64ObservableCounter("system.cpu.time",
+		metric.WithUnit("s"), metric.WithDescription("Seconds each logical cpu spent on each mode"))
+	if err != nil {
+		return nil, err
+	}
+	procTime, err := meter.Float64ObservableCounter("process.cpu.time",
+		metric.WithUnit("s"), metric.WithDescription("Total cpu seconds broken down by different cpu modes"))
+	if err != nil {
+		return nil, err
+	}
+	instruments := []metric.Observable{cpuTime, procTime}
+
+	var cpuUtil, procUtil metric.Float64ObservableGauge
+	if o.monitor != nil {
+		cpuUtil, err = meter.Float64ObservableGauge("system.cpu.utilization",
+			metric.WithUnit("1"), metric.WithDescription("Busy fraction of the cpus over the latest sample"))
+		if err != nil {
+			return nil, err
+		}
+		procUtil, err = meter.Float64ObservableGauge("process.cpu.utilization",
+			metric.WithUnit("1"), metric.WithDescription("Cpu usage of the process over the latest sample, normalized by the cpus"))
+		if err != nil {
+			return nil, err
+		}
+		instruments = append(instruments, cpuUtil, procUtil)
+	}
+
+	return meter.RegisterCallback(func(ctx context.Context, obs metric.Observer) error {
+		if times, err := cpuproc.TimesWithContext(ctx, true); err == nil {
+			for _, t := range times {
+				cpu := cpuLogicalNumberKey.Int(logicalNumber(t.CPU))
+				for mode, v := range map[string]float64{
+					"user":      t.User,
+					"nice":      t.Nice,
+					"system":    t.System,
+					"idle":      t.Idle,
+					"iowait":    t.Iowait,
+					"interrupt": t.Irq + t.Softirq,
+					"steal":     t.Steal,
+				} {
+					obs.ObserveFloat64(cpuTime, v, metric.WithAttributes(cpu, cpuModeKey.String(mode)))
+				}
+			}
+		}
+
+		for _, pid := range o.pids {
+			p := cpuproc.NewProcess(pid)
+			if p == nil {
+				continue
+			}
+			t, err := p.TimesWithContext(ctx)
+			if err != nil {
+				continue
+			}
+			attr := processPidKey.Int(int(pid))
+			obs.ObserveFloat64(procTime, t.User, metric.WithAttributes(attr, cpuModeKey.String("user")))
+			obs.ObserveFloat64(procTime, t.System, metric.WithAttributes(attr, cpuModeKey.String("system")))
+		}
+
+		if o.monitor == nil {
+			return nil
+		}
+		s := o.monitor.Latest()
+		if s.Time.IsZero() {
+			return nil
+		}
+		if s.PerCPU == nil {
+			obs.ObserveFloat64(cpuUtil, s.Total/100)
+		}
+		for _, c := range s.PerCPU {
+			obs.ObserveFloat64(cpuUtil, c.Percent/100, metric.WithAttributes(cpuLogicalNumberKey.Int(logicalNumber(c.CPU))))
+		}
+		for pid, v := range s.Processes {
+			obs.ObserveFloat64(procUtil, v/100, metric.WithAttributes(processPidKey.Int(int(pid))))
+		}
+		return nil
+	}, instruments...)
+}
+
+// logicalNumber returns the number of a cpu named like "cpu3".
+func logicalNumber(name string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(name, "cpu"))
+	return n
+}
@@ -0,0 +1,98 @@
+package otelmetrics
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/antlabs/cpuproc"
+)
+
+func TestRegister(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer provider.Shutdown(context.Background())
+
+	reg, err := Register(provider.Meter("cpuproc"), WithProcesses(int32(os.Getpid())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reg.Unregister()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			got[m.Name] = true
+		}
+	}
+	for _, name := range []string{"system.cpu.time", "process.cpu.time"} {
+		if !got[name] {
+			t.Errorf("%s not reported", name)
+		}
+	}
+}
+
+func TestRegisterCPUNumbers(t *testing.T) {
+	m, err := cpuproc.NewMonitor(cpuproc.WithPerCPU(true), cpuproc.WithSampleInterval(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Stop()
+	for m.Latest().Time.IsZero() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer provider.Shutdown(context.Background())
+	reg, err := Register(provider.Meter("cpuproc"), WithMonitor(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reg.Unregister()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	cpus := map[string]map[int64]bool{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, metric := range sm.Metrics {
+			cpus[metric.Name] = map[int64]bool{}
+			var sets []attribute.Set
+			switch data := metric.Data.(type) {
+			case metricdata.Sum[float64]:
+				for _, p := range data.DataPoints {
+					sets = append(sets, p.Attributes)
+				}
+			case metricdata.Gauge[float64]:
+				for _, p := range data.DataPoints {
+					sets = append(sets, p.Attributes)
+				}
+			}
+			for _, set := range sets {
+				if v, ok := set.Value(cpuLogicalNumberKey); ok {
+					cpus[metric.Name][v.AsInt64()] = true
+				}
+			}
+		}
+	}
+	if len(cpus["system.cpu.utilization"]) == 0 {
+		t.Fatal("no per cpu utilization")
+	}
+	// a cpu has the same number in both instruments
+	for cpu := range cpus["system.cpu.utilization"] {
+		if !cpus["system.cpu.time"][cpu] {
+			t.Errorf("utilization of cpu %d has no cpu time", cpu)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package cpuproc
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type overloadOptions struct {
+	threshold   float64
+	ceiling     float64
+	retryAfter  time.Duration
+	exempt      []string
+	monitorOpts []MonitorOption
+	cpu         func() float64
+}
+
+// OverloadOption configures an OverloadProtector.
+type OverloadOption func(*overloadOptions)
+
+// WithOverloadThreshold sets the cpu percent above which requests start to be shed, 80 by
+// default.
+func WithOverloadThreshold(percent float64) OverloadOption {
+	return func(o *overloadOptions) {
+		o.threshold = percent
+	}
+}
+
+// WithOverloadCeiling sets the cpu percent at which all requests are shed, the fraction of
+// requests shed ramps up linearly from the threshold to it. It is the threshold by default,
+// which is a hard cutoff.
+func WithOverloadCeiling(percent float64) OverloadOption {
+	return func(o *overloadOptions) {
+		o.ceiling = percent
+	}
+}
+
+// WithRetryAfter sets the Retry-After of shed requests, one second by default.
+func WithRetryAfter(d time.Duration) OverloadOption {
+	return func(o *overloadOptions) {
+		o.retryAfter = d
+	}
+}
+
+// WithExemptPaths exempts the requests whose path starts with one of prefixes from shedding,
+// e.g. "/healthz".
+func WithExemptPaths(prefixes ...string) OverloadOption {
+	return func(o *overloadOptions) {
+		o.exempt = append(o.exempt, prefixes...)
+	}
+}
+
+// WithOverloadMonitorOptions sets the options of the Monitor sampling the cpu, by default it
+// samples every 250ms with a one second WithSmoothing.
+func WithOverloadMonitorOptions(opts ...MonitorOption) OverloadOption {
+	return func(o *overloadOptions) {
+		o.monitorOpts = append(o.monitorOpts, opts...)
+	}
+}
+
+// WithOverloadCPUSource makes the OverloadProtector read the cpu percent from fn instead of
+// sampling it with a Monitor, e.g. to share a Monitor or to stub the cpu in tests.
+func WithOverloadCPUSource(fn func() float64) OverloadOption {
+	return func(o *overloadOptions) {
+		o.cpu = fn
+	}
+}
+
+// OverloadProtector sheds http requests with 503 Service Unavailable when the busy percent of
+// the machine is above a threshold.
+type OverloadProtector struct {
+	opts    overloadOptions
+	monitor *Monitor
+	cpu     func() float64
+}
+
+// NewOverloadProtector starts an OverloadProtector, it must be stopped with Stop.
+func NewOverloadProtector(opts ...OverloadOption) (*OverloadProtector, error) {
+	o := overloadOptions{threshold: 80, retryAfter: time.Second}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.ceiling == 0 {
+		o.ceiling = o.threshold
+	}
+	if o.threshold <= 0 || o.ceiling < o.threshold {
+		return nil, errors.New("threshold must be positive and not above the ceiling")
+	}
+
+	if o.cpu != nil {
+		return &OverloadProtector{opts: o, cpu: o.cpu}, nil
+	}
+	m, err := NewMonitor(append([]MonitorOption{
+		WithSampleInterval(250 * time.Millisecond),
+		WithSmoothing(time.Second),
+	}, o.monitorOpts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &OverloadProtector{opts: o, monitor: m, cpu: m.Total}, nil
+}
+
+// shedFraction returns the fraction of requests to shed at cpu.
+func (p *OverloadProtector) shedFraction(cpu float64) float64 {
+	if cpu <= p.opts.threshold {
+		return 0
+	}
+	if cpu >= p.opts.ceiling {
+		return 1
+	}
+	return (cpu - p.opts.threshold) / (p.opts.ceiling - p.opts.threshold)
+}
+
+// Shed tells whether a request should be shed at the current cpu usage, for servers other
+// than net/http. The fraction of true results follows the ramp from the threshold to the
+// ceiling.
+func (p *OverloadProtector) Shed() bool {
+	f := p.shedFraction(p.cpu())
+	return f > 0 && rand.Float64() < f
+}
+
+// Overloaded tells whether the cpu usage is above the threshold.
+func (p *OverloadProtector) Overloaded() bool {
+	return p.cpu() > p.opts.threshold
+}
+
+// RetryAfter returns the delay after which shed requests may be retried.
+func (p *OverloadProtector) RetryAfter() time.Duration {
+	return p.opts.retryAfter
+}
+
+func (p *OverloadProtector) exempt(path string) bool {
+	for _, prefix := range p.opts.exempt {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Wrap returns a handler calling next unless the request is shed.
+func (p *OverloadProtector) Wrap(next http.Handler) http.Handler {
+	retryAfter := strconv.Itoa(int(math.Ceil(p.opts.retryAfter.Seconds())))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !p.exempt(r.URL.Path) && p.Shed() {
+			w.Header().Set("Retry-After", retryAfter)
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Stop stops sampling the cpu, the wrapped handlers then no longer shed requests, unless
+// the cpu comes from WithOverloadCPUSource.
+func (p *OverloadProtector) Stop() {
+	if p.monitor == nil {
+		return
+	}
+	p.monitor.Stop()
+	p.monitor.Reset()
+}
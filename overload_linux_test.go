@@ -0,0 +1,34 @@
+package cpuproc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_OverloadProtector(t *testing.T) {
+	p, err := NewOverloadProtector(WithOverloadThreshold(60), WithOverloadCeiling(90), WithExemptPaths("/healthz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	for cpu, want := range map[float64]float64{50: 0, 75: 0.5, 95: 1} {
+		if got := p.shedFraction(cpu); got != want {
+			t.Errorf("shedFraction(%v) = %v, want %v", cpu, got, want)
+		}
+	}
+
+	p.cpu = func() float64 { return 100 }
+	h := p.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for path, want := range map[string]int{"/api": http.StatusServiceUnavailable, "/healthz/live": http.StatusOK} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != want {
+			t.Errorf("%s: status %d, want %d", path, w.Code, want)
+		}
+		if want != http.StatusOK && w.Header().Get("Retry-After") != "1" {
+			t.Errorf("%s: Retry-After %q, want 1", path, w.Header().Get("Retry-After"))
+		}
+	}
+}
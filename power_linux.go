@@ -0,0 +1,111 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// PowerZoneStat contains the energy counter of a RAPL power zone, e.g. "package-0",
+// "core" or "dram". Energy is in microjoules and wraps at MaxEnergyRange.
+// It is based on linux /sys/class/powercap/intel-rapl*, which is also used by AMD cpus.
+// The counters are only readable by root since linux 5.10.
+type PowerZoneStat struct {
+	Zone           string `json:"zone"`
+	Name           string `json:"name"`
+	Energy         uint64 `json:"energy"`
+	MaxEnergyRange uint64 `json:"maxEnergyRange"`
+}
+
+// PowerStat contains the average power of a zone over an interval.
+type PowerStat struct {
+	Zone  string  `json:"zone"`
+	Name  string  `json:"name"`
+	Watts float64 `json:"watts"`
+}
+
+func PowerZones() ([]PowerZoneStat, error) {
+	return PowerZonesWithContext(context.Background())
+}
+
+// PowerZonesWithContext returns the RAPL zones and subzones sorted by zone, e.g.
+// intel-rapl:0 (package-0) followed by intel-rapl:0:0 (core).
+func PowerZonesWithContext(ctx context.Context) ([]PowerZoneStat, error) {
+	dirs, err := filepath.Glob(HostSysWithContext(ctx, "class/powercap/intel-rapl:*"))
+	if err != nil {
+		return nil, err
+	}
+	if len(dirs) == 0 {
+		return nil, errors.New("rapl is not available")
+	}
+
+	ret := make([]PowerZoneStat, 0, len(dirs))
+	for _, dir := range dirs {
+		z := PowerZoneStat{Zone: filepath.Base(dir)}
+		if z.Name, err = readTrimmed(filepath.Join(dir, "name")); err != nil {
+			return nil, err
+		}
+		if z.Energy, err = readUint(filepath.Join(dir, "energy_uj")); err != nil {
+			return nil, err
+		}
+		if z.MaxEnergyRange, err = readUint(filepath.Join(dir, "max_energy_range_uj")); err != nil {
+			return nil, err
+		}
+		ret = append(ret, z)
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Zone < ret[j].Zone })
+	return ret, nil
+}
+
+func Power(interval time.Duration) ([]PowerStat, error) {
+	return PowerWithContext(context.Background(), interval)
+}
+
+// PowerWithContext returns the average power of every zone over interval.
+// The interval should be well below the wrap time of the counters, usually minutes.
+func PowerWithContext(ctx context.Context, interval time.Duration) ([]PowerStat, error) {
+	z1, err := PowerZonesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	if err := Sleep(ctx, interval); err != nil {
+		return nil, err
+	}
+
+	z2, err := PowerZonesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start).Seconds()
+
+	prev := make(map[string]PowerZoneStat, len(z1))
+	for _, z := range z1 {
+		prev[z.Zone] = z
+	}
+
+	ret := make([]PowerStat, 0, len(z2))
+	for _, z := range z2 {
+		p, ok := prev[z.Zone]
+		if !ok || elapsed <= 0 {
+			continue
+		}
+		var delta uint64
+		if z.Energy >= p.Energy {
+			delta = z.Energy - p.Energy
+		} else {
+			// the counter wrapped
+			delta = z.MaxEnergyRange - p.Energy + z.Energy
+		}
+		ret = append(ret, PowerStat{
+			Zone:  z.Zone,
+			Name:  z.Name,
+			Watts: float64(delta) / 1e6 / elapsed,
+		})
+	}
+	return ret, nil
+}
@@ -0,0 +1,177 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	PressureCPU    = "cpu"
+	PressureMemory = "memory"
+	PressureIO     = "io"
+)
+
+// PressureLine is one line of a PSI file. Averages are percents, Total is the
+// accumulated stall time in microseconds.
+type PressureLine struct {
+	Avg10  float64 `json:"avg10"`
+	Avg60  float64 `json:"avg60"`
+	Avg300 float64 `json:"avg300"`
+	Total  uint64  `json:"total"`
+}
+
+// PressureStat contains the pressure stall information of a resource.
+// It is based on linux /proc/pressure/{cpu,memory,io} or the *.pressure files of a cgroup.
+type PressureStat struct {
+	Some PressureLine `json:"some"`
+	Full PressureLine `json:"full"`
+}
+
+// PressureTrigger describes a PSI trigger: the watcher fires when tasks are stalled
+// for more than Threshold within any Window. Window must be between 500ms and 10s,
+// unprivileged users are limited to multiples of 2s.
+type PressureTrigger struct {
+	Full      bool          `json:"full"`
+	Threshold time.Duration `json:"threshold"`
+	Window    time.Duration `json:"window"`
+}
+
+func Pressure(resource string) (*PressureStat, error) {
+	return PressureWithContext(context.Background(), resource)
+}
+
+// PressureWithContext returns the system wide pressure of resource, one of PressureCPU,
+// PressureMemory or PressureIO.
+func PressureWithContext(ctx context.Context, resource string) (*PressureStat, error) {
+	return ReadPressure(HostProcWithContext(ctx, "pressure", resource))
+}
+
+// ReadPressure parses a PSI file, e.g. /sys/fs/cgroup/<group>/cpu.pressure.
+func ReadPressure(filename string) (*PressureStat, error) {
+	lines, err := ReadLines(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &PressureStat{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		var dst *PressureLine
+		switch fields[0] {
+		case "some":
+			dst = &ret.Some
+		case "full":
+			dst = &ret.Full
+		default:
+			continue
+		}
+		for _, f := range fields[1:] {
+			key, value, ok := strings.Cut(f, "=")
+			if !ok {
+				continue
+			}
+			switch key {
+			case "avg10":
+				dst.Avg10, err = strconv.ParseFloat(value, 64)
+			case "avg60":
+				dst.Avg60, err = strconv.ParseFloat(value, 64)
+			case "avg300":
+				dst.Avg300, err = strconv.ParseFloat(value, 64)
+			case "total":
+				dst.Total, err = strconv.ParseUint(value, 10, 64)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ret, nil
+}
+
+// WatchPressure registers a PSI trigger on the system wide pressure of resource and sends
+// the current pressure on the returned channel every time the trigger fires.
+// The channel is closed when ctx is done or the trigger can't be polled any more.
+func WatchPressure(ctx context.Context, resource string, trigger PressureTrigger) (<-chan PressureStat, error) {
+	return WatchPressureFile(ctx, HostProcWithContext(ctx, "pressure", resource), trigger)
+}
+
+// WatchPressureFile is like WatchPressure for any PSI file, e.g. the ones of a cgroup.
+func WatchPressureFile(ctx context.Context, filename string, trigger PressureTrigger) (<-chan PressureStat, error) {
+	if trigger.Threshold <= 0 || trigger.Window <= 0 {
+		return nil, errors.New("threshold and window must be positive")
+	}
+
+	f, err := os.OpenFile(filename, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	kind := "some"
+	if trigger.Full {
+		kind = "full"
+	}
+	// the kernel expects the trigger to be NUL terminated
+	spec := fmt.Sprintf("%s %d %d\x00", kind, trigger.Threshold.Microseconds(), trigger.Window.Microseconds())
+	if _, err := f.WriteString(spec); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	wakeup, stop, err := newCtxWakeup(ctx)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	ch := make(chan PressureStat)
+	go func() {
+		defer close(ch)
+		defer f.Close()
+		defer stop()
+
+		fds := []unix.PollFd{
+			{Fd: int32(f.Fd()), Events: unix.POLLPRI},
+			{Fd: int32(wakeup), Events: unix.POLLIN},
+		}
+		for {
+			_, err := unix.Poll(fds, -1)
+			if err != nil {
+				if err == unix.EINTR {
+					continue
+				}
+				return
+			}
+			if fds[1].Revents != 0 {
+				return
+			}
+			if fds[0].Revents&unix.POLLERR != 0 {
+				// the monitored cgroup went away
+				return
+			}
+			if fds[0].Revents&unix.POLLPRI == 0 {
+				continue
+			}
+
+			st, err := ReadPressure(filename)
+			if err != nil {
+				continue
+			}
+			select {
+			case ch <- *st:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
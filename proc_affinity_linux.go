@@ -0,0 +1,85 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// SetAffinity pins every thread of the process to cpus, which must be online.
+func (p *proc) SetAffinity(cpus []int) error {
+	return p.SetAffinityWithContext(context.Background(), cpus)
+}
+
+func (p *proc) SetAffinityWithContext(ctx context.Context, cpus []int) error {
+	if len(cpus) == 0 {
+		return errors.New("empty cpu list")
+	}
+	online, err := OnlineCPUsWithContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var set unix.CPUSet
+	for _, cpu := range cpus {
+		if !slices.Contains(online, cpu) {
+			return fmt.Errorf("cpu %d is not online", cpu)
+		}
+		set.Set(cpu)
+	}
+
+	// sched_setaffinity only changes one thread
+	tasks, err := os.ReadDir(p.procPath(ctx, "task"))
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil {
+			continue
+		}
+		if err := unix.SchedSetaffinity(tid, &set); err != nil && err != unix.ESRCH {
+			return err
+		}
+	}
+	return nil
+}
+
+// Affinity returns the cpus the process may run on. The affinity is read again on every
+// call as it may change after NewProcess.
+func (p *proc) Affinity() ([]int, error) {
+	return p.AffinityWithContext(context.Background())
+}
+
+func (p *proc) AffinityWithContext(ctx context.Context) ([]int, error) {
+	var set unix.CPUSet
+	if err := unix.SchedGetaffinity(int(p.pid), &set); err != nil {
+		return nil, err
+	}
+
+	ret := make([]int, 0, set.Count())
+	for cpu := 0; len(ret) < set.Count(); cpu++ {
+		if set.IsSet(cpu) {
+			ret = append(ret, cpu)
+		}
+	}
+	return ret, nil
+}
+
+// AffinityCount returns the number of cpus the process may run on.
+func (p *proc) AffinityCount() (int, error) {
+	return p.AffinityCountWithContext(context.Background())
+}
+
+func (p *proc) AffinityCountWithContext(ctx context.Context) (int, error) {
+	cpus, err := p.AffinityWithContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return len(cpus), nil
+}
@@ -0,0 +1,210 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// child returns the process pid with the same options as p.
+func (p *proc) child(pid int32) *proc {
+	c := &proc{set: p.set, pid: pid, opts: p.opts}
+	c.startTime, _ = c.startTimeWithContext(context.Background())
+	return c
+}
+
+func (p *proc) Children(recursive bool) ([]*proc, error) {
+	return p.ChildrenWithContext(context.Background(), recursive)
+}
+
+// ChildrenWithContext returns the child processes of p, and all their descendants when
+// recursive is set. It reads /proc/<pid>/task/*/children, which needs CONFIG_PROC_CHILDREN,
+// and falls back to scanning the parent of every process.
+func (p *proc) ChildrenWithContext(ctx context.Context, recursive bool) ([]*proc, error) {
+	var pids []int32
+	if _, err := os.Stat(p.procPath(ctx, "task", strconv.Itoa(int(p.pid)), "children")); err == nil {
+		pids, err = childrenFromTasks(ctx, p.pid, recursive)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err := os.Stat(p.procPath(ctx)); err != nil {
+			return nil, err
+		}
+		parents, err := ppidMap(ctx)
+		if err != nil {
+			return nil, err
+		}
+		pids = descendants(parents, p.pid, recursive)
+	}
+
+	ret := make([]*proc, 0, len(pids))
+	for _, pid := range pids {
+		ret = append(ret, p.child(pid))
+	}
+	return ret, nil
+}
+
+// childrenFromTasks collects the children of every thread of pid, the children file of a
+// thread only lists the processes that thread forked.
+func childrenFromTasks(ctx context.Context, pid int32, recursive bool) ([]int32, error) {
+	var ret []int32
+	queue := []int32{pid}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		tasks, err := os.ReadDir(HostProcWithContext(ctx, strconv.Itoa(int(cur)), "task"))
+		if err != nil {
+			if cur == pid {
+				return nil, err
+			}
+			// a descendant exited meanwhile
+			continue
+		}
+		for _, task := range tasks {
+			content, err := ReadFile(HostProcWithContext(ctx, strconv.Itoa(int(cur)), "task", task.Name(), "children"))
+			if err != nil {
+				continue
+			}
+			for _, f := range strings.Fields(content) {
+				child, err := strconv.ParseInt(f, 10, 32)
+				if err != nil {
+					continue
+				}
+				ret = append(ret, int32(child))
+				if recursive {
+					queue = append(queue, int32(child))
+				}
+			}
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret, nil
+}
+
+// ppidMap returns the children of every process, keyed by parent pid.
+func ppidMap(ctx context.Context) (map[int32][]int32, error) {
+	entries, err := os.ReadDir(HostProcWithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[int32][]int32)
+	for _, e := range entries {
+		pid, err := strconv.ParseInt(e.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+		contents, err := os.ReadFile(HostProcWithContext(ctx, e.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		fields := splitProcStat(contents)
+		ppid, err := strconv.ParseInt(fields[4], 10, 32)
+		if err != nil {
+			continue
+		}
+		ret[int32(ppid)] = append(ret[int32(ppid)], int32(pid))
+	}
+	return ret, nil
+}
+
+func descendants(parents map[int32][]int32, pid int32, recursive bool) []int32 {
+	var ret []int32
+	queue := []int32{pid}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, child := range parents[cur] {
+			ret = append(ret, child)
+			if recursive {
+				queue = append(queue, child)
+			}
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret
+}
+
+// Ppid returns the pid of the parent of the process, 0 for init and kthreadd.
+func (p *proc) Ppid() (int32, error) {
+	return p.PpidWithContext(context.Background())
+}
+
+func (p *proc) PpidWithContext(ctx context.Context) (int32, error) {
+	_, ppid, _, _, _, _, _, err := p.fillFromStatWithContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return ppid, nil
+}
+
+// Parent returns the parent process, with the same options as p.
+func (p *proc) Parent() (*proc, error) {
+	return p.ParentWithContext(context.Background())
+}
+
+func (p *proc) ParentWithContext(ctx context.Context) (*proc, error) {
+	ppid, err := p.PpidWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ppid == 0 {
+		return nil, errors.New("process has no parent")
+	}
+	return p.child(ppid), nil
+}
+
+// Ancestors returns the parent of the process, its grandparent and so on up to init,
+// closest first. It is empty for init.
+func (p *proc) Ancestors() ([]*proc, error) {
+	return p.AncestorsWithContext(context.Background())
+}
+
+// AncestorsWithContext walks the ppid links, an ancestor that exits meanwhile ends the
+// chain since its children are reparented.
+func (p *proc) AncestorsWithContext(ctx context.Context) ([]*proc, error) {
+	ppid, err := p.PpidWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ret []*proc
+	for ppid != 0 {
+		parent := p.child(ppid)
+		ret = append(ret, parent)
+		if ppid, err = parent.PpidWithContext(ctx); err != nil {
+			break
+		}
+	}
+	return ret, nil
+}
+
+func (p *proc) TreeCPUPercent() (float64, error) {
+	return p.TreeCPUPercentWithContext(context.Background())
+}
+
+// TreeCPUPercentWithContext is CPUPercent summed over p and all its descendants,
+// e.g. the master and the workers of nginx. Descendants that exit meanwhile are left out.
+func (p *proc) TreeCPUPercentWithContext(ctx context.Context) (float64, error) {
+	total, err := p.cpuCount(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sum, err := p.CPUPercentWithContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	children, err := p.ChildrenWithContext(ctx, true)
+	if err != nil {
+		return 0, err
+	}
+	for _, c := range children {
+		if percent, err := c.CPUPercentWithContext(ctx); err == nil {
+			sum += percent
+		}
+	}
+	return sum / (total * float64(100)), nil
+}
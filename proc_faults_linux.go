@@ -0,0 +1,55 @@
+package cpuproc
+
+import (
+	"context"
+	"time"
+)
+
+// PageFaultRateStat contains the page faults of a process per second over an interval.
+// Major faults had to read the page from disk, minor faults did not.
+type PageFaultRateStat struct {
+	MinorFaults float64 `json:"minorFaults"`
+	MajorFaults float64 `json:"majorFaults"`
+}
+
+func (p *proc) PageFaults() (*PageFaultsStat, error) {
+	return p.PageFaultsWithContext(context.Background())
+}
+
+// PageFaultsWithContext returns the page faults of the process since it started, and those
+// of its children that were waited for.
+func (p *proc) PageFaultsWithContext(ctx context.Context) (*PageFaultsStat, error) {
+	_, _, _, _, _, _, faults, err := p.fillFromStatWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return faults, nil
+}
+
+func (p *proc) PageFaultRate(interval time.Duration) (*PageFaultRateStat, error) {
+	return p.PageFaultRateWithContext(context.Background(), interval)
+}
+
+// PageFaultRateWithContext samples the page faults of the process twice, interval apart.
+func (p *proc) PageFaultRateWithContext(ctx context.Context, interval time.Duration) (*PageFaultRateStat, error) {
+	f1, err := p.PageFaultsWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	if err := Sleep(ctx, interval); err != nil {
+		return nil, err
+	}
+
+	f2, err := p.PageFaultsWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start).Seconds()
+
+	return &PageFaultRateStat{
+		MinorFaults: counterRate(f1.MinorFaults, f2.MinorFaults, elapsed),
+		MajorFaults: counterRate(f1.MajorFaults, f2.MajorFaults, elapsed),
+	}, nil
+}
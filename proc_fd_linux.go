@@ -0,0 +1,76 @@
+package cpuproc
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OpenFilesStat is an open file descriptor of a process. Path is the target of the fd,
+// e.g. "socket:[1234]" or "pipe:[5678]" for what is not a file. Position is the file
+// offset and Flags the open flags in octal, as in /proc/<pid>/fdinfo.
+type OpenFilesStat struct {
+	Fd       uint64 `json:"fd"`
+	Path     string `json:"path"`
+	Position uint64 `json:"position"`
+	Flags    uint64 `json:"flags"`
+}
+
+// NumFDs returns the number of file descriptors opened by the process.
+func (p *proc) NumFDs() (int32, error) {
+	return p.NumFDsWithContext(context.Background())
+}
+
+func (p *proc) NumFDsWithContext(ctx context.Context) (int32, error) {
+	entries, err := os.ReadDir(p.procPath(ctx, "fd"))
+	if err != nil {
+		return 0, err
+	}
+	return int32(len(entries)), nil
+}
+
+// OpenFiles lists the file descriptors of the process, sorted by fd.
+func (p *proc) OpenFiles() ([]OpenFilesStat, error) {
+	return p.OpenFilesWithContext(context.Background())
+}
+
+func (p *proc) OpenFilesWithContext(ctx context.Context) ([]OpenFilesStat, error) {
+	entries, err := os.ReadDir(p.procPath(ctx, "fd"))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]OpenFilesStat, 0, len(entries))
+	for _, e := range entries {
+		fd, err := strconv.ParseUint(e.Name(), 10, 64)
+		if err != nil {
+			continue
+		}
+		path, err := os.Readlink(p.procPath(ctx, "fd", e.Name()))
+		if err != nil {
+			// closed meanwhile
+			continue
+		}
+		f := OpenFilesStat{Fd: fd, Path: path}
+		if lines, err := ReadLines(p.procPath(ctx, "fdinfo", e.Name())); err == nil {
+			for _, line := range lines {
+				key, value, ok := strings.Cut(line, ":")
+				if !ok {
+					continue
+				}
+				value = strings.TrimSpace(value)
+				switch key {
+				case "pos":
+					f.Position, _ = strconv.ParseUint(value, 10, 64)
+				case "flags":
+					f.Flags, _ = strconv.ParseUint(value, 8, 64)
+				}
+			}
+		}
+		ret = append(ret, f)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Fd < ret[j].Fd })
+	return ret, nil
+}
@@ -0,0 +1,54 @@
+package cpuproc
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func Test_OpenFiles(t *testing.T) {
+	ctx := newTestContext(t, map[string]string{
+		"proc/42/fdinfo/0":  "pos:\t0\nflags:\t0100002\nmnt_id:\t25\n",
+		"proc/42/fdinfo/3":  "pos:\t4096\nflags:\t02100000\n",
+		"proc/42/fdinfo/10": "pos\t12\nflags:\tnot octal\n",
+	})
+	fd := HostProcWithContext(ctx, "42", "fd")
+	if err := os.MkdirAll(fd, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, target := range map[string]string{
+		"0":  "/dev/pts/0",
+		"3":  "/var/log/app.log",
+		"10": "socket:[1234]",
+		// fdinfo is gone, the fd was closed meanwhile
+		"11": "pipe:[5678]",
+	} {
+		if err := os.Symlink(target, fd+"/"+name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// not an fd
+	if err := os.WriteFile(fd+"/x", nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &proc{pid: 42}
+	n, err := p.NumFDsWithContext(ctx)
+	if err != nil || n != 5 {
+		t.Errorf("NumFDs = %d, %v, want 5", n, err)
+	}
+
+	got, err := p.OpenFilesWithContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []OpenFilesStat{
+		{Fd: 0, Path: "/dev/pts/0", Flags: 0o100002},
+		{Fd: 3, Path: "/var/log/app.log", Position: 4096, Flags: 0o2100000},
+		{Fd: 10, Path: "socket:[1234]"},
+		{Fd: 11, Path: "pipe:[5678]"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,132 @@
+package cpuproc
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// GroupCPUStat is the cpu usage of a process group or a session over an interval, 100
+// being one cpu. ID is the pgid or the sid, which is the pid of the group or session leader.
+type GroupCPUStat struct {
+	ID      int32   `json:"id"`
+	Pids    []int32 `json:"pids"`
+	Percent float64 `json:"percent"`
+}
+
+// Pgid returns the process group of the process.
+func (p *proc) Pgid() (int32, error) {
+	return p.PgidWithContext(context.Background())
+}
+
+func (p *proc) PgidWithContext(ctx context.Context) (int32, error) {
+	return p.statInt32(ctx, 5)
+}
+
+// Sid returns the session of the process.
+func (p *proc) Sid() (int32, error) {
+	return p.SidWithContext(context.Background())
+}
+
+func (p *proc) SidWithContext(ctx context.Context) (int32, error) {
+	return p.statInt32(ctx, 6)
+}
+
+func (p *proc) statInt32(ctx context.Context, field int) (int32, error) {
+	contents, err := ReadFile(p.procPath(ctx, "stat"))
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseInt(splitProcStat([]byte(contents))[field], 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return int32(v), nil
+}
+
+// ProcessGroupsCPU returns the cpu usage of every process group over interval, busiest first.
+func ProcessGroupsCPU(interval time.Duration) ([]GroupCPUStat, error) {
+	return ProcessGroupsCPUWithContext(context.Background(), interval)
+}
+
+func ProcessGroupsCPUWithContext(ctx context.Context, interval time.Duration) ([]GroupCPUStat, error) {
+	return groupsCPU(ctx, interval, func(s procSample) int32 { return s.pgrp })
+}
+
+// SessionsCPU returns the cpu usage of every session over interval, busiest first.
+func SessionsCPU(interval time.Duration) ([]GroupCPUStat, error) {
+	return SessionsCPUWithContext(context.Background(), interval)
+}
+
+func SessionsCPUWithContext(ctx context.Context, interval time.Duration) ([]GroupCPUStat, error) {
+	return groupsCPU(ctx, interval, func(s procSample) int32 { return s.session })
+}
+
+// ProcessGroupCPU returns the cpu usage of the process group pgid over interval.
+func ProcessGroupCPU(pgid int32, interval time.Duration) (*GroupCPUStat, error) {
+	return ProcessGroupCPUWithContext(context.Background(), pgid, interval)
+}
+
+func ProcessGroupCPUWithContext(ctx context.Context, pgid int32, interval time.Duration) (*GroupCPUStat, error) {
+	return groupCPU(ctx, pgid, interval, func(s procSample) int32 { return s.pgrp })
+}
+
+// SessionCPU returns the cpu usage of the session sid over interval.
+func SessionCPU(sid int32, interval time.Duration) (*GroupCPUStat, error) {
+	return SessionCPUWithContext(context.Background(), sid, interval)
+}
+
+func SessionCPUWithContext(ctx context.Context, sid int32, interval time.Duration) (*GroupCPUStat, error) {
+	return groupCPU(ctx, sid, interval, func(s procSample) int32 { return s.session })
+}
+
+func groupCPU(ctx context.Context, id int32, interval time.Duration, key func(procSample) int32) (*GroupCPUStat, error) {
+	groups, err := groupsCPU(ctx, interval, key)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		if g.ID == id {
+			return &g, nil
+		}
+	}
+	// the group has no process left that ran the whole interval
+	return &GroupCPUStat{ID: id}, nil
+}
+
+func groupsCPU(ctx context.Context, interval time.Duration, key func(procSample) int32) ([]GroupCPUStat, error) {
+	samples, err := samplePercents(ctx, interval)
+	if err != nil {
+		return nil, err
+	}
+	return groupSamples(samples, key), nil
+}
+
+// groupSamples sums samples by key, busiest group first.
+func groupSamples(samples map[int32]procSample, key func(procSample) int32) []GroupCPUStat {
+	byID := make(map[int32]*GroupCPUStat)
+	for pid, s := range samples {
+		id := key(s)
+		g, ok := byID[id]
+		if !ok {
+			g = &GroupCPUStat{ID: id}
+			byID[id] = g
+		}
+		g.Pids = append(g.Pids, pid)
+		g.Percent += s.percent
+	}
+
+	ret := make([]GroupCPUStat, 0, len(byID))
+	for _, g := range byID {
+		sort.Slice(g.Pids, func(i, j int) bool { return g.Pids[i] < g.Pids[j] })
+		ret = append(ret, *g)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Percent != ret[j].Percent {
+			return ret[i].Percent > ret[j].Percent
+		}
+		return ret[i].ID < ret[j].ID
+	})
+	return ret
+}
@@ -0,0 +1,120 @@
+package cpuproc
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// Name returns the command name of the process from /proc/<pid>/comm, truncated
+// by the kernel to 15 bytes.
+func (p *proc) Name() (string, error) {
+	return p.NameWithContext(context.Background())
+}
+
+func (p *proc) NameWithContext(ctx context.Context) (string, error) {
+	return readTrimmed(p.procPath(ctx, "comm"))
+}
+
+// Cmdline returns the command line of the process with the arguments joined by spaces.
+// It is empty for kernel threads and zombies.
+func (p *proc) Cmdline() (string, error) {
+	return p.CmdlineWithContext(context.Background())
+}
+
+func (p *proc) CmdlineWithContext(ctx context.Context) (string, error) {
+	args, err := p.CmdlineSliceWithContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(args, " "), nil
+}
+
+// CmdlineSlice returns the arguments of the process from /proc/<pid>/cmdline.
+func (p *proc) CmdlineSlice() ([]string, error) {
+	return p.CmdlineSliceWithContext(context.Background())
+}
+
+func (p *proc) CmdlineSliceWithContext(ctx context.Context) ([]string, error) {
+	content, err := os.ReadFile(p.procPath(ctx, "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) == 0 {
+		return []string{}, nil
+	}
+	// arguments are NUL terminated
+	return strings.Split(strings.TrimSuffix(string(content), "\x00"), "\x00"), nil
+}
+
+// Exe returns the path of the executable of the process, reading it needs ptrace access.
+func (p *proc) Exe() (string, error) {
+	return p.ExeWithContext(context.Background())
+}
+
+func (p *proc) ExeWithContext(ctx context.Context) (string, error) {
+	exe, err := os.Readlink(p.procPath(ctx, "exe"))
+	if err != nil {
+		return "", err
+	}
+	// the binary was replaced or removed since the process started
+	return strings.TrimSuffix(exe, " (deleted)"), nil
+}
+
+// Environ returns the environment of the process as "KEY=value" strings, from
+// /proc/<pid>/environ. It is the environment the process started with, later changes
+// made by the process itself are not visible. Reading it needs ptrace access.
+func (p *proc) Environ() ([]string, error) {
+	return p.EnvironWithContext(context.Background())
+}
+
+func (p *proc) EnvironWithContext(ctx context.Context) ([]string, error) {
+	content, err := os.ReadFile(p.procPath(ctx, "environ"))
+	if err != nil {
+		return nil, err
+	}
+	ret := []string{}
+	for _, kv := range strings.Split(string(content), "\x00") {
+		if kv != "" {
+			ret = append(ret, kv)
+		}
+	}
+	return ret, nil
+}
+
+// EnvironMap is Environ keyed by variable name.
+func (p *proc) EnvironMap() (map[string]string, error) {
+	return p.EnvironMapWithContext(context.Background())
+}
+
+func (p *proc) EnvironMapWithContext(ctx context.Context) (map[string]string, error) {
+	env, err := p.EnvironWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]string, len(env))
+	for _, kv := range env {
+		k, v, _ := strings.Cut(kv, "=")
+		ret[k] = v
+	}
+	return ret, nil
+}
+
+// Cwd returns the current working directory of the process.
+func (p *proc) Cwd() (string, error) {
+	return p.CwdWithContext(context.Background())
+}
+
+func (p *proc) CwdWithContext(ctx context.Context) (string, error) {
+	return os.Readlink(p.procPath(ctx, "cwd"))
+}
+
+// Root returns the root directory of the process, "/" unless it is chrooted.
+// The path is relative to the root of the caller, e.g. the root of a container.
+func (p *proc) Root() (string, error) {
+	return p.RootWithContext(context.Background())
+}
+
+func (p *proc) RootWithContext(ctx context.Context) (string, error) {
+	return os.Readlink(p.procPath(ctx, "root"))
+}
@@ -0,0 +1,82 @@
+package cpuproc
+
+import (
+	"context"
+	"time"
+)
+
+// ProcIOCountersStat contains the I/O of a process since it started, from /proc/<pid>/io.
+// ReadChars and WriteChars count the bytes passed to read and write like syscalls, cached
+// or not, ReadBytes and WriteBytes the bytes actually fetched from or sent to the storage.
+// Reading the io file of another user's process needs ptrace access.
+type ProcIOCountersStat struct {
+	ReadChars           uint64 `json:"readChars"`
+	WriteChars          uint64 `json:"writeChars"`
+	ReadCount           uint64 `json:"readCount"`
+	WriteCount          uint64 `json:"writeCount"`
+	ReadBytes           uint64 `json:"readBytes"`
+	WriteBytes          uint64 `json:"writeBytes"`
+	CancelledWriteBytes uint64 `json:"cancelledWriteBytes"`
+}
+
+// ProcIORateStat contains the I/O of a process per second over an interval.
+type ProcIORateStat struct {
+	ReadChars  float64 `json:"readChars"`
+	WriteChars float64 `json:"writeChars"`
+	ReadCount  float64 `json:"readCount"`
+	WriteCount float64 `json:"writeCount"`
+	ReadBytes  float64 `json:"readBytes"`
+	WriteBytes float64 `json:"writeBytes"`
+}
+
+func (p *proc) IOCounters() (*ProcIOCountersStat, error) {
+	return p.IOCountersWithContext(context.Background())
+}
+
+func (p *proc) IOCountersWithContext(ctx context.Context) (*ProcIOCountersStat, error) {
+	values, err := readKBValues(p.procPath(ctx, "io"))
+	if err != nil {
+		return nil, err
+	}
+	return &ProcIOCountersStat{
+		ReadChars:           values["rchar"],
+		WriteChars:          values["wchar"],
+		ReadCount:           values["syscr"],
+		WriteCount:          values["syscw"],
+		ReadBytes:           values["read_bytes"],
+		WriteBytes:          values["write_bytes"],
+		CancelledWriteBytes: values["cancelled_write_bytes"],
+	}, nil
+}
+
+func (p *proc) IORate(interval time.Duration) (*ProcIORateStat, error) {
+	return p.IORateWithContext(context.Background(), interval)
+}
+
+// IORateWithContext samples the I/O counters of the process twice, interval apart.
+func (p *proc) IORateWithContext(ctx context.Context, interval time.Duration) (*ProcIORateStat, error) {
+	s1, err := p.IOCountersWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	if err := Sleep(ctx, interval); err != nil {
+		return nil, err
+	}
+
+	s2, err := p.IOCountersWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start).Seconds()
+
+	return &ProcIORateStat{
+		ReadChars:  counterRate(s1.ReadChars, s2.ReadChars, elapsed),
+		WriteChars: counterRate(s1.WriteChars, s2.WriteChars, elapsed),
+		ReadCount:  counterRate(s1.ReadCount, s2.ReadCount, elapsed),
+		WriteCount: counterRate(s1.WriteCount, s2.WriteCount, elapsed),
+		ReadBytes:  counterRate(s1.ReadBytes, s2.ReadBytes, elapsed),
+		WriteBytes: counterRate(s1.WriteBytes, s2.WriteBytes, elapsed),
+	}, nil
+}
@@ -0,0 +1,41 @@
+package cpuproc
+
+import "testing"
+
+func Test_IOCounters(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		io   string
+		want ProcIOCountersStat
+	}{
+		{
+			name: "full",
+			io: "rchar: 4096\nwchar: 2048\nsyscr: 12\nsyscw: 6\nread_bytes: 8192\n" +
+				"write_bytes: 1024\ncancelled_write_bytes: 512\n",
+			want: ProcIOCountersStat{ReadChars: 4096, WriteChars: 2048, ReadCount: 12, WriteCount: 6,
+				ReadBytes: 8192, WriteBytes: 1024, CancelledWriteBytes: 512},
+		},
+		{
+			// without CONFIG_TASK_IO_ACCOUNTING only the char counters are there
+			name: "no task io accounting",
+			io:   "rchar: 10\nwchar: 20\nsyscr: 1\nsyscw: 2\n",
+			want: ProcIOCountersStat{ReadChars: 10, WriteChars: 20, ReadCount: 1, WriteCount: 2},
+		},
+		{
+			name: "malformed",
+			io:   "rchar 10\nwchar:\nsyscr: -1\nsyscw: x\nread_bytes: 7\n",
+			want: ProcIOCountersStat{ReadBytes: 7},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := newTestContext(t, map[string]string{"proc/42/io": tc.io})
+			got, err := (&proc{pid: 42}).IOCountersWithContext(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tc.want {
+				t.Errorf("got %+v, want %+v", *got, tc.want)
+			}
+		})
+	}
+}
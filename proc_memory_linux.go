@@ -0,0 +1,131 @@
+package cpuproc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// MemoryInfoStat contains the memory of a process in bytes, from /proc/<pid>/statm.
+// Shared is the resident memory backed by files, Text the code and Data the data
+// and stack segments.
+type MemoryInfoStat struct {
+	RSS    uint64 `json:"rss"`
+	VMS    uint64 `json:"vms"`
+	Shared uint64 `json:"shared"`
+	Text   uint64 `json:"text"`
+	Data   uint64 `json:"data"`
+}
+
+func (p *proc) MemoryInfo() (*MemoryInfoStat, error) {
+	return p.MemoryInfoWithContext(context.Background())
+}
+
+func (p *proc) MemoryInfoWithContext(ctx context.Context) (*MemoryInfoStat, error) {
+	line, err := readTrimmed(p.procPath(ctx, "statm"))
+	if err != nil {
+		return nil, err
+	}
+	// size resident shared text lib data dt, in pages
+	fields := strings.Fields(line)
+	if len(fields) < 6 {
+		return nil, fmt.Errorf("wrong statm format: %q", line)
+	}
+	var pages [6]uint64
+	for i := range pages {
+		if pages[i], err = strconv.ParseUint(fields[i], 10, 64); err != nil {
+			return nil, err
+		}
+	}
+
+	pageSize := uint64(os.Getpagesize())
+	return &MemoryInfoStat{
+		VMS:    pages[0] * pageSize,
+		RSS:    pages[1] * pageSize,
+		Shared: pages[2] * pageSize,
+		Text:   pages[3] * pageSize,
+		Data:   pages[5] * pageSize,
+	}, nil
+}
+
+// MemoryInfoExStat contains the proportional memory of a process in bytes, from
+// /proc/<pid>/smaps_rollup. PSS shares the pages mapped by several processes between
+// them and USS only counts the pages private to the process, which is what it frees on
+// exit. Estimated is set on kernels older than 4.14, PSS and USS are then derived from
+// statm: PSS is the RSS and USS the resident memory not backed by files.
+type MemoryInfoExStat struct {
+	RSS         uint64 `json:"rss"`
+	PSS         uint64 `json:"pss"`
+	USS         uint64 `json:"uss"`
+	Swap        uint64 `json:"swap"`
+	SharedDirty uint64 `json:"sharedDirty"`
+	Estimated   bool   `json:"estimated"`
+}
+
+func (p *proc) MemoryInfoEx() (*MemoryInfoExStat, error) {
+	return p.MemoryInfoExWithContext(context.Background())
+}
+
+func (p *proc) MemoryInfoExWithContext(ctx context.Context) (*MemoryInfoExStat, error) {
+	values, err := readKBValues(p.procPath(ctx, "smaps_rollup"))
+	if os.IsNotExist(err) {
+		mem, err := p.MemoryInfoWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &MemoryInfoExStat{RSS: mem.RSS, PSS: mem.RSS, USS: mem.RSS - mem.Shared, Estimated: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &MemoryInfoExStat{
+		RSS:         values["Rss"],
+		PSS:         values["Pss"],
+		USS:         values["Private_Clean"] + values["Private_Dirty"],
+		Swap:        values["Swap"],
+		SharedDirty: values["Shared_Dirty"],
+	}, nil
+}
+
+// MemoryPercent returns the resident memory of the process as a percent of the memory of
+// the machine, or of the memory limit of its cgroup with NormalizeEffective, like
+// CPUPercent does with the cpus.
+func (p *proc) MemoryPercent() (float64, error) {
+	return p.MemoryPercentWithContext(context.Background())
+}
+
+func (p *proc) MemoryPercentWithContext(ctx context.Context) (float64, error) {
+	mem, err := p.MemoryInfoWithContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	total, err := p.memoryTotal(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if total == 0 {
+		return 0, nil
+	}
+	return float64(mem.RSS) / float64(total) * 100, nil
+}
+
+// memoryTotal returns the memory MemoryPercent is relative to.
+func (p *proc) memoryTotal(ctx context.Context) (uint64, error) {
+	sys, err := MemoryWithContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if p.opts.normalization != NormalizeEffective {
+		return sys.Total, nil
+	}
+	c, err := pidCgroup(ctx, strconv.Itoa(int(p.pid)))
+	if err != nil {
+		return sys.Total, nil
+	}
+	if m, err := c.MemoryWithContext(ctx); err == nil && m.Limit > 0 && m.Limit < sys.Total {
+		return m.Limit, nil
+	}
+	return sys.Total, nil
+}
@@ -0,0 +1,85 @@
+package cpuproc
+
+import (
+	"os"
+	"testing"
+)
+
+func Test_MemoryInfo(t *testing.T) {
+	page := uint64(os.Getpagesize())
+	for _, tc := range []struct {
+		name  string
+		statm string
+		want  MemoryInfoStat
+		err   bool
+	}{
+		{"ok", "1000 200 50 10 0 300 0\n", MemoryInfoStat{VMS: 1000 * page, RSS: 200 * page, Shared: 50 * page, Text: 10 * page, Data: 300 * page}, false},
+		{"short", "1000 200 50\n", MemoryInfoStat{}, true},
+		{"not a number", "1000 x 50 10 0 300 0\n", MemoryInfoStat{}, true},
+		{"empty", "", MemoryInfoStat{}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := newTestContext(t, map[string]string{"proc/42/statm": tc.statm})
+			got, err := (&proc{pid: 42}).MemoryInfoWithContext(ctx)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tc.want {
+				t.Errorf("got %+v, want %+v", *got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_MemoryInfoEx(t *testing.T) {
+	page := uint64(os.Getpagesize())
+	for _, tc := range []struct {
+		name  string
+		files map[string]string
+		want  MemoryInfoExStat
+	}{
+		{
+			name: "smaps_rollup",
+			files: map[string]string{
+				"proc/42/smaps_rollup": "00400000-7ffd [rollup]\nRss:     1000 kB\nPss:      600 kB\n" +
+					"Shared_Dirty:     8 kB\nPrivate_Clean:   100 kB\nPrivate_Dirty:   300 kB\nSwap:      16 kB\n",
+				"proc/42/statm": "1 1 1 1 0 1 0\n",
+			},
+			want: MemoryInfoExStat{RSS: 1000 << 10, PSS: 600 << 10, USS: 400 << 10, Swap: 16 << 10, SharedDirty: 8 << 10},
+		},
+		{
+			name: "malformed smaps_rollup",
+			files: map[string]string{
+				"proc/42/smaps_rollup": "Rss:\nPss: lots kB\nPrivate_Dirty:   300 kB\n",
+			},
+			want: MemoryInfoExStat{USS: 300 << 10},
+		},
+		{
+			// kernels older than 4.14
+			name:  "statm fallback",
+			files: map[string]string{"proc/42/statm": "1000 200 50 10 0 300 0\n"},
+			want:  MemoryInfoExStat{RSS: 200 * page, PSS: 200 * page, USS: 150 * page, Estimated: true},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := newTestContext(t, tc.files)
+			got, err := (&proc{pid: 42}).MemoryInfoExWithContext(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tc.want {
+				t.Errorf("got %+v, want %+v", *got, tc.want)
+			}
+		})
+	}
+
+	if _, err := (&proc{pid: 42}).MemoryInfoExWithContext(newTestContext(t, nil)); err == nil {
+		t.Error("expected an error without smaps_rollup nor statm")
+	}
+}
@@ -0,0 +1,31 @@
+package cpuproc
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// Nice returns the nice value of the process, from -20 (highest priority) to 19.
+func (p *proc) Nice() (int32, error) {
+	return p.NiceWithContext(context.Background())
+}
+
+func (p *proc) NiceWithContext(ctx context.Context) (int32, error) {
+	prio, err := unix.Getpriority(prioProcess, int(p.pid))
+	if err != nil {
+		return 0, err
+	}
+	// the raw syscall returns 20 - nice to stay positive, the libc wrapper undoes it
+	return int32(20 - prio), nil
+}
+
+// SetNice changes the nice value of the process, lowering it needs CAP_SYS_NICE or RLIMIT_NICE.
+// Only the main thread is changed on linux, threads created afterwards inherit it.
+func (p *proc) SetNice(nice int) error {
+	if nice < -20 || nice > 19 {
+		return fmt.Errorf("nice %d out of range [-20, 19]", nice)
+	}
+	return unix.Setpriority(prioProcess, int(p.pid), nice)
+}
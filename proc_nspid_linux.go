@@ -0,0 +1,73 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// ErrPidNotFound is returned by HostPid when no process has the pid in the namespace.
+var ErrPidNotFound = errors.New("pid not found in the pid namespace")
+
+// NSPids returns the pid of the process in each pid namespace it belongs to, from the
+// namespace of the /proc it is read from to its own, so the last one is the pid the process
+// sees for itself. Kernels before 4.1 have no NSpid line and only the first pid is returned.
+func (p *proc) NSPids() ([]int32, error) {
+	return p.NSPidsWithContext(context.Background())
+}
+
+func (p *proc) NSPidsWithContext(ctx context.Context) ([]int32, error) {
+	st, err := p.StatusWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(st.NSpid) == 0 {
+		return []int32{p.pid}, nil
+	}
+	return st.NSpid, nil
+}
+
+// HostPid maps pid, as seen in the pid namespace of the current process, to the pid of the
+// same process in the /proc of HOST_PROC, e.g. the host /proc mounted in a sidecar.
+func HostPid(pid int32) (int32, error) {
+	return HostPidWithContext(context.Background(), pid)
+}
+
+// HostPidWithContext scans HOST_PROC for the process of the current pid namespace whose
+// innermost NSpid is pid. Reading the namespace of other processes needs ptrace access,
+// usually root or CAP_SYS_PTRACE.
+func HostPidWithContext(ctx context.Context, pid int32) (int32, error) {
+	var self unix.Stat_t
+	if err := unix.Stat("/proc/self/ns/pid", &self); err != nil {
+		return 0, err
+	}
+
+	entries, err := os.ReadDir(HostProcWithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range entries {
+		hostPid, err := strconv.ParseInt(e.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+		var ns unix.Stat_t
+		if err := unix.Stat(HostProcWithContext(ctx, e.Name(), "ns", "pid"), &ns); err != nil {
+			continue
+		}
+		if ns.Ino != self.Ino || ns.Dev != self.Dev {
+			continue
+		}
+		nspids, err := NewProcess(int32(hostPid)).NSPidsWithContext(ctx)
+		if err != nil || len(nspids) == 0 {
+			continue
+		}
+		if nspids[len(nspids)-1] == pid {
+			return int32(hostPid), nil
+		}
+	}
+	return 0, ErrPidNotFound
+}
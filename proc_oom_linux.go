@@ -0,0 +1,40 @@
+package cpuproc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// OOMScore returns the badness of the process from 0 to 1000 (2000 on older kernels with a
+// positive adjustment), the OOM killer picks the process with the highest score.
+func (p *proc) OOMScore() (int, error) {
+	return p.OOMScoreWithContext(context.Background())
+}
+
+func (p *proc) OOMScoreWithContext(ctx context.Context) (int, error) {
+	return readInt(p.procPath(ctx, "oom_score"))
+}
+
+// OOMScoreAdj returns the adjustment of the OOM score, from -1000 to 1000.
+func (p *proc) OOMScoreAdj() (int, error) {
+	return p.OOMScoreAdjWithContext(context.Background())
+}
+
+func (p *proc) OOMScoreAdjWithContext(ctx context.Context) (int, error) {
+	return readInt(p.procPath(ctx, "oom_score_adj"))
+}
+
+// SetOOMScoreAdj sets the adjustment of the OOM score, -1000 disables the OOM killer for the
+// process and 1000 makes it the first victim. Lowering it below its previous minimum needs
+// CAP_SYS_RESOURCE.
+func (p *proc) SetOOMScoreAdj(v int) error {
+	return p.SetOOMScoreAdjWithContext(context.Background(), v)
+}
+
+func (p *proc) SetOOMScoreAdjWithContext(ctx context.Context, v int) error {
+	if v < -1000 || v > 1000 {
+		return fmt.Errorf("oom_score_adj %d out of range [-1000, 1000]", v)
+	}
+	return writeExistingFile(p.procPath(ctx, "oom_score_adj"), strconv.Itoa(v))
+}
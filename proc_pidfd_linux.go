@@ -0,0 +1,63 @@
+package cpuproc
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// NewProcessFD is NewProcess holding a pidfd (linux 5.3) of the process, which keeps
+// referring to it after it exits: IsRunning, SendSignal and Wait then go through the pidfd
+// and can never reach another process that reuses the pid, which suits long lived monitors.
+// On older kernels it falls back to NewProcess and the start time check. The pidfd is
+// released by Close.
+func NewProcessFD(pid int32, opts ...ProcessOption) (*proc, error) {
+	fd, err := unix.PidfdOpen(int(pid), 0)
+	if err != nil {
+		if errors.Is(err, unix.ESRCH) {
+			return nil, err
+		}
+		p := NewProcess(pid, opts...)
+		if p == nil {
+			return nil, errors.New("could not get the cpu affinity")
+		}
+		return p, nil
+	}
+	pidfd := os.NewFile(uintptr(fd), "pidfd")
+
+	p := NewProcess(pid, opts...)
+	if p == nil {
+		pidfd.Close()
+		return nil, errors.New("could not get the cpu affinity")
+	}
+	p.pidfd = pidfd
+	// the pid may have been recycled before the pidfd was opened, the stat file of the
+	// exited process would then have been missing
+	if p.startTime == 0 || p.pidfdExited() {
+		pidfd.Close()
+		return nil, unix.ESRCH
+	}
+	return p, nil
+}
+
+// Close releases the pidfd of a process created by NewProcessFD, it does nothing otherwise.
+func (p *proc) Close() error {
+	if p.pidfd == nil {
+		return nil
+	}
+	return p.pidfd.Close()
+}
+
+// pidfdExited tells whether the process of the pidfd exited, the pidfd becomes readable
+// once it has.
+func (p *proc) pidfdExited() bool {
+	fds := []unix.PollFd{{Fd: int32(p.pidfd.Fd()), Events: unix.POLLIN}}
+	for {
+		n, err := unix.Poll(fds, 0)
+		if err == unix.EINTR {
+			continue
+		}
+		return err == nil && n > 0
+	}
+}
@@ -0,0 +1,150 @@
+package cpuproc
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// RLimInfinity is the Soft or Hard value of an unlimited resource.
+const RLimInfinity = math.MaxUint64
+
+// RlimitStat is a resource limit of a process, Resource is one of the unix.RLIMIT_* constants.
+// Used is only filled by RlimitUsage, for the resources whose usage is known per process.
+type RlimitStat struct {
+	Resource int32  `json:"resource"`
+	Soft     uint64 `json:"soft"`
+	Hard     uint64 `json:"hard"`
+	Used     uint64 `json:"used"`
+}
+
+// the names of the resources in /proc/<pid>/limits
+var rlimitNames = []struct {
+	name     string
+	resource int32
+}{
+	{"Max cpu time", unix.RLIMIT_CPU},
+	{"Max file size", unix.RLIMIT_FSIZE},
+	{"Max data size", unix.RLIMIT_DATA},
+	{"Max stack size", unix.RLIMIT_STACK},
+	{"Max core file size", unix.RLIMIT_CORE},
+	{"Max resident set", unix.RLIMIT_RSS},
+	{"Max processes", unix.RLIMIT_NPROC},
+	{"Max open files", unix.RLIMIT_NOFILE},
+	{"Max locked memory", unix.RLIMIT_MEMLOCK},
+	{"Max address space", unix.RLIMIT_AS},
+	{"Max file locks", unix.RLIMIT_LOCKS},
+	{"Max pending signals", unix.RLIMIT_SIGPENDING},
+	{"Max msgqueue size", unix.RLIMIT_MSGQUEUE},
+	{"Max nice priority", unix.RLIMIT_NICE},
+	{"Max realtime priority", unix.RLIMIT_RTPRIO},
+	{"Max realtime timeout", unix.RLIMIT_RTTIME},
+}
+
+func (p *proc) Rlimit() ([]RlimitStat, error) {
+	return p.RlimitWithContext(context.Background())
+}
+
+// RlimitWithContext returns the resource limits of /proc/<pid>/limits.
+func (p *proc) RlimitWithContext(ctx context.Context) ([]RlimitStat, error) {
+	lines, err := ReadLines(p.procPath(ctx, "limits"))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]RlimitStat, 0, len(rlimitNames))
+	for _, line := range lines {
+		for _, r := range rlimitNames {
+			rest, ok := strings.CutPrefix(line, r.name)
+			if !ok {
+				continue
+			}
+			fields := strings.Fields(rest)
+			if len(fields) < 2 {
+				break
+			}
+			ret = append(ret, RlimitStat{
+				Resource: r.resource,
+				Soft:     parseRlimit(fields[0]),
+				Hard:     parseRlimit(fields[1]),
+			})
+			break
+		}
+	}
+	return ret, nil
+}
+
+func (p *proc) RlimitUsage() ([]RlimitStat, error) {
+	return p.RlimitUsageWithContext(context.Background())
+}
+
+// RlimitUsageWithContext is Rlimit with the current usage of the cpu time (seconds), open
+// files, memory sizes and pending signals, to tell how close the process is to its limits.
+func (p *proc) RlimitUsageWithContext(ctx context.Context) ([]RlimitStat, error) {
+	limits, err := p.RlimitWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status, _ := readKBValues(p.procPath(ctx, "status"))
+
+	for i := range limits {
+		l := &limits[i]
+		switch l.Resource {
+		case unix.RLIMIT_CPU:
+			if t, err := p.TimesWithContext(ctx); err == nil {
+				l.Used = uint64(t.User + t.System)
+			}
+		case unix.RLIMIT_NOFILE:
+			if n, err := p.NumFDsWithContext(ctx); err == nil {
+				l.Used = uint64(n)
+			}
+		case unix.RLIMIT_AS:
+			l.Used = status["VmSize"]
+		case unix.RLIMIT_RSS:
+			l.Used = status["VmRSS"]
+		case unix.RLIMIT_DATA:
+			l.Used = status["VmData"]
+		case unix.RLIMIT_STACK:
+			l.Used = status["VmStk"]
+		case unix.RLIMIT_MEMLOCK:
+			l.Used = status["VmLck"]
+		case unix.RLIMIT_SIGPENDING:
+			l.Used = p.queuedSignals(ctx)
+		}
+	}
+	return limits, nil
+}
+
+// SetRlimit changes a resource limit of the process with prlimit(2), raising a hard
+// limit needs CAP_SYS_RESOURCE.
+func (p *proc) SetRlimit(resource int32, soft, hard uint64) error {
+	return unix.Prlimit(int(p.pid), int(resource), &unix.Rlimit{Cur: soft, Max: hard}, nil)
+}
+
+// queuedSignals returns the signals queued for the real user of the process, from
+// "SigQ: queued/limit" in /proc/<pid>/status.
+func (p *proc) queuedSignals(ctx context.Context) uint64 {
+	lines, err := ReadLines(p.procPath(ctx, "status"))
+	if err != nil {
+		return 0
+	}
+	for _, line := range lines {
+		if value, ok := strings.CutPrefix(line, "SigQ:"); ok {
+			queued, _, _ := strings.Cut(strings.TrimSpace(value), "/")
+			v, _ := strconv.ParseUint(queued, 10, 64)
+			return v
+		}
+	}
+	return 0
+}
+
+func parseRlimit(s string) uint64 {
+	if s == "unlimited" {
+		return RLimInfinity
+	}
+	v, _ := strconv.ParseUint(s, 10, 64)
+	return v
+}
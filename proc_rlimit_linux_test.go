@@ -0,0 +1,49 @@
+package cpuproc
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func Test_Rlimit(t *testing.T) {
+	header := "Limit                     Soft Limit           Hard Limit           Units     \n"
+	for _, tc := range []struct {
+		name   string
+		limits string
+		want   []RlimitStat
+	}{
+		{
+			name: "ok",
+			limits: header +
+				"Max cpu time              unlimited            unlimited            seconds   \n" +
+				"Max open files            1024                 524288               files     \n" +
+				"Max nice priority         0                    0                    \n",
+			want: []RlimitStat{
+				{Resource: unix.RLIMIT_CPU, Soft: RLimInfinity, Hard: RLimInfinity},
+				{Resource: unix.RLIMIT_NOFILE, Soft: 1024, Hard: 524288},
+				{Resource: unix.RLIMIT_NICE},
+			},
+		},
+		{
+			name: "malformed",
+			limits: header +
+				"Max open files            1024\n" +
+				"Max unknown thing         1                    2                    \n" +
+				"Max processes             lots                 63432                processes \n",
+			want: []RlimitStat{{Resource: unix.RLIMIT_NPROC, Hard: 63432}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := newTestContext(t, map[string]string{"proc/42/limits": tc.limits})
+			got, err := (&proc{pid: 42}).RlimitWithContext(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
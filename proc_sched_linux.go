@@ -0,0 +1,111 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// SchedPolicy is a linux scheduling policy.
+type SchedPolicy uint32
+
+const (
+	SchedOther    SchedPolicy = unix.SCHED_NORMAL
+	SchedFIFO     SchedPolicy = unix.SCHED_FIFO
+	SchedRR       SchedPolicy = unix.SCHED_RR
+	SchedBatch    SchedPolicy = unix.SCHED_BATCH
+	SchedIdle     SchedPolicy = unix.SCHED_IDLE
+	SchedDeadline SchedPolicy = unix.SCHED_DEADLINE
+)
+
+func (s SchedPolicy) String() string {
+	switch s {
+	case SchedOther:
+		return "SCHED_OTHER"
+	case SchedFIFO:
+		return "SCHED_FIFO"
+	case SchedRR:
+		return "SCHED_RR"
+	case SchedBatch:
+		return "SCHED_BATCH"
+	case SchedIdle:
+		return "SCHED_IDLE"
+	case SchedDeadline:
+		return "SCHED_DEADLINE"
+	}
+	return "SchedPolicy(" + strconv.Itoa(int(s)) + ")"
+}
+
+// SchedPolicyStat is the scheduling of a process. Priority is the real-time priority of
+// SCHED_FIFO and SCHED_RR, from 1 to 99, Nice is used by SCHED_OTHER and SCHED_BATCH.
+// Runtime, Deadline and Period are the reservation of SCHED_DEADLINE.
+type SchedPolicyStat struct {
+	Policy   SchedPolicy   `json:"policy"`
+	Priority uint32        `json:"priority"`
+	Nice     int32         `json:"nice"`
+	Runtime  time.Duration `json:"runtime"`
+	Deadline time.Duration `json:"deadline"`
+	Period   time.Duration `json:"period"`
+}
+
+// SchedPolicy returns the scheduling policy of the main thread of the process.
+func (p *proc) SchedPolicy() (*SchedPolicyStat, error) {
+	return p.SchedPolicyWithContext(context.Background())
+}
+
+func (p *proc) SchedPolicyWithContext(ctx context.Context) (*SchedPolicyStat, error) {
+	attr, err := unix.SchedGetAttr(int(p.pid), 0)
+	if err != nil {
+		return nil, err
+	}
+	return &SchedPolicyStat{
+		// the reset-on-fork flag may be or'ed into the policy
+		Policy:   SchedPolicy(attr.Policy &^ unix.SCHED_RESET_ON_FORK),
+		Priority: attr.Priority,
+		Nice:     attr.Nice,
+		Runtime:  time.Duration(attr.Runtime),
+		Deadline: time.Duration(attr.Deadline),
+		Period:   time.Duration(attr.Period),
+	}, nil
+}
+
+// SetSchedPolicy changes the scheduling policy of the main thread of the process, priority
+// is the real-time priority of SCHED_FIFO and SCHED_RR and must be 0 for the other policies.
+// The nice value is kept. Real-time policies need CAP_SYS_NICE or RLIMIT_RTPRIO.
+// SCHED_DEADLINE needs a reservation, see SetSchedDeadline.
+func (p *proc) SetSchedPolicy(policy SchedPolicy, priority int) error {
+	if policy == SchedDeadline {
+		return errors.New("SCHED_DEADLINE needs a reservation, use SetSchedDeadline")
+	}
+	if priority < 0 {
+		return errors.New("priority must not be negative")
+	}
+	cur, err := unix.SchedGetAttr(int(p.pid), 0)
+	if err != nil {
+		return err
+	}
+	return unix.SchedSetAttr(int(p.pid), &unix.SchedAttr{
+		Size:     unix.SizeofSchedAttr,
+		Policy:   uint32(policy),
+		Nice:     cur.Nice,
+		Priority: uint32(priority),
+	}, 0)
+}
+
+// SetSchedDeadline switches the main thread of the process to SCHED_DEADLINE, it is
+// guaranteed runtime every period, to be used within deadline of the period start.
+func (p *proc) SetSchedDeadline(runtime, deadline, period time.Duration) error {
+	if runtime <= 0 || runtime > deadline || (period != 0 && deadline > period) {
+		return errors.New("SCHED_DEADLINE needs 0 < runtime <= deadline <= period")
+	}
+	return unix.SchedSetAttr(int(p.pid), &unix.SchedAttr{
+		Size:     unix.SizeofSchedAttr,
+		Policy:   uint32(SchedDeadline),
+		Runtime:  uint64(runtime),
+		Deadline: uint64(deadline),
+		Period:   uint64(period),
+	}, 0)
+}
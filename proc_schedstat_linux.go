@@ -0,0 +1,140 @@
+package cpuproc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProcSchedstatStat contains the scheduler statistics of a process from /proc/<pid>/schedstat.
+// RunTime is the nanoseconds spent on a cpu, WaitTime the nanoseconds spent runnable on a
+// runqueue waiting for one and Timeslices the number of times it was scheduled. They are
+// those of the main thread, see ThreadsSchedstat for the other threads.
+type ProcSchedstatStat struct {
+	RunTime    uint64 `json:"runTime"`
+	WaitTime   uint64 `json:"waitTime"`
+	Timeslices uint64 `json:"timeslices"`
+}
+
+// ProcSchedstatRateStat contains the scheduling of a process over an interval. Run and Wait
+// are the seconds spent running and waiting on a runqueue per second, AvgWait is the mean
+// runqueue latency of a timeslice.
+type ProcSchedstatRateStat struct {
+	Run        float64       `json:"run"`
+	Wait       float64       `json:"wait"`
+	Timeslices float64       `json:"timeslices"`
+	AvgWait    time.Duration `json:"avgWait"`
+}
+
+func (p *proc) Schedstat() (*ProcSchedstatStat, error) {
+	return p.SchedstatWithContext(context.Background())
+}
+
+func (p *proc) SchedstatWithContext(ctx context.Context) (*ProcSchedstatStat, error) {
+	return readProcSchedstat(p.procPath(ctx, "schedstat"))
+}
+
+// ThreadsSchedstat returns the scheduler statistics of every thread of the process, by tid.
+func (p *proc) ThreadsSchedstat() (map[int32]*ProcSchedstatStat, error) {
+	return p.ThreadsSchedstatWithContext(context.Background())
+}
+
+func (p *proc) ThreadsSchedstatWithContext(ctx context.Context) (map[int32]*ProcSchedstatStat, error) {
+	entries, err := os.ReadDir(p.procPath(ctx, "task"))
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[int32]*ProcSchedstatStat, len(entries))
+	for _, e := range entries {
+		tid, err := strconv.ParseInt(e.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+		s, err := readProcSchedstat(p.procPath(ctx, "task", e.Name(), "schedstat"))
+		if err != nil {
+			// the thread exited
+			continue
+		}
+		ret[int32(tid)] = s
+	}
+	return ret, nil
+}
+
+// SchedstatTotal sums the scheduler statistics of all the threads of the process.
+func (p *proc) SchedstatTotal() (*ProcSchedstatStat, error) {
+	return p.SchedstatTotalWithContext(context.Background())
+}
+
+func (p *proc) SchedstatTotalWithContext(ctx context.Context) (*ProcSchedstatStat, error) {
+	threads, err := p.ThreadsSchedstatWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret := &ProcSchedstatStat{}
+	for _, s := range threads {
+		ret.RunTime += s.RunTime
+		ret.WaitTime += s.WaitTime
+		ret.Timeslices += s.Timeslices
+	}
+	return ret, nil
+}
+
+func (p *proc) SchedstatRate(interval time.Duration) (*ProcSchedstatRateStat, error) {
+	return p.SchedstatRateWithContext(context.Background(), interval)
+}
+
+// SchedstatRateWithContext samples the scheduler statistics of all the threads of the
+// process twice, interval apart. A Wait close to the number of runnable threads means
+// the process is starved of cpu.
+func (p *proc) SchedstatRateWithContext(ctx context.Context, interval time.Duration) (*ProcSchedstatRateStat, error) {
+	s1, err := p.SchedstatTotalWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	if err := Sleep(ctx, interval); err != nil {
+		return nil, err
+	}
+
+	s2, err := p.SchedstatTotalWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start).Seconds()
+
+	ret := &ProcSchedstatRateStat{
+		Run:        counterRate(s1.RunTime, s2.RunTime, elapsed) / 1e9,
+		Wait:       counterRate(s1.WaitTime, s2.WaitTime, elapsed) / 1e9,
+		Timeslices: counterRate(s1.Timeslices, s2.Timeslices, elapsed),
+	}
+	if s2.Timeslices > s1.Timeslices && s2.WaitTime >= s1.WaitTime {
+		ret.AvgWait = time.Duration((s2.WaitTime - s1.WaitTime) / (s2.Timeslices - s1.Timeslices))
+	}
+	return ret, nil
+}
+
+func readProcSchedstat(filename string) (*ProcSchedstatStat, error) {
+	lines, err := ReadLines(filename)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("empty %s", filename)
+	}
+	fields := strings.Fields(lines[0])
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("wrong schedstat format")
+	}
+
+	var v [3]uint64
+	for i := range v {
+		if v[i], err = strconv.ParseUint(fields[i], 10, 64); err != nil {
+			return nil, err
+		}
+	}
+	return &ProcSchedstatStat{RunTime: v[0], WaitTime: v[1], Timeslices: v[2]}, nil
+}
@@ -0,0 +1,51 @@
+package cpuproc
+
+import "testing"
+
+func Test_Schedstat(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		schedstat string
+		want      ProcSchedstatStat
+		err       bool
+	}{
+		{"ok", "2500000 1000000 30\n", ProcSchedstatStat{RunTime: 2500000, WaitTime: 1000000, Timeslices: 30}, false},
+		{"short", "2500000 1000000\n", ProcSchedstatStat{}, true},
+		{"not a number", "2500000 x 30\n", ProcSchedstatStat{}, true},
+		{"empty", "", ProcSchedstatStat{}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := newTestContext(t, map[string]string{"proc/42/schedstat": tc.schedstat})
+			got, err := (&proc{pid: 42}).SchedstatWithContext(ctx)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tc.want {
+				t.Errorf("got %+v, want %+v", *got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_SchedstatTotal(t *testing.T) {
+	ctx := newTestContext(t, map[string]string{
+		"proc/42/task/42/schedstat": "100 10 1\n",
+		"proc/42/task/43/schedstat": "200 20 2\n",
+		// exited or malformed threads are skipped
+		"proc/42/task/44/schedstat": "garbage\n",
+		"proc/42/task/self":         "",
+	})
+	got, err := (&proc{pid: 42}).SchedstatTotalWithContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (ProcSchedstatStat{RunTime: 300, WaitTime: 30, Timeslices: 3}); *got != want {
+		t.Errorf("got %+v, want %+v", *got, want)
+	}
+}
@@ -0,0 +1,66 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// Terminate sends SIGTERM to the process.
+func (p *proc) Terminate() error {
+	return p.SendSignalWithContext(context.Background(), unix.SIGTERM)
+}
+
+// Kill sends SIGKILL to the process.
+func (p *proc) Kill() error {
+	return p.SendSignalWithContext(context.Background(), unix.SIGKILL)
+}
+
+// Suspend stops the process with SIGSTOP.
+func (p *proc) Suspend() error {
+	return p.SendSignalWithContext(context.Background(), unix.SIGSTOP)
+}
+
+// Resume continues a stopped process with SIGCONT.
+func (p *proc) Resume() error {
+	return p.SendSignalWithContext(context.Background(), unix.SIGCONT)
+}
+
+func (p *proc) SendSignal(sig unix.Signal) error {
+	return p.SendSignalWithContext(context.Background(), sig)
+}
+
+// SendSignalWithContext sends sig through a pidfd (linux 5.1), so that it cannot reach
+// another process reusing the pid once the pidfd is open, and falls back to kill(2).
+// It returns unix.ESRCH when the process is gone.
+func (p *proc) SendSignalWithContext(ctx context.Context, sig unix.Signal) error {
+	if p.pidfd != nil {
+		return unix.PidfdSendSignal(int(p.pidfd.Fd()), sig, nil, 0)
+	}
+	fd, err := unix.PidfdOpen(int(p.pid), 0)
+	if err != nil {
+		if errors.Is(err, unix.ESRCH) {
+			return err
+		}
+		return p.kill(ctx, sig)
+	}
+	defer unix.Close(fd)
+
+	// the pid may have been recycled before the pidfd was opened
+	if running, err := p.IsRunningWithContext(ctx); err == nil && !running {
+		return unix.ESRCH
+	}
+	err = unix.PidfdSendSignal(fd, sig, nil, 0)
+	if errors.Is(err, unix.ENOSYS) {
+		return p.kill(ctx, sig)
+	}
+	return err
+}
+
+func (p *proc) kill(ctx context.Context, sig unix.Signal) error {
+	if running, err := p.IsRunningWithContext(ctx); err == nil && !running {
+		return unix.ESRCH
+	}
+	return unix.Kill(int(p.pid), sig)
+}
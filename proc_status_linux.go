@@ -0,0 +1,194 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// ProcStatusStat contains the fields of /proc/<pid>/status that go along the cpu times.
+// State is the letter of the scheduler state, e.g. "R" or "S". Memory is in bytes.
+// Uids and Gids are the real, effective, saved set and filesystem ids. NSpid is the pid
+// in each nested pid namespace, outermost first. The signal masks are bitmaps where
+// bit n-1 stands for signal n.
+type ProcStatusStat struct {
+	Name                     string  `json:"name"`
+	State                    string  `json:"state"`
+	PPid                     int32   `json:"ppid"`
+	Threads                  int32   `json:"threads"`
+	VoluntaryCtxtSwitches    uint64  `json:"voluntaryCtxtSwitches"`
+	NonvoluntaryCtxtSwitches uint64  `json:"nonvoluntaryCtxtSwitches"`
+	VmHWM                    uint64  `json:"vmHWM"`
+	VmRSS                    uint64  `json:"vmRSS"`
+	VmSwap                   uint64  `json:"vmSwap"`
+	Uids                     []int32 `json:"uids"`
+	Gids                     []int32 `json:"gids"`
+	NSpid                    []int32 `json:"nspid"`
+	SigPnd                   uint64  `json:"sigPnd"`
+	ShdPnd                   uint64  `json:"shdPnd"`
+	SigBlk                   uint64  `json:"sigBlk"`
+	SigIgn                   uint64  `json:"sigIgn"`
+	SigCgt                   uint64  `json:"sigCgt"`
+}
+
+func (p *proc) Status() (*ProcStatusStat, error) {
+	return p.StatusWithContext(context.Background())
+}
+
+func (p *proc) StatusWithContext(ctx context.Context) (*ProcStatusStat, error) {
+	lines, err := ReadLines(p.procPath(ctx, "status"))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &ProcStatusStat{}
+	for _, line := range lines {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Name":
+			ret.Name = value
+		case "State":
+			// "S (sleeping)"
+			ret.State, _, _ = strings.Cut(value, " ")
+		case "PPid":
+			ret.PPid = parseInt32(value)
+		case "Threads":
+			ret.Threads = parseInt32(value)
+		case "voluntary_ctxt_switches":
+			ret.VoluntaryCtxtSwitches, _ = strconv.ParseUint(value, 10, 64)
+		case "nonvoluntary_ctxt_switches":
+			ret.NonvoluntaryCtxtSwitches, _ = strconv.ParseUint(value, 10, 64)
+		case "VmHWM":
+			ret.VmHWM = parseKB(value)
+		case "VmRSS":
+			ret.VmRSS = parseKB(value)
+		case "VmSwap":
+			ret.VmSwap = parseKB(value)
+		case "Uid":
+			ret.Uids = parseInt32s(value)
+		case "Gid":
+			ret.Gids = parseInt32s(value)
+		case "NSpid":
+			ret.NSpid = parseInt32s(value)
+		case "SigPnd":
+			ret.SigPnd, _ = strconv.ParseUint(value, 16, 64)
+		case "ShdPnd":
+			ret.ShdPnd, _ = strconv.ParseUint(value, 16, 64)
+		case "SigBlk":
+			ret.SigBlk, _ = strconv.ParseUint(value, 16, 64)
+		case "SigIgn":
+			ret.SigIgn, _ = strconv.ParseUint(value, 16, 64)
+		case "SigCgt":
+			ret.SigCgt, _ = strconv.ParseUint(value, 16, 64)
+		}
+	}
+	return ret, nil
+}
+
+func parseInt32(s string) int32 {
+	v, _ := strconv.ParseInt(s, 10, 32)
+	return int32(v)
+}
+
+func parseInt32s(s string) []int32 {
+	fields := strings.Fields(s)
+	ret := make([]int32, 0, len(fields))
+	for _, f := range fields {
+		ret = append(ret, parseInt32(f))
+	}
+	return ret
+}
+
+// parseKB parses "1320 kB" into bytes.
+func parseKB(s string) uint64 {
+	num, _, _ := strings.Cut(s, " ")
+	v, _ := strconv.ParseUint(num, 10, 64)
+	return v * 1024
+}
+
+// Uids returns the real, effective, saved set and filesystem user ids of the process.
+func (p *proc) Uids() ([]int32, error) {
+	return p.UidsWithContext(context.Background())
+}
+
+func (p *proc) UidsWithContext(ctx context.Context) ([]int32, error) {
+	st, err := p.StatusWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return st.Uids, nil
+}
+
+// Gids returns the real, effective, saved set and filesystem group ids of the process.
+func (p *proc) Gids() ([]int32, error) {
+	return p.GidsWithContext(context.Background())
+}
+
+func (p *proc) GidsWithContext(ctx context.Context) ([]int32, error) {
+	st, err := p.StatusWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return st.Gids, nil
+}
+
+// Username returns the name of the real user of the process. Users unknown to the
+// passwd database, e.g. of another container, are reported by their uid.
+func (p *proc) Username() (string, error) {
+	return p.UsernameWithContext(context.Background())
+}
+
+func (p *proc) UsernameWithContext(ctx context.Context) (string, error) {
+	uids, err := p.UidsWithContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(uids) == 0 {
+		return "", errors.New("no uid in status")
+	}
+	return lookupUsername(uids[0]), nil
+}
+
+// lookupUsername returns the name of uid, or uid itself when it has no passwd entry,
+// e.g. in a container.
+func lookupUsername(uid int32) string {
+	id := strconv.Itoa(int(uid))
+	u, err := user.LookupId(id)
+	if err != nil {
+		return id
+	}
+	return u.Username
+}
+
+// process states of /proc/<pid>/stat
+const (
+	StateRunning    = "R"
+	StateSleeping   = "S"
+	StateDiskSleep  = "D"
+	StateZombie     = "Z"
+	StateStopped    = "T"
+	StateTracedStop = "t"
+	StateIdle       = "I"
+	StateDead       = "X"
+)
+
+// State returns the scheduler state of the process, one of the State* constants.
+// StateDiskSleep is an uninterruptible sleep, usually waiting for I/O.
+func (p *proc) State() (string, error) {
+	return p.StateWithContext(context.Background())
+}
+
+func (p *proc) StateWithContext(ctx context.Context) (string, error) {
+	contents, err := os.ReadFile(p.procPath(ctx, "stat"))
+	if err != nil {
+		return "", err
+	}
+	return splitProcStat(contents)[3], nil
+}
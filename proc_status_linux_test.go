@@ -0,0 +1,57 @@
+package cpuproc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_Status(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status string
+		want   ProcStatusStat
+	}{
+		{
+			name: "full",
+			status: "Name:\tnginx: worker\nState:\tS (sleeping)\nPPid:\t1\nUid:\t1000\t1000\t1000\t1000\n" +
+				"Gid:\t100\t100\t100\t100\nNSpid:\t42\t7\nVmHWM:\t   2048 kB\nVmRSS:\t   1024 kB\nVmSwap:\t      0 kB\n" +
+				"Threads:\t4\nSigBlk:\t0000000000000002\nSigCgt:\t0000000180004a02\n" +
+				"voluntary_ctxt_switches:\t150\nnonvoluntary_ctxt_switches:\t3\n",
+			want: ProcStatusStat{
+				Name: "nginx: worker", State: "S", PPid: 1, Threads: 4,
+				VoluntaryCtxtSwitches: 150, NonvoluntaryCtxtSwitches: 3,
+				VmHWM: 2048 * 1024, VmRSS: 1024 * 1024,
+				Uids: []int32{1000, 1000, 1000, 1000}, Gids: []int32{100, 100, 100, 100}, NSpid: []int32{42, 7},
+				SigBlk: 2, SigCgt: 0x180004a02,
+			},
+		},
+		{
+			// kernel threads have no memory
+			name:   "kthread",
+			status: "Name:\tkworker/0:1\nState:\tI (idle)\nPPid:\t2\nThreads:\t1\n",
+			want:   ProcStatusStat{Name: "kworker/0:1", State: "I", PPid: 2, Threads: 1},
+		},
+		{
+			name:   "malformed",
+			status: "no colon here\nThreads:\tmany\nVmRSS:\tkB\nSigCgt:\tzz\nState:\n\nPPid:\t5\n",
+			want:   ProcStatusStat{PPid: 5},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := newTestContext(t, map[string]string{"proc/42/status": tc.status})
+			p := &proc{pid: 42}
+			got, err := p.StatusWithContext(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*got, tc.want) {
+				t.Errorf("got %+v, want %+v", *got, tc.want)
+			}
+		})
+	}
+
+	p := &proc{pid: 43}
+	if _, err := p.StatusWithContext(newTestContext(t, nil)); err == nil {
+		t.Error("expected an error for a missing process")
+	}
+}
@@ -0,0 +1,92 @@
+package cpuproc
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ThreadStat contains the cpu times of a thread of a process, Name is its comm.
+type ThreadStat struct {
+	TID   int32     `json:"tid"`
+	Name  string    `json:"name"`
+	Times TimesStat `json:"times"`
+}
+
+// ThreadPercentStat contains the cpu usage of a thread over an interval, 100 means one cpu.
+type ThreadPercentStat struct {
+	TID     int32   `json:"tid"`
+	Name    string  `json:"name"`
+	Percent float64 `json:"percent"`
+}
+
+func (p *proc) Threads() ([]ThreadStat, error) {
+	return p.ThreadsWithContext(context.Background())
+}
+
+// ThreadsWithContext lists the threads of /proc/<pid>/task, sorted by tid.
+// Threads that exit while they are listed are skipped.
+func (p *proc) ThreadsWithContext(ctx context.Context) ([]ThreadStat, error) {
+	entries, err := os.ReadDir(p.procPath(ctx, "task"))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]ThreadStat, 0, len(entries))
+	for _, e := range entries {
+		tid, err := strconv.ParseInt(e.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+		_, _, times, _, _, _, _, err := p.fillFromTIDStatWithContext(ctx, int32(tid))
+		if err != nil {
+			continue
+		}
+		name, _ := readTrimmed(p.procPath(ctx, "task", e.Name(), "comm"))
+		ret = append(ret, ThreadStat{TID: int32(tid), Name: name, Times: *times})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].TID < ret[j].TID })
+	return ret, nil
+}
+
+func (p *proc) ThreadsPercent(interval time.Duration) ([]ThreadPercentStat, error) {
+	return p.ThreadsPercentWithContext(context.Background(), interval)
+}
+
+// ThreadsPercentWithContext returns the cpu usage of every thread that lived through interval,
+// the busiest first.
+func (p *proc) ThreadsPercentWithContext(ctx context.Context, interval time.Duration) ([]ThreadPercentStat, error) {
+	t1, err := p.ThreadsWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	if err := Sleep(ctx, interval); err != nil {
+		return nil, err
+	}
+
+	t2, err := p.ThreadsWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start).Seconds()
+
+	before := make(map[int32]TimesStat, len(t1))
+	for _, t := range t1 {
+		before[t.TID] = t.Times
+	}
+	ret := make([]ThreadPercentStat, 0, len(t2))
+	for _, t := range t2 {
+		b, ok := before[t.TID]
+		if !ok {
+			continue
+		}
+		busy := t.Times.User + t.Times.System - b.User - b.System
+		ret = append(ret, ThreadPercentStat{TID: t.TID, Name: t.Name, Percent: max(0, busy/elapsed*100)})
+	}
+	sort.SliceStable(ret, func(i, j int) bool { return ret[i].Percent > ret[j].Percent })
+	return ret, nil
+}
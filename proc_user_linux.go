@@ -0,0 +1,58 @@
+package cpuproc
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// UserCPUStat is the cpu usage of the processes of a user over an interval, 100 being one
+// cpu. Processes are grouped by real uid, as Username does.
+type UserCPUStat struct {
+	Uid      int32   `json:"uid"`
+	Username string  `json:"username"`
+	Pids     []int32 `json:"pids"`
+	Percent  float64 `json:"percent"`
+}
+
+// UserCPU returns the cpu usage of every user over interval, busiest first.
+func UserCPU(interval time.Duration) ([]UserCPUStat, error) {
+	return UserCPUWithContext(context.Background(), interval)
+}
+
+func UserCPUWithContext(ctx context.Context, interval time.Duration) ([]UserCPUStat, error) {
+	samples, err := samplePercents(ctx, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	byUid := make(map[int32]*UserCPUStat)
+	for pid, s := range samples {
+		st, err := (&proc{pid: pid}).StatusWithContext(ctx)
+		if err != nil || len(st.Uids) == 0 {
+			// exited meanwhile
+			continue
+		}
+		uid := st.Uids[0]
+		u, ok := byUid[uid]
+		if !ok {
+			u = &UserCPUStat{Uid: uid, Username: lookupUsername(uid)}
+			byUid[uid] = u
+		}
+		u.Pids = append(u.Pids, pid)
+		u.Percent += s.percent
+	}
+
+	ret := make([]UserCPUStat, 0, len(byUid))
+	for _, u := range byUid {
+		sort.Slice(u.Pids, func(i, j int) bool { return u.Pids[i] < u.Pids[j] })
+		ret = append(ret, *u)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Percent != ret[j].Percent {
+			return ret[i].Percent > ret[j].Percent
+		}
+		return ret[i].Uid < ret[j].Uid
+	})
+	return ret, nil
+}
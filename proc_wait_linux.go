@@ -0,0 +1,134 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// waitPollInterval is how often WaitWithContext checks the process on kernels without pidfd.
+var waitPollInterval = 100 * time.Millisecond
+
+// ExitStat is how a process exited. It is only Available for children of the current
+// process, whose status the kernel keeps until they are reaped; Code is then the exit
+// code, or -1 when the process was killed by Signal.
+type ExitStat struct {
+	Available bool        `json:"available"`
+	Code      int         `json:"code"`
+	Signal    unix.Signal `json:"signal"`
+}
+
+// siginfo codes of SIGCHLD
+const (
+	cldExited = 1
+	cldKilled = 2
+	cldDumped = 3
+)
+
+// Wait blocks until the process exits.
+func (p *proc) Wait() (*ExitStat, error) {
+	return p.WaitWithContext(context.Background())
+}
+
+// WaitWithContext blocks until the process exits or ctx is done. It polls a pidfd (linux 5.3)
+// and falls back to checking /proc periodically. A child of the current process is not
+// reaped, os/exec or the caller still has to wait for it.
+func (p *proc) WaitWithContext(ctx context.Context) (*ExitStat, error) {
+	var fd int
+	if p.pidfd != nil {
+		fd = int(p.pidfd.Fd())
+	} else {
+		var err error
+		fd, err = unix.PidfdOpen(int(p.pid), 0)
+		if err != nil {
+			if errors.Is(err, unix.ESRCH) {
+				return &ExitStat{}, nil
+			}
+			return p.pollExit(ctx)
+		}
+		defer unix.Close(fd)
+
+		// the pid may have been recycled before the pidfd was opened
+		if running, err := p.IsRunningWithContext(ctx); err == nil && !running {
+			return &ExitStat{}, nil
+		}
+	}
+
+	wakeup, stop, err := newCtxWakeup(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+
+	fds := []unix.PollFd{
+		{Fd: int32(fd), Events: unix.POLLIN},
+		{Fd: int32(wakeup), Events: unix.POLLIN},
+	}
+	for {
+		if _, err := unix.Poll(fds, -1); err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			return nil, err
+		}
+		if fds[0].Revents != 0 {
+			return pidfdExitStat(fd), nil
+		}
+		if fds[1].Revents != 0 {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (p *proc) pollExit(ctx context.Context) (*ExitStat, error) {
+	for {
+		running, err := p.IsRunningWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !running {
+			return &ExitStat{}, nil
+		}
+		// a zombie has exited, it only waits for its parent
+		if state, err := p.StateWithContext(ctx); err == nil && state == StateZombie {
+			return &ExitStat{}, nil
+		}
+		if err := Sleep(ctx, waitPollInterval); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// pidfdExitStat peeks at the exit status of the process of pidfd, which only works for
+// children, WNOWAIT leaves them to be reaped by their owner.
+func pidfdExitStat(fd int) *ExitStat {
+	var info unix.Siginfo
+	if err := unix.Waitid(unix.P_PIDFD, fd, &info, unix.WEXITED|unix.WNOHANG|unix.WNOWAIT, nil); err != nil {
+		return &ExitStat{}
+	}
+
+	// the sigchld fields follow signo, errno and code, aligned to a pointer:
+	// pid int32, uid uint32, status int32
+	offset := 3 * unsafe.Sizeof(int32(0))
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		offset += 4
+	}
+	base := unsafe.Pointer(&info)
+	pid := *(*int32)(unsafe.Add(base, offset))
+	status := *(*int32)(unsafe.Add(base, offset+8))
+	if pid == 0 {
+		// WNOHANG and not exited yet, cannot happen once the pidfd is readable
+		return &ExitStat{}
+	}
+
+	switch info.Code {
+	case cldExited:
+		return &ExitStat{Available: true, Code: int(status)}
+	case cldKilled, cldDumped:
+		return &ExitStat{Available: true, Code: -1, Signal: unix.Signal(status)}
+	}
+	return &ExitStat{}
+}
@@ -0,0 +1,47 @@
+package cpuproc
+
+import (
+	"context"
+	"strings"
+)
+
+// Wchan returns the kernel function the process sleeps in, e.g. "do_epoll_wait", or an
+// empty string when it is running. Kernels without CONFIG_KALLSYMS only report "0".
+func (p *proc) Wchan() (string, error) {
+	return p.WchanWithContext(context.Background())
+}
+
+func (p *proc) WchanWithContext(ctx context.Context) (string, error) {
+	wchan, err := readTrimmed(p.procPath(ctx, "wchan"))
+	if err != nil {
+		return "", err
+	}
+	if wchan == "0" {
+		return "", nil
+	}
+	return wchan, nil
+}
+
+// KernelStack returns the kernel stack of the main thread of the process, innermost frame
+// first, e.g. "do_wait+0x171/0x300". Reading it needs CAP_SYS_ADMIN.
+func (p *proc) KernelStack() ([]string, error) {
+	return p.KernelStackWithContext(context.Background())
+}
+
+func (p *proc) KernelStackWithContext(ctx context.Context) ([]string, error) {
+	lines, err := ReadLines(p.procPath(ctx, "stack"))
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]string, 0, len(lines))
+	for _, line := range lines {
+		// "[<0>] do_wait+0x171/0x300", the address is hidden without kptr_restrict access
+		if _, frame, ok := strings.Cut(line, "] "); ok {
+			line = frame
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			ret = append(ret, line)
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,193 @@
+package cpuproc
+
+import (
+	"context"
+	"encoding/binary"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// ProcEventType is the kind of a ProcEvent.
+type ProcEventType uint32
+
+// proc connector events, see linux/cn_proc.h
+const (
+	ProcEventFork ProcEventType = 0x00000001
+	ProcEventExec ProcEventType = 0x00000002
+	ProcEventComm ProcEventType = 0x00000200
+	ProcEventExit ProcEventType = 0x80000000
+)
+
+func (t ProcEventType) String() string {
+	switch t {
+	case ProcEventFork:
+		return "fork"
+	case ProcEventExec:
+		return "exec"
+	case ProcEventComm:
+		return "comm"
+	case ProcEventExit:
+		return "exit"
+	}
+	return "unknown"
+}
+
+// ProcEvent is a process event of the proc connector. Pid and Tgid are the thread and the
+// process, a new thread is a fork whose Pid differs from its Tgid. ParentPid and ParentTgid
+// are set for fork events, ExitCode and ExitSignal for exit events: the wait status and the
+// signal sent to the parent, usually SIGCHLD. Timestamp is the monotonic time of the event in nanoseconds since boot.
+type ProcEvent struct {
+	Type       ProcEventType `json:"type"`
+	CPU        uint32        `json:"cpu"`
+	Timestamp  uint64        `json:"timestamp"`
+	Pid        int32         `json:"pid"`
+	Tgid       int32         `json:"tgid"`
+	ParentPid  int32         `json:"parentPid"`
+	ParentTgid int32         `json:"parentTgid"`
+	ExitCode   uint32        `json:"exitCode"`
+	ExitSignal uint32        `json:"exitSignal"`
+}
+
+// connector ids and operations of the proc connector, see linux/connector.h
+const (
+	cnIdxProc          = 1
+	cnValProc          = 1
+	procCnMcastListen  = 1
+	procCnMcastIgnore  = 2
+	sizeofCnMsg        = 20
+	sizeofProcEventHdr = 16
+)
+
+// WatchProcessEvents subscribes to the proc connector and sends the fork, exec, comm and exit
+// events of every process on the returned channel, so that process discovery doesn't have to
+// poll /proc. It needs CAP_NET_ADMIN and the kernel only sends the events to the initial
+// network namespace. Events are dropped by the kernel when the reader falls behind.
+// The channel is closed when ctx is done.
+func WatchProcessEvents(ctx context.Context) (<-chan ProcEvent, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, unix.NETLINK_CONNECTOR)
+	if err != nil {
+		return nil, err
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: cnIdxProc}); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	if err := procConnectorControl(fd, procCnMcastListen); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	wakeup, stop, err := newCtxWakeup(ctx)
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	ch := make(chan ProcEvent)
+	go func() {
+		defer close(ch)
+		defer stop()
+		defer unix.Close(fd)
+		defer procConnectorControl(fd, procCnMcastIgnore)
+
+		fds := []unix.PollFd{
+			{Fd: int32(fd), Events: unix.POLLIN},
+			{Fd: int32(wakeup), Events: unix.POLLIN},
+		}
+		buf := make([]byte, unix.Getpagesize())
+		for {
+			if _, err := unix.Poll(fds, -1); err != nil {
+				if err == unix.EINTR {
+					continue
+				}
+				return
+			}
+			if fds[1].Revents != 0 {
+				return
+			}
+			if fds[0].Revents == 0 {
+				continue
+			}
+			n, _, err := unix.Recvfrom(fd, buf, unix.MSG_DONTWAIT)
+			if err != nil {
+				if err == unix.EAGAIN || err == unix.EINTR || err == unix.ENOBUFS {
+					// ENOBUFS: the socket overflowed and events were lost
+					continue
+				}
+				return
+			}
+			msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+			if err != nil {
+				continue
+			}
+			for _, m := range msgs {
+				ev, ok := parseProcEvent(m.Data)
+				if !ok {
+					continue
+				}
+				select {
+				case ch <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// procConnectorControl sends a listen or ignore operation to the proc connector.
+func procConnectorControl(fd int, op uint32) error {
+	msg := make([]byte, unix.NLMSG_HDRLEN+sizeofCnMsg+4)
+	binary.NativeEndian.PutUint32(msg[0:], uint32(len(msg)))
+	binary.NativeEndian.PutUint16(msg[4:], unix.NLMSG_DONE)
+	binary.NativeEndian.PutUint32(msg[8:], uint32(time.Now().UnixNano()))
+
+	cn := msg[unix.NLMSG_HDRLEN:]
+	binary.NativeEndian.PutUint32(cn[0:], cnIdxProc)
+	binary.NativeEndian.PutUint32(cn[4:], cnValProc)
+	binary.NativeEndian.PutUint16(cn[16:], 4)
+	binary.NativeEndian.PutUint32(cn[sizeofCnMsg:], op)
+	return unix.Sendto(fd, msg, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK})
+}
+
+// parseProcEvent decodes the cn_msg and proc_event of a netlink message payload.
+func parseProcEvent(b []byte) (ProcEvent, bool) {
+	if len(b) < sizeofCnMsg+sizeofProcEventHdr {
+		return ProcEvent{}, false
+	}
+	if binary.NativeEndian.Uint32(b[0:]) != cnIdxProc || binary.NativeEndian.Uint32(b[4:]) != cnValProc {
+		return ProcEvent{}, false
+	}
+	b = b[sizeofCnMsg:]
+	ev := ProcEvent{
+		Type:      ProcEventType(binary.NativeEndian.Uint32(b[0:])),
+		CPU:       binary.NativeEndian.Uint32(b[4:]),
+		Timestamp: binary.NativeEndian.Uint64(b[8:]),
+	}
+	data := b[sizeofProcEventHdr:]
+	u32 := func(i int) uint32 {
+		if len(data) < (i+1)*4 {
+			return 0
+		}
+		return binary.NativeEndian.Uint32(data[i*4:])
+	}
+
+	switch ev.Type {
+	case ProcEventFork:
+		ev.ParentPid, ev.ParentTgid = int32(u32(0)), int32(u32(1))
+		ev.Pid, ev.Tgid = int32(u32(2)), int32(u32(3))
+	case ProcEventExec, ProcEventComm:
+		ev.Pid, ev.Tgid = int32(u32(0)), int32(u32(1))
+	case ProcEventExit:
+		ev.Pid, ev.Tgid = int32(u32(0)), int32(u32(1))
+		ev.ExitCode, ev.ExitSignal = u32(2), u32(3)
+	default:
+		// the acknowledgement of the listen operation, uid, gid, sid, ptrace and coredump
+		// events are not reported
+		return ProcEvent{}, false
+	}
+	return ev, true
+}
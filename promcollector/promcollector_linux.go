@@ -0,0 +1,171 @@
+// Package promcollector exports the metrics of cpuproc with a prometheus.Collector, so
+// cpuproc can back /metrics directly. Cpu times, page faults and throttling are exported as
+// counters, rates are left to PromQL.
+package promcollector
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/antlabs/cpuproc"
+)
+
+const namespace = "cpuproc"
+
+var (
+	cpuSecondsDesc = prometheus.NewDesc(namespace+"_cpu_seconds_total",
+		"Seconds the cpus spent in each mode.", []string{"cpu", "mode"}, nil)
+	cpuBusyDesc = prometheus.NewDesc(namespace+"_cpu_busy_percent",
+		"Busy percent of the cpus over the latest sample of the monitor, cpu is \"total\" for the machine.", []string{"cpu"}, nil)
+	thermalThrottlesDesc = prometheus.NewDesc(namespace+"_cpu_thermal_throttles_total",
+		"Thermal throttling events of the cpus.", []string{"cpu", "level"}, nil)
+	bootTimeDesc = prometheus.NewDesc(namespace+"_boot_time_seconds",
+		"Boot time of the machine, in seconds since the epoch.", nil, nil)
+	processSecondsDesc = prometheus.NewDesc(namespace+"_process_cpu_seconds_total",
+		"Cpu seconds used by the process in each mode.", []string{"pid", "mode"}, nil)
+	processFaultsDesc = prometheus.NewDesc(namespace+"_process_page_faults_total",
+		"Page faults of the process.", []string{"pid", "type"}, nil)
+	cgroupPeriodsDesc = prometheus.NewDesc(namespace+"_cgroup_periods_total",
+		"Cfs periods of the cgroup of the current process.", nil, nil)
+	cgroupThrottledPeriodsDesc = prometheus.NewDesc(namespace+"_cgroup_throttled_periods_total",
+		"Cfs periods in which the cgroup of the current process was throttled.", nil, nil)
+	cgroupThrottledSecondsDesc = prometheus.NewDesc(namespace+"_cgroup_throttled_seconds_total",
+		"Seconds the cgroup of the current process was throttled.", nil, nil)
+)
+
+type options struct {
+	pids    []int32
+	monitor *cpuproc.Monitor
+}
+
+// Option configures a Collector.
+type Option func(*options)
+
+// WithProcesses exports the cpu times and page faults of pids.
+func WithProcesses(pids ...int32) Option {
+	return func(o *options) {
+		o.pids = append(o.pids, pids...)
+	}
+}
+
+// WithMonitor exports the busy percents of the latest sample of m, turn WithPerCPU on for the
+// per cpu ones.
+func WithMonitor(m *cpuproc.Monitor) Option {
+	return func(o *options) {
+		o.monitor = m
+	}
+}
+
+// Collector is a prometheus.Collector of the cpu metrics of the machine, of the cgroup of the
+// current process and of selected processes. Metrics that can't be read, e.g. thermal
+// throttling outside of x86, are left out.
+type Collector struct {
+	opts options
+}
+
+// New returns a Collector, register it with prometheus.MustRegister.
+func New(opts ...Option) *Collector {
+	c := &Collector{}
+	for _, opt := range opts {
+		opt(&c.opts)
+	}
+	return c
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		cpuSecondsDesc, cpuBusyDesc, thermalThrottlesDesc, bootTimeDesc,
+		processSecondsDesc, processFaultsDesc,
+		cgroupPeriodsDesc, cgroupThrottledPeriodsDesc, cgroupThrottledSecondsDesc,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	ctx := context.Background()
+	c.collectCPUs(ctx, ch)
+	c.collectProcesses(ctx, ch)
+	c.collectCgroup(ctx, ch)
+
+	if boot, err := cpuproc.BootTimeWithContext(ctx, true); err == nil {
+		ch <- prometheus.MustNewConstMetric(bootTimeDesc, prometheus.GaugeValue, float64(boot))
+	}
+}
+
+func (c *Collector) collectCPUs(ctx context.Context, ch chan<- prometheus.Metric) {
+	if times, err := cpuproc.TimesWithContext(ctx, true); err == nil {
+		for _, t := range times {
+			for mode, v := range map[string]float64{
+				"user":       t.User,
+				"nice":       t.Nice,
+				"system":     t.System,
+				"idle":       t.Idle,
+				"iowait":     t.Iowait,
+				"irq":        t.Irq,
+				"softirq":    t.Softirq,
+				"steal":      t.Steal,
+				"guest":      t.Guest,
+				"guest_nice": t.GuestNice,
+			} {
+				ch <- prometheus.MustNewConstMetric(cpuSecondsDesc, prometheus.CounterValue, v, t.CPU, mode)
+			}
+		}
+	}
+
+	if c.opts.monitor != nil {
+		if s := c.opts.monitor.Latest(); !s.Time.IsZero() {
+			ch <- prometheus.NewMetricWithTimestamp(s.Time,
+				prometheus.MustNewConstMetric(cpuBusyDesc, prometheus.GaugeValue, s.Total, "total"))
+			for _, c := range s.PerCPU {
+				ch <- prometheus.NewMetricWithTimestamp(s.Time,
+					prometheus.MustNewConstMetric(cpuBusyDesc, prometheus.GaugeValue, c.Percent, c.CPU))
+			}
+		}
+	}
+
+	if stats, err := cpuproc.ThermalThrottleWithContext(ctx); err == nil {
+		for _, s := range stats {
+			cpu := "cpu" + strconv.Itoa(s.CPU)
+			ch <- prometheus.MustNewConstMetric(thermalThrottlesDesc, prometheus.CounterValue, float64(s.CoreCount), cpu, "core")
+			ch <- prometheus.MustNewConstMetric(thermalThrottlesDesc, prometheus.CounterValue, float64(s.PackageCount), cpu, "package")
+		}
+	}
+}
+
+func (c *Collector) collectProcesses(ctx context.Context, ch chan<- prometheus.Metric) {
+	for _, pid := range c.opts.pids {
+		p := cpuproc.NewProcess(pid)
+		if p == nil {
+			continue
+		}
+		label := strconv.Itoa(int(pid))
+		if t, err := p.TimesWithContext(ctx); err == nil {
+			ch <- prometheus.MustNewConstMetric(processSecondsDesc, prometheus.CounterValue, t.User, label, "user")
+			ch <- prometheus.MustNewConstMetric(processSecondsDesc, prometheus.CounterValue, t.System, label, "system")
+		}
+		if f, err := p.PageFaultsWithContext(ctx); err == nil {
+			ch <- prometheus.MustNewConstMetric(processFaultsDesc, prometheus.CounterValue, float64(f.MinorFaults), label, "minor")
+			ch <- prometheus.MustNewConstMetric(processFaultsDesc, prometheus.CounterValue, float64(f.MajorFaults), label, "major")
+		}
+	}
+}
+
+func (c *Collector) collectCgroup(ctx context.Context, ch chan<- prometheus.Metric) {
+	cg, err := cpuproc.SelfCgroupWithContext(ctx)
+	if err != nil {
+		return
+	}
+	s, err := cg.ThrottlingWithContext(ctx)
+	if err != nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(cgroupPeriodsDesc, prometheus.CounterValue, float64(s.NrPeriods))
+	ch <- prometheus.MustNewConstMetric(cgroupThrottledPeriodsDesc, prometheus.CounterValue, float64(s.NrThrottled))
+	// ThrottledTime is in microseconds
+	ch <- prometheus.MustNewConstMetric(cgroupThrottledSecondsDesc, prometheus.CounterValue, float64(s.ThrottledTime)/1e6)
+}
@@ -0,0 +1,70 @@
+package promcollector
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/antlabs/cpuproc"
+)
+
+func TestCollector(t *testing.T) {
+	c := New(WithProcesses(int32(os.Getpid())))
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(c); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{
+		"cpuproc_cpu_seconds_total",
+		"cpuproc_boot_time_seconds",
+		"cpuproc_process_cpu_seconds_total",
+		"cpuproc_process_page_faults_total",
+	} {
+		if n, err := testutil.GatherAndCount(reg, name); err != nil || n == 0 {
+			t.Errorf("%s: %d metrics, %v", name, n, err)
+		}
+	}
+}
+
+func TestCollectorCPULabels(t *testing.T) {
+	m, err := cpuproc.NewMonitor(cpuproc.WithPerCPU(true), cpuproc.WithSampleInterval(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Stop()
+	for m.Latest().Time.IsZero() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(New(WithMonitor(m))); err != nil {
+		t.Fatal(err)
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := map[string]map[string]bool{}
+	for _, f := range families {
+		labels[f.GetName()] = map[string]bool{}
+		for _, metric := range f.GetMetric() {
+			for _, l := range metric.GetLabel() {
+				if l.GetName() == "cpu" {
+					labels[f.GetName()][l.GetValue()] = true
+				}
+			}
+		}
+	}
+	if len(labels["cpuproc_cpu_busy_percent"]) < 2 {
+		t.Fatalf("no per cpu busy percent: %v", labels["cpuproc_cpu_busy_percent"])
+	}
+	// a cpu has the same label in both metrics
+	for cpu := range labels["cpuproc_cpu_busy_percent"] {
+		if cpu != "total" && !labels["cpuproc_cpu_seconds_total"][cpu] {
+			t.Errorf("busy percent of %s has no cpu seconds", cpu)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package cpuproc
+
+import (
+	"errors"
+	"time"
+)
+
+// RunLimit is the limit RunLimited runs a command under. CPUs is the number of cpus the
+// command and its descendants may use, e.g. 1.5, 0 for no quota. Cpuset restricts them to
+// some cpus.
+// MoveToLeaf lets RunLimited move the current process for good to the leaf "cpuproc" of its
+// cgroup v2, which is required unless it is already there or in the root cgroup. It is
+// ignored on cgroup v1 and windows.
+type RunLimit struct {
+	CPUs       float64 `json:"cpus"`
+	Cpuset     []int   `json:"cpuset"`
+	MoveToLeaf bool    `json:"moveToLeaf"`
+}
+
+// RunLimitedStat is the resource usage of a command run by RunLimited, its descendants
+// included. User and System are in seconds, Percent is the average cpu usage over Duration,
+// 100 being one cpu. Throttled is the time the limit held the command back, it is only
+// known on linux.
+type RunLimitedStat struct {
+	Duration  time.Duration `json:"duration"`
+	User      float64       `json:"user"`
+	System    float64       `json:"system"`
+	Percent   float64       `json:"percent"`
+	Throttled time.Duration `json:"throttled"`
+	ExitCode  int           `json:"exitCode"`
+}
+
+func (l RunLimit) validate() error {
+	if l.CPUs < 0 {
+		return errors.New("cpus must not be negative")
+	}
+	if l.CPUs == 0 && len(l.Cpuset) == 0 {
+		return errors.New("no limit")
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package cpuproc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// runLimitedSeq numbers the transient cgroups of RunLimited
+var runLimitedSeq atomic.Uint64
+
+// runLimitedPeriod is the CFS period of the quota RunLimited sets
+const runLimitedPeriod = 100000
+
+// runLimitedLeaf is the cgroup v2 leaf RunLimited moves the current process to
+const runLimitedLeaf = "cpuproc"
+
+// RunLimited runs cmd in a transient cgroup limited to limit and waits for it, on cgroup v2
+// it first moves the current process for good to the leaf "cpuproc" of its cgroup when
+// limit.MoveToLeaf is set. The cgroup is created under the one of the current process, which
+// needs write access to it, e.g. with the Delegate= setting of systemd. A cgroup v2 with
+// processes can't enable controllers for its children: without MoveToLeaf RunLimited fails
+// unless the process is already in the leaf, and it fails anyway when other processes share
+// its cgroup.
+// On cgroup v2 the command is started right in the cgroup with CLONE_INTO_CGROUP
+// (linux 5.7), on cgroup v1 it is moved there once started.
+// The command is killed when ctx is done. The usage is that of the cgroup, so descendants
+// are accounted for; those left behind are killed before the cgroup is removed.
+// The error is the one of (*exec.Cmd).Wait, the usage is still returned when the command failed.
+func RunLimited(ctx context.Context, cmd *exec.Cmd, limit RunLimit) (*RunLimitedStat, error) {
+	if err := limit.validate(); err != nil {
+		return nil, err
+	}
+	parent, err := runLimitedParent(ctx, limit.MoveToLeaf)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := parent.NewChild(fmt.Sprintf("cpuproc-%d-%d", os.Getpid(), runLimitedSeq.Add(1)))
+	if err != nil {
+		return nil, err
+	}
+	defer c.removeAll(ctx)
+
+	if limit.CPUs > 0 {
+		quota := CgroupCPULimit{Quota: int64(limit.CPUs * runLimitedPeriod), Period: runLimitedPeriod}
+		if err := c.SetCPULimit(quota); err != nil {
+			return nil, err
+		}
+	}
+	if len(limit.Cpuset) > 0 {
+		if err := c.SetCpuset(limit.Cpuset); err != nil {
+			return nil, err
+		}
+	}
+
+	start := time.Now()
+	if err := c.start(cmd); err != nil {
+		return nil, err
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			cmd.Process.Kill()
+		case <-stop:
+		}
+	}()
+	waitErr := cmd.Wait()
+
+	ret := &RunLimitedStat{Duration: time.Since(start)}
+	if cmd.ProcessState != nil {
+		ret.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if cpu, err := c.CPUStatWithContext(context.Background()); err == nil {
+		ret.User = float64(cpu.User) / 1e6
+		ret.System = float64(cpu.System) / 1e6
+		if d := ret.Duration.Seconds(); d > 0 {
+			ret.Percent = float64(cpu.Usage) / 1e6 / d * 100
+		}
+	}
+	if t, err := c.ThrottlingWithContext(context.Background()); err == nil {
+		ret.Throttled = time.Duration(t.ThrottledTime) * time.Microsecond
+	}
+	return ret, waitErr
+}
+
+// runLimitedParent returns the cgroup RunLimited creates its cgroups under: the one of the
+// current process, after moving the process to a leaf on cgroup v2 when move is set.
+func runLimitedParent(ctx context.Context, move bool) (*Cgroup, error) {
+	self, err := SelfCgroupWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if self.version == 1 || self.path == cgroup2Root(ctx) {
+		return self, nil
+	}
+	if filepath.Base(self.path) == runLimitedLeaf {
+		// moved by a previous call
+		return self.Parent(), nil
+	}
+	if !move {
+		return nil, fmt.Errorf("the process must leave %s to limit a command, see RunLimit.MoveToLeaf", self.path)
+	}
+	// not NewChild, self can't enable controllers before the process has left it
+	leaf := &Cgroup{version: 2, path: filepath.Join(self.path, runLimitedLeaf)}
+	if err := os.Mkdir(leaf.path, 0o755); err != nil && !os.IsExist(err) {
+		return nil, err
+	}
+	if err := leaf.AddProcess(int32(os.Getpid())); err != nil {
+		return nil, err
+	}
+	if pids, err := self.PidsWithContext(ctx); err == nil && len(pids) > 0 {
+		return nil, fmt.Errorf("%s has other processes, it can't enable controllers", self.path)
+	}
+	return self, nil
+}
+
+// start starts cmd in the cgroup.
+func (c *Cgroup) start(cmd *exec.Cmd) error {
+	if c.version == 1 {
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		if err := c.AddProcess(int32(cmd.Process.Pid)); err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return err
+		}
+		return nil
+	}
+
+	dir, err := os.Open(c.path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(dir.Fd())
+	return cmd.Start()
+}
+
+// removeAll kills the processes left in the cgroup and removes it.
+func (c *Cgroup) removeAll(ctx context.Context) error {
+	var err error
+	for i := 0; i < 50; i++ {
+		pids, _ := c.PidsWithContext(ctx)
+		for _, pid := range pids {
+			unix.Kill(int(pid), unix.SIGKILL)
+		}
+		if err = c.Remove(); err == nil {
+			return nil
+		}
+		// killed processes leave the cgroup once they are reaped
+		time.Sleep(20 * time.Millisecond)
+	}
+	return err
+}
@@ -0,0 +1,77 @@
+package cpuproc
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func Test_runLimitedParent(t *testing.T) {
+	ctx := newTestContext(t, map[string]string{
+		"proc/self/cgroup":                                         "0::/app.service\n",
+		"sys/fs/cgroup/cgroup.controllers":                         "cpuset cpu\n",
+		"sys/fs/cgroup/app.service/cgroup.controllers":             "cpu\n",
+		"sys/fs/cgroup/app.service/cgroup.procs":                   "",
+		"sys/fs/cgroup/app.service/cgroup.subtree_control":         "",
+		"sys/fs/cgroup/app.service/cpuproc/cgroup.procs":           "",
+		"sys/fs/cgroup/app.service/cpuproc/cgroup.controllers":     "",
+		"sys/fs/cgroup/app.service/cpuproc/cgroup.subtree_control": "",
+	})
+	service := HostSysWithContext(ctx, "fs/cgroup/app.service")
+
+	// the process is only moved on request
+	if _, err := runLimitedParent(ctx, false); err == nil {
+		t.Error("no error without MoveToLeaf")
+	}
+	if got, _ := readTrimmed(filepath.Join(service, "cpuproc", "cgroup.procs")); got != "" {
+		t.Errorf("leaf cgroup.procs = %q without MoveToLeaf", got)
+	}
+
+	parent, err := runLimitedParent(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// under the cgroup of the process, not next to it
+	if parent.Path() != service {
+		t.Errorf("parent = %s, want %s", parent.Path(), service)
+	}
+	if got, _ := readTrimmed(filepath.Join(service, "cpuproc", "cgroup.procs")); got != strconv.Itoa(os.Getpid()) {
+		t.Errorf("leaf cgroup.procs = %q, want the current process", got)
+	}
+
+	child, err := parent.NewChild("job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := readTrimmed(filepath.Join(service, "cgroup.subtree_control")); got != "+cpu" {
+		t.Errorf("subtree_control = %q, want +cpu", got)
+	}
+	if filepath.Dir(child.Path()) != service {
+		t.Errorf("child = %s, want it under %s", child.Path(), service)
+	}
+
+	// once moved, the process is in the leaf and the cgroup above is reused
+	if err := os.WriteFile(HostProcWithContext(ctx, "self/cgroup"), []byte("0::/app.service/cpuproc\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	parent, err = runLimitedParent(ctx, false)
+	if err != nil || parent.Path() != service {
+		t.Errorf("got %v, %v, want %s", parent, err, service)
+	}
+}
+
+func Test_runLimitedParentShared(t *testing.T) {
+	ctx := newTestContext(t, map[string]string{
+		"proc/self/cgroup":                               "0::/app.service\n",
+		"sys/fs/cgroup/cgroup.controllers":               "cpuset cpu\n",
+		"sys/fs/cgroup/app.service/cgroup.controllers":   "cpu\n",
+		"sys/fs/cgroup/app.service/cgroup.procs":         "1234\n",
+		"sys/fs/cgroup/app.service/cpuproc/cgroup.procs": "",
+	})
+
+	// another process stays in the cgroup, which can't enable controllers
+	if _, err := runLimitedParent(ctx, true); err == nil {
+		t.Error("no error with another process in the cgroup")
+	}
+}
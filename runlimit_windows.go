@@ -0,0 +1,114 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os/exec"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// jobBasicAccountingInformation is JOBOBJECT_BASIC_ACCOUNTING_INFORMATION, times are in
+// 100ns units.
+type jobBasicAccountingInformation struct {
+	TotalUserTime             int64
+	TotalKernelTime           int64
+	ThisPeriodTotalUserTime   int64
+	ThisPeriodTotalKernelTime int64
+	TotalPageFaultCount       uint32
+	TotalProcesses            uint32
+	ActiveProcesses           uint32
+	TotalTerminatedProcesses  uint32
+}
+
+// RunLimited runs cmd in a new job object limited to limit and waits for it. The cpu limit is
+// a hard capped cpu rate of the job, the cpuset its affinity. The command is assigned to the
+// job once started, processes it creates afterwards belong to the job too. The command is
+// killed when ctx is done, and the processes left in the job once it exited are killed too.
+// The error is the one of (*exec.Cmd).Wait, the usage is still returned when the command failed.
+func RunLimited(ctx context.Context, cmd *exec.Cmd, limit RunLimit) (*RunLimitedStat, error) {
+	if err := limit.validate(); err != nil {
+		return nil, err
+	}
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	// closing the last handle of the job kills its processes
+	defer windows.CloseHandle(job)
+
+	info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{}
+	info.BasicLimitInformation.LimitFlags = windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE
+	if len(limit.Cpuset) > 0 {
+		var mask uintptr
+		for _, cpu := range limit.Cpuset {
+			if cpu < 0 || cpu >= int(unsafe.Sizeof(mask))*8 {
+				return nil, errors.New("cpu out of the affinity mask")
+			}
+			mask |= 1 << cpu
+		}
+		info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_AFFINITY
+		info.BasicLimitInformation.Affinity = mask
+	}
+	if _, err := windows.SetInformationJobObject(job, windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info))); err != nil {
+		return nil, err
+	}
+
+	if limit.CPUs > 0 {
+		n := float64(windows.GetActiveProcessorCount(windows.ALL_PROCESSOR_GROUPS))
+		// the rate is in 1/100 of a percent of the whole machine
+		rate := jobCPURateControlInformation{
+			ControlFlags: jobCPURateControlEnable | jobCPURateControlHardCap,
+			Rate:         uint32(math.Max(1, math.Min(10000, limit.CPUs/n*10000))),
+		}
+		if _, err := windows.SetInformationJobObject(job, windows.JobObjectCpuRateControlInformation,
+			uintptr(unsafe.Pointer(&rate)), uint32(unsafe.Sizeof(rate))); err != nil {
+			return nil, err
+		}
+	}
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	h, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(cmd.Process.Pid))
+	if err == nil {
+		err = windows.AssignProcessToJobObject(job, h)
+		windows.CloseHandle(h)
+	}
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			windows.TerminateJobObject(job, 1)
+		case <-stop:
+		}
+	}()
+	waitErr := cmd.Wait()
+
+	ret := &RunLimitedStat{Duration: time.Since(start)}
+	if cmd.ProcessState != nil {
+		ret.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	var acct jobBasicAccountingInformation
+	if err := windows.QueryInformationJobObject(job, windows.JobObjectBasicAccountingInformation,
+		uintptr(unsafe.Pointer(&acct)), uint32(unsafe.Sizeof(acct)), nil); err == nil {
+		ret.User = float64(acct.TotalUserTime) / 1e7
+		ret.System = float64(acct.TotalKernelTime) / 1e7
+		if d := ret.Duration.Seconds(); d > 0 {
+			ret.Percent = (ret.User + ret.System) / d * 100
+		}
+	}
+	return ret, waitErr
+}
@@ -0,0 +1,31 @@
+package cpuproc
+
+import "time"
+
+// Sample is the cpu usage over the interval that ended at Time. Total and PerCPU are the busy
+// percents of the machine, Total is the usage of the cgroup relative to its limit with
+// WithCgroup. PerCPU is nil unless per cpu sampling is on. Processes holds the usage of every
+// sampled process that is still running, normalized like (*proc).Percent.
+type Sample struct {
+	Time      time.Time         `json:"time"`
+	Total     float64           `json:"total"`
+	PerCPU    []CPUPercent      `json:"perCPU"`
+	Processes map[int32]float64 `json:"processes"`
+}
+
+// CPUPercent is the busy percent of a cpu, CPU is its name in TimesStat, e.g. "cpu3". The
+// cpus of a Sample are not indexed by their number: offline and filtered out cpus are missing.
+type CPUPercent struct {
+	CPU     string  `json:"cpu"`
+	Percent float64 `json:"percent"`
+}
+
+// CPU returns the busy percent of the cpu named name, ok is false when it is not sampled.
+func (s Sample) CPU(name string) (percent float64, ok bool) {
+	for _, c := range s.PerCPU {
+		if c.CPU == name {
+			return c.Percent, true
+		}
+	}
+	return 0, false
+}
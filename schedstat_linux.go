@@ -0,0 +1,127 @@
+package cpuproc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SchedstatStat contains the scheduler statistics of a cpu. Times are in nanoseconds.
+// It is based on linux /proc/schedstat (version 15 and later), which requires CONFIG_SCHEDSTATS.
+type SchedstatStat struct {
+	CPU           string `json:"cpu"`
+	Yields        uint64 `json:"yields"`
+	Schedules     uint64 `json:"schedules"`
+	IdleSchedules uint64 `json:"idleSchedules"`
+	Wakeups       uint64 `json:"wakeups"`
+	LocalWakeups  uint64 `json:"localWakeups"`
+	RunTime       uint64 `json:"runTime"`
+	WaitTime      uint64 `json:"waitTime"`
+	Timeslices    uint64 `json:"timeslices"`
+}
+
+// SchedstatRateStat contains the scheduler activity of a cpu over an interval.
+// Run and Wait are the seconds spent running and waiting on the runqueue per second,
+// AvgWait is the mean runqueue latency of a timeslice.
+type SchedstatRateStat struct {
+	CPU        string        `json:"cpu"`
+	Run        float64       `json:"run"`
+	Wait       float64       `json:"wait"`
+	Timeslices float64       `json:"timeslices"`
+	AvgWait    time.Duration `json:"avgWait"`
+}
+
+func Schedstat() ([]SchedstatStat, error) {
+	return SchedstatWithContext(context.Background())
+}
+
+func SchedstatWithContext(ctx context.Context) ([]SchedstatStat, error) {
+	lines, err := ReadLines(HostProcWithContext(ctx, "schedstat"))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]SchedstatStat, 0, len(lines))
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "version" && len(fields) > 1 {
+			if v, err := strconv.Atoi(fields[1]); err != nil || v < 15 {
+				return nil, fmt.Errorf("unsupported schedstat version %s", fields[1])
+			}
+			continue
+		}
+		if _, ok := cpuIndex(fields[0]); !ok {
+			// timestamp and domain lines
+			continue
+		}
+		if len(fields) < 10 {
+			return nil, fmt.Errorf("wrong schedstat format")
+		}
+
+		var v [9]uint64
+		for i := range v {
+			if v[i], err = strconv.ParseUint(fields[i+1], 10, 64); err != nil {
+				return nil, err
+			}
+		}
+		ret = append(ret, SchedstatStat{
+			CPU:           fields[0],
+			Yields:        v[0],
+			Schedules:     v[2],
+			IdleSchedules: v[3],
+			Wakeups:       v[4],
+			LocalWakeups:  v[5],
+			RunTime:       v[6],
+			WaitTime:      v[7],
+			Timeslices:    v[8],
+		})
+	}
+	return ret, nil
+}
+
+func SchedstatRate(interval time.Duration) ([]SchedstatRateStat, error) {
+	return SchedstatRateWithContext(context.Background(), interval)
+}
+
+func SchedstatRateWithContext(ctx context.Context, interval time.Duration) ([]SchedstatRateStat, error) {
+	s1, err := SchedstatWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	if err := Sleep(ctx, interval); err != nil {
+		return nil, err
+	}
+
+	s2, err := SchedstatWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start).Seconds()
+
+	prev := make(map[string]SchedstatStat, len(s1))
+	for _, s := range s1 {
+		prev[s.CPU] = s
+	}
+
+	ret := make([]SchedstatRateStat, 0, len(s2))
+	for _, s := range s2 {
+		r := SchedstatRateStat{CPU: s.CPU}
+		if p, ok := prev[s.CPU]; ok {
+			r.Run = counterRate(p.RunTime, s.RunTime, elapsed) / 1e9
+			r.Wait = counterRate(p.WaitTime, s.WaitTime, elapsed) / 1e9
+			r.Timeslices = counterRate(p.Timeslices, s.Timeslices, elapsed)
+			if s.Timeslices > p.Timeslices && s.WaitTime >= p.WaitTime {
+				r.AvgWait = time.Duration((s.WaitTime - p.WaitTime) / (s.Timeslices - p.Timeslices))
+			}
+		}
+		ret = append(ret, r)
+	}
+	return ret, nil
+}
@@ -0,0 +1,27 @@
+package cpuproc
+
+import "testing"
+
+func Test_SnapshotPids(t *testing.T) {
+	stat := "42 (a) b (c) S 1 42 42 0 -1 4194560 100 0 0 0 250 130 0 0 20 0 1 0 1000 1000000 200 " +
+		"18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 0 0 0 7 0 0\n"
+	ctx := newTestContext(t, map[string]string{"proc/42/stat": stat})
+
+	ret, err := SnapshotPidsWithContext(ctx, []int32{42, 43})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ret) != 1 {
+		t.Fatalf("got %d processes, want 1", len(ret))
+	}
+	got := ret[42]
+	want := TimesStat{
+		CPU:    "cpu",
+		User:   250 / float64(clockTicks),
+		System: 130 / float64(clockTicks),
+		Iowait: 7 / float64(clockTicks),
+	}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,99 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// SoftirqStat contains the counters of one softirq type (NET_RX, TIMER, RCU...) of /proc/softirqs.
+// Counts are aligned with the CPUs of SoftirqsStat.
+type SoftirqStat struct {
+	Type   string   `json:"type"`
+	Counts []uint64 `json:"counts"`
+	Total  uint64   `json:"total"`
+}
+
+type SoftirqsStat struct {
+	CPUs     []int         `json:"cpus"`
+	Softirqs []SoftirqStat `json:"softirqs"`
+}
+
+// SoftirqRateStat contains the per second rates of a softirq type over an interval.
+// PerCPU is aligned with the CPUs of the later sample.
+type SoftirqRateStat struct {
+	Type   string    `json:"type"`
+	PerCPU []float64 `json:"perCPU"`
+	Total  float64   `json:"total"`
+}
+
+func Softirqs() (*SoftirqsStat, error) {
+	return SoftirqsWithContext(context.Background())
+}
+
+func SoftirqsWithContext(ctx context.Context) (*SoftirqsStat, error) {
+	lines, err := ReadLines(HostProcWithContext(ctx, "softirqs"))
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("softirqs is empty")
+	}
+
+	cpus, err := parseCPUHeader(lines[0])
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &SoftirqsStat{CPUs: cpus}
+	for _, line := range lines[1:] {
+		typ, rest, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		counts, _ := parseCounts(strings.Fields(rest), len(cpus))
+
+		s := SoftirqStat{Type: strings.TrimSpace(typ), Counts: counts}
+		for _, c := range counts {
+			s.Total += c
+		}
+		ret.Softirqs = append(ret.Softirqs, s)
+	}
+	return ret, nil
+}
+
+func SoftirqRates(interval time.Duration) ([]SoftirqRateStat, error) {
+	return SoftirqRatesWithContext(context.Background(), interval)
+}
+
+func SoftirqRatesWithContext(ctx context.Context, interval time.Duration) ([]SoftirqRateStat, error) {
+	s1, err := SoftirqsWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	if err := Sleep(ctx, interval); err != nil {
+		return nil, err
+	}
+
+	s2, err := SoftirqsWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start).Seconds()
+
+	prev := make(map[string]SoftirqStat, len(s1.Softirqs))
+	for _, s := range s1.Softirqs {
+		prev[s.Type] = s
+	}
+
+	ret := make([]SoftirqRateStat, 0, len(s2.Softirqs))
+	for _, s := range s2.Softirqs {
+		r := SoftirqRateStat{Type: s.Type}
+		r.PerCPU, r.Total = countRates(s1.CPUs, prev[s.Type].Counts, s2.CPUs, s.Counts, elapsed)
+		ret = append(ret, r)
+	}
+	return ret, nil
+}
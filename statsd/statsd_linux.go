@@ -0,0 +1,188 @@
+// Package statsd pushes the samples of a cpuproc.Monitor to a StatsD or DogStatsD server.
+package statsd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antlabs/cpuproc"
+)
+
+// maxPacketSize keeps packets under the usual MTU of 1500 bytes
+const maxPacketSize = 1432
+
+type options struct {
+	prefix    string
+	tags      []string
+	dogstatsd bool
+	interval  time.Duration
+}
+
+// Option configures an Emitter.
+type Option func(*options)
+
+// WithPrefix sets the prefix of the metric names, "cpuproc." by default.
+func WithPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// WithDogStatsD makes the Emitter use the DogStatsD tags extension: the cpu and pid are tags
+// instead of parts of the metric names, and the tags of WithTags are sent.
+func WithDogStatsD(enable bool) Option {
+	return func(o *options) {
+		o.dogstatsd = enable
+	}
+}
+
+// WithTags adds tags like "env:prod" to every metric, they are only sent with WithDogStatsD.
+func WithTags(tags ...string) Option {
+	return func(o *options) {
+		o.tags = append(o.tags, tags...)
+	}
+}
+
+// WithInterval sets the time between two pushes, 10 seconds by default.
+func WithInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.interval = interval
+	}
+}
+
+// Emitter periodically pushes the latest sample of a Monitor as gauges: the busy percent of the
+// machine, of every cpu when the Monitor samples them, and the usage of the sampled processes.
+// A sample is only pushed once.
+type Emitter struct {
+	opts    options
+	monitor *cpuproc.Monitor
+	conn    net.Conn
+
+	mu   sync.Mutex
+	last time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New starts an Emitter pushing the samples of m to the udp address addr, e.g.
+// "127.0.0.1:8125". It must be stopped with Stop, m is not stopped with it.
+func New(m *cpuproc.Monitor, addr string, opts ...Option) (*Emitter, error) {
+	o := options{prefix: "cpuproc.", interval: 10 * time.Second}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &Emitter{opts: o, monitor: m, conn: conn, cancel: cancel, done: make(chan struct{})}
+	go e.run(ctx)
+	return e, nil
+}
+
+func (e *Emitter) run(ctx context.Context) {
+	defer close(e.done)
+	ticker := time.NewTicker(e.opts.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// udp errors are transient, e.g. the agent restarting
+			e.Emit()
+		}
+	}
+}
+
+// Emit pushes the latest sample now, unless it was already pushed.
+func (e *Emitter) Emit() error {
+	s := e.monitor.Latest()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if s.Time.IsZero() || !s.Time.After(e.last) {
+		return nil
+	}
+	e.last = s.Time
+
+	var packet []byte
+	for _, line := range e.lines(s) {
+		if len(packet) > 0 && len(packet)+1+len(line) > maxPacketSize {
+			if _, err := e.conn.Write(packet); err != nil {
+				return err
+			}
+			packet = packet[:0]
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, line...)
+	}
+	if len(packet) == 0 {
+		return nil
+	}
+	_, err := e.conn.Write(packet)
+	return err
+}
+
+// lines formats the gauges of s.
+func (e *Emitter) lines(s cpuproc.Sample) []string {
+	ret := []string{e.gauge("cpu", "", "", "percent", s.Total)}
+	for _, c := range s.PerCPU {
+		// the number of the cpu, not its index: offline and filtered cpus are missing
+		ret = append(ret, e.gauge("cpu", "cpu", strings.TrimPrefix(c.CPU, "cpu"), "percent", c.Percent))
+	}
+
+	pids := make([]int32, 0, len(s.Processes))
+	for pid := range s.Processes {
+		pids = append(pids, pid)
+	}
+	sort.Slice(pids, func(i, j int) bool { return pids[i] < pids[j] })
+	for _, pid := range pids {
+		ret = append(ret, e.gauge("process", "pid", strconv.Itoa(int(pid)), "cpu.percent", s.Processes[pid]))
+	}
+	return ret
+}
+
+// gauge formats the gauge scope.name, id identifies the cpu or process within the scope: it
+// is the tag key:id with DogStatsD, else a part of the name, e.g. "cpu.3.percent".
+func (e *Emitter) gauge(scope, key, id, name string, v float64) string {
+	var b strings.Builder
+	b.WriteString(e.opts.prefix + scope + ".")
+	if id != "" && !e.opts.dogstatsd {
+		b.WriteString(id + ".")
+	}
+	b.WriteString(name + ":" + strconv.FormatFloat(v, 'f', -1, 64) + "|g")
+
+	if e.opts.dogstatsd {
+		tags := e.opts.tags
+		if id != "" {
+			tags = append(tags[:len(tags):len(tags)], key+":"+id)
+		}
+		if len(tags) > 0 {
+			b.WriteString("|#" + strings.Join(tags, ","))
+		}
+	}
+	return b.String()
+}
+
+// Stop stops pushing and closes the connection, it can be called more than once.
+func (e *Emitter) Stop() {
+	e.cancel()
+	<-e.done
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.conn.Close()
+}
@@ -0,0 +1,73 @@
+package statsd
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antlabs/cpuproc"
+)
+
+func TestLines(t *testing.T) {
+	s := cpuproc.Sample{Total: 12.5, PerCPU: []cpuproc.CPUPercent{{CPU: "cpu0", Percent: 10}, {CPU: "cpu2", Percent: 15}}, Processes: map[int32]float64{42: 3}}
+
+	e := &Emitter{opts: options{prefix: "app."}}
+	want := []string{
+		"app.cpu.percent:12.5|g",
+		"app.cpu.0.percent:10|g",
+		"app.cpu.2.percent:15|g",
+		"app.process.42.cpu.percent:3|g",
+	}
+	if got := e.lines(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("statsd lines = %q, want %q", got, want)
+	}
+
+	e.opts.dogstatsd, e.opts.tags = true, []string{"env:prod"}
+	want = []string{
+		"app.cpu.percent:12.5|g|#env:prod",
+		"app.cpu.percent:10|g|#env:prod,cpu:0",
+		"app.cpu.percent:15|g|#env:prod,cpu:2",
+		"app.process.cpu.percent:3|g|#env:prod,pid:42",
+	}
+	if got := e.lines(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("dogstatsd lines = %q, want %q", got, want)
+	}
+}
+
+func TestEmit(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+
+	m, err := cpuproc.NewMonitor(cpuproc.WithSampleInterval(20 * time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Stop()
+	e, err := New(m, conn.LocalAddr().String(), WithInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Stop()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for m.Latest().Time.IsZero() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := e.Emit(); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, maxPacketSize)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); !strings.HasPrefix(got, "cpuproc.cpu.percent:") {
+		t.Errorf("packet %q", got)
+	}
+}
@@ -0,0 +1,76 @@
+package cpuproc
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// SwapMemoryStat contains the swap usage, sizes are in bytes. In and Out are the bytes
+// swapped in and out since boot. It is based on linux /proc/meminfo and /proc/vmstat.
+type SwapMemoryStat struct {
+	Total       uint64  `json:"total"`
+	Free        uint64  `json:"free"`
+	Used        uint64  `json:"used"`
+	UsedPercent float64 `json:"usedPercent"`
+	Cached      uint64  `json:"cached"`
+	In          uint64  `json:"in"`
+	Out         uint64  `json:"out"`
+}
+
+// SwapRateStat contains the bytes swapped in and out per second over an interval.
+type SwapRateStat struct {
+	In  float64 `json:"in"`
+	Out float64 `json:"out"`
+}
+
+func SwapMemory() (*SwapMemoryStat, error) {
+	return SwapMemoryWithContext(context.Background())
+}
+
+func SwapMemoryWithContext(ctx context.Context) (*SwapMemoryStat, error) {
+	info, err := readMeminfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &SwapMemoryStat{
+		Total:  info["SwapTotal"],
+		Free:   info["SwapFree"],
+		Cached: info["SwapCached"],
+	}
+	if ret.Free > ret.Total {
+		ret.Free = ret.Total
+	}
+	ret.Used = ret.Total - ret.Free
+	if ret.Total > 0 {
+		ret.UsedPercent = float64(ret.Used) / float64(ret.Total) * 100
+	}
+
+	vm, err := VmstatWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pageSize := uint64(os.Getpagesize())
+	ret.In = vm["pswpin"] * pageSize
+	ret.Out = vm["pswpout"] * pageSize
+
+	return ret, nil
+}
+
+func SwapRate(interval time.Duration) (*SwapRateStat, error) {
+	return SwapRateWithContext(context.Background(), interval)
+}
+
+func SwapRateWithContext(ctx context.Context, interval time.Duration) (*SwapRateStat, error) {
+	rates, err := VmstatRateWithContext(ctx, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := float64(os.Getpagesize())
+	return &SwapRateStat{
+		In:  rates["pswpin"] * pageSize,
+		Out: rates["pswpout"] * pageSize,
+	}, nil
+}
@@ -0,0 +1,99 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// SystemdUnitStat contains the cpu usage of a systemd unit, a service or a scope, found in
+// the system.slice and user.slice cgroups. Slice is the slice the unit belongs to, e.g.
+// "user-1000.slice". Throttling is nil when the unit has no cpu controller.
+type SystemdUnitStat struct {
+	Unit       string                `json:"unit"`
+	Slice      string                `json:"slice"`
+	CgroupPath string                `json:"cgroupPath"`
+	CPU        CgroupCPUStat         `json:"cpu"`
+	Throttling *CgroupThrottlingStat `json:"throttling"`
+}
+
+func SystemdUnits() ([]SystemdUnitStat, error) {
+	return SystemdUnitsWithContext(context.Background())
+}
+
+// SystemdUnitsWithContext returns the usage of the units of system.slice and user.slice,
+// nested slices such as user-1000.slice/user@1000.service are walked too.
+func SystemdUnitsWithContext(ctx context.Context) ([]SystemdUnitStat, error) {
+	var ret []SystemdUnitStat
+	found := false
+	for _, slice := range []string{"/system.slice", "/user.slice"} {
+		c := newCgroup(ctx, slice)
+		if !PathExists(c.Path()) {
+			continue
+		}
+		found = true
+		ret = appendUnits(ctx, ret, c)
+	}
+	if !found {
+		return nil, errors.New("systemd slices not found")
+	}
+	return ret, nil
+}
+
+// UnitOf returns the systemd unit of pid from its cgroup path, e.g. "nginx.service".
+func UnitOf(pid int32) (string, error) {
+	return UnitOfWithContext(context.Background(), pid)
+}
+
+func UnitOfWithContext(ctx context.Context, pid int32) (string, error) {
+	c, err := pidCgroup(ctx, strconv.Itoa(int(pid)))
+	if err != nil {
+		return "", err
+	}
+	if unit := systemdUnit(c.Path()); unit != "" {
+		return unit, nil
+	}
+	return "", errors.New("process is not in a systemd unit")
+}
+
+func appendUnits(ctx context.Context, units []SystemdUnitStat, slice *Cgroup) []SystemdUnitStat {
+	children, err := slice.ChildrenWithContext(ctx)
+	if err != nil {
+		return units
+	}
+	for _, c := range children {
+		name := c.Name()
+		switch {
+		case strings.HasSuffix(name, ".slice"):
+			units = appendUnits(ctx, units, c)
+		case strings.HasSuffix(name, ".service") || strings.HasSuffix(name, ".scope"):
+			cpu, err := c.CPUStatWithContext(ctx)
+			if err != nil {
+				continue
+			}
+			u := SystemdUnitStat{Unit: name, Slice: slice.Name(), CgroupPath: c.Path(), CPU: *cpu}
+			if t, err := c.ThrottlingWithContext(ctx); err == nil {
+				u.Throttling = t
+			}
+			units = append(units, u)
+			// user@1000.service holds the units of the user manager
+			if strings.HasPrefix(name, "user@") {
+				units = appendUnits(ctx, units, c)
+			}
+		}
+	}
+	return units
+}
+
+// systemdUnit returns the innermost unit of a cgroup path, the units of a user
+// manager are reported rather than user@<uid>.service.
+func systemdUnit(path string) string {
+	parts := strings.Split(path, "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		if strings.HasSuffix(parts[i], ".service") || strings.HasSuffix(parts[i], ".scope") {
+			return parts[i]
+		}
+	}
+	return ""
+}
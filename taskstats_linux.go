@@ -0,0 +1,279 @@
+package cpuproc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// DelayStat contains the delay accounting of a task from the taskstats netlink interface.
+// Delays are the total nanoseconds the task waited for a cpu on the runqueue, for
+// synchronous block io, for swapping pages in, for memory reclaim and for thrashing,
+// Count fields are the number of delays. CPURunReal and CPURunVirtual are the
+// nanoseconds the task ran in wall clock and in virtual time.
+type DelayStat struct {
+	Pid               int32  `json:"pid"`
+	CPUCount          uint64 `json:"cpuCount"`
+	CPUDelay          uint64 `json:"cpuDelay"`
+	BlkIOCount        uint64 `json:"blkioCount"`
+	BlkIODelay        uint64 `json:"blkioDelay"`
+	SwapinCount       uint64 `json:"swapinCount"`
+	SwapinDelay       uint64 `json:"swapinDelay"`
+	FreepagesCount    uint64 `json:"freepagesCount"`
+	FreepagesDelay    uint64 `json:"freepagesDelay"`
+	ThrashingCount    uint64 `json:"thrashingCount"`
+	ThrashingDelay    uint64 `json:"thrashingDelay"`
+	CPURunReal        uint64 `json:"cpuRunReal"`
+	CPURunVirtual     uint64 `json:"cpuRunVirtual"`
+	VoluntarySwitch   uint64 `json:"voluntarySwitch"`
+	InvoluntarySwitch uint64 `json:"involuntarySwitch"`
+}
+
+// Taskstats is a generic netlink socket to the TASKSTATS family. It is opt-in since the
+// kernel only answers with CAP_NET_ADMIN and delays are only accounted with
+// CONFIG_TASK_DELAY_ACCT and the delayacct boot option or kernel.task_delayacct sysctl.
+// A Taskstats is safe for concurrent use and must be closed.
+type Taskstats struct {
+	mu     sync.Mutex
+	fd     int
+	family uint16
+	seq    uint32
+}
+
+func NewTaskstats() (*Taskstats, error) {
+	return NewTaskstatsWithContext(context.Background())
+}
+
+// NewTaskstatsWithContext opens the netlink socket and resolves the id of the TASKSTATS family.
+func NewTaskstatsWithContext(ctx context.Context) (*Taskstats, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_GENERIC)
+	if err != nil {
+		return nil, err
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	t := &Taskstats{fd: fd}
+	attrs, err := t.request(ctx, unix.GENL_ID_CTRL, unix.CTRL_CMD_GETFAMILY, 1,
+		netlinkAttr(unix.CTRL_ATTR_FAMILY_NAME, append([]byte(unix.TASKSTATS_GENL_NAME), 0)))
+	if err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("taskstats family: %w", err)
+	}
+	for _, a := range attrs {
+		if a.typ == unix.CTRL_ATTR_FAMILY_ID && len(a.data) >= 2 {
+			t.family = binary.NativeEndian.Uint16(a.data)
+		}
+	}
+	if t.family == 0 {
+		unix.Close(fd)
+		return nil, errors.New("taskstats family not found")
+	}
+	return t, nil
+}
+
+func (t *Taskstats) Close() error {
+	return unix.Close(t.fd)
+}
+
+// Delays returns the delays of the process pid, summed over its threads.
+func (t *Taskstats) Delays(pid int32) (*DelayStat, error) {
+	return t.DelaysWithContext(context.Background(), pid)
+}
+
+func (t *Taskstats) DelaysWithContext(ctx context.Context, pid int32) (*DelayStat, error) {
+	return t.get(ctx, unix.TASKSTATS_CMD_ATTR_TGID, pid)
+}
+
+// ThreadDelays returns the delays of the single thread tid.
+func (t *Taskstats) ThreadDelays(tid int32) (*DelayStat, error) {
+	return t.ThreadDelaysWithContext(context.Background(), tid)
+}
+
+func (t *Taskstats) ThreadDelaysWithContext(ctx context.Context, tid int32) (*DelayStat, error) {
+	return t.get(ctx, unix.TASKSTATS_CMD_ATTR_PID, tid)
+}
+
+// Delays is a shortcut opening a Taskstats for a single query, see (*Taskstats).Delays.
+func (p *proc) Delays() (*DelayStat, error) {
+	return p.DelaysWithContext(context.Background())
+}
+
+func (p *proc) DelaysWithContext(ctx context.Context) (*DelayStat, error) {
+	t, err := NewTaskstatsWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer t.Close()
+	return t.DelaysWithContext(ctx, p.pid)
+}
+
+func (t *Taskstats) get(ctx context.Context, attr uint16, pid int32) (*DelayStat, error) {
+	data := make([]byte, 4)
+	binary.NativeEndian.PutUint32(data, uint32(pid))
+	attrs, err := t.request(ctx, t.family, unix.TASKSTATS_CMD_GET, unix.TASKSTATS_GENL_VERSION, netlinkAttr(attr, data))
+	if err != nil {
+		return nil, err
+	}
+
+	// the stats are nested in an AGGR_PID or AGGR_TGID attribute next to the pid
+	for _, a := range attrs {
+		if a.typ != unix.TASKSTATS_TYPE_AGGR_PID && a.typ != unix.TASKSTATS_TYPE_AGGR_TGID {
+			continue
+		}
+		for _, n := range parseNetlinkAttrs(a.data) {
+			if n.typ == unix.TASKSTATS_TYPE_STATS {
+				return newDelayStat(pid, n.data), nil
+			}
+		}
+	}
+	return nil, errors.New("no taskstats in reply")
+}
+
+// newDelayStat decodes struct taskstats, older kernels send a shorter struct whose
+// missing fields are left zero.
+func newDelayStat(pid int32, data []byte) *DelayStat {
+	var ts unix.Taskstats
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(&ts)), unsafe.Sizeof(ts)), data)
+	return &DelayStat{
+		Pid:               pid,
+		CPUCount:          ts.Cpu_count,
+		CPUDelay:          ts.Cpu_delay_total,
+		BlkIOCount:        ts.Blkio_count,
+		BlkIODelay:        ts.Blkio_delay_total,
+		SwapinCount:       ts.Swapin_count,
+		SwapinDelay:       ts.Swapin_delay_total,
+		FreepagesCount:    ts.Freepages_count,
+		FreepagesDelay:    ts.Freepages_delay_total,
+		ThrashingCount:    ts.Thrashing_count,
+		ThrashingDelay:    ts.Thrashing_delay_total,
+		CPURunReal:        ts.Cpu_run_real_total,
+		CPURunVirtual:     ts.Cpu_run_virtual_total,
+		VoluntarySwitch:   ts.Nvcsw,
+		InvoluntarySwitch: ts.Nivcsw,
+	}
+}
+
+type netlinkAttribute struct {
+	typ  uint16
+	data []byte
+}
+
+// request sends a generic netlink command and returns the attributes of the reply, or
+// ctx.Err() when ctx is done first.
+func (t *Taskstats) request(ctx context.Context, family uint16, cmd, version uint8, attrs ...[]byte) ([]netlinkAttribute, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.seq++
+	msg := make([]byte, unix.NLMSG_HDRLEN+unix.GENL_HDRLEN)
+	for _, a := range attrs {
+		msg = append(msg, a...)
+	}
+	binary.NativeEndian.PutUint32(msg[0:], uint32(len(msg)))
+	binary.NativeEndian.PutUint16(msg[4:], family)
+	binary.NativeEndian.PutUint16(msg[6:], unix.NLM_F_REQUEST)
+	binary.NativeEndian.PutUint32(msg[8:], t.seq)
+	msg[unix.NLMSG_HDRLEN] = cmd
+	msg[unix.NLMSG_HDRLEN+1] = version
+
+	if err := unix.Sendto(t.fd, msg, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, err
+	}
+
+	// a reply arriving after ctx is done is skipped by the next request, its sequence
+	// number is stale
+	wakeup, stop, err := newCtxWakeup(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+	fds := []unix.PollFd{
+		{Fd: int32(t.fd), Events: unix.POLLIN},
+		{Fd: int32(wakeup), Events: unix.POLLIN},
+	}
+
+	buf := make([]byte, unix.Getpagesize())
+	for {
+		if _, err := unix.Poll(fds, -1); err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			return nil, err
+		}
+		if fds[0].Revents == 0 && fds[1].Revents != 0 {
+			return nil, ctx.Err()
+		}
+		n, _, err := unix.Recvfrom(t.fd, buf, 0)
+		if err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			return nil, err
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range msgs {
+			if m.Header.Seq != t.seq {
+				continue
+			}
+			if m.Header.Type == unix.NLMSG_ERROR {
+				if len(m.Data) >= 4 {
+					if errno := int32(binary.NativeEndian.Uint32(m.Data)); errno != 0 {
+						return nil, unix.Errno(-errno)
+					}
+				}
+				continue
+			}
+			if len(m.Data) < unix.GENL_HDRLEN {
+				return nil, errors.New("short generic netlink message")
+			}
+			return parseNetlinkAttrs(m.Data[unix.GENL_HDRLEN:]), nil
+		}
+	}
+}
+
+// netlinkAttr encodes an attribute, padded to NLA_ALIGNTO.
+func netlinkAttr(typ uint16, data []byte) []byte {
+	l := unix.SizeofNlAttr + len(data)
+	b := make([]byte, nlaAlign(l))
+	binary.NativeEndian.PutUint16(b[0:], uint16(l))
+	binary.NativeEndian.PutUint16(b[2:], typ)
+	copy(b[unix.SizeofNlAttr:], data)
+	return b
+}
+
+func parseNetlinkAttrs(b []byte) []netlinkAttribute {
+	var ret []netlinkAttribute
+	for len(b) >= unix.SizeofNlAttr {
+		l := int(binary.NativeEndian.Uint16(b[0:]))
+		if l < unix.SizeofNlAttr || l > len(b) {
+			break
+		}
+		// the high bits flag nested and byte order attributes
+		typ := binary.NativeEndian.Uint16(b[2:]) &^ (unix.NLA_F_NESTED | unix.NLA_F_NET_BYTEORDER)
+		ret = append(ret, netlinkAttribute{typ: typ, data: b[unix.SizeofNlAttr:l]})
+		if nlaAlign(l) >= len(b) {
+			break
+		}
+		b = b[nlaAlign(l):]
+	}
+	return ret
+}
+
+func nlaAlign(l int) int {
+	return (l + unix.NLA_ALIGNTO - 1) &^ (unix.NLA_ALIGNTO - 1)
+}
@@ -0,0 +1,128 @@
+package cpuproc
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// TemperatureStat contains the reading of a temperature sensor in degrees Celsius.
+// Package and Core are -1 when the sensor can't be mapped to a cpu package or core.
+// It is based on linux /sys/class/hwmon and /sys/class/thermal.
+type TemperatureStat struct {
+	SensorKey   string  `json:"sensorKey"`
+	Label       string  `json:"label"`
+	Temperature float64 `json:"temperature"`
+	High        float64 `json:"high"`
+	Critical    float64 `json:"critical"`
+	Package     int     `json:"package"`
+	Core        int     `json:"core"`
+}
+
+func Temperatures() ([]TemperatureStat, error) {
+	return TemperaturesWithContext(context.Background())
+}
+
+func TemperaturesWithContext(ctx context.Context) ([]TemperatureStat, error) {
+	ret, err := hwmonTemperatures(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	zones, err := filepath.Glob(HostSysWithContext(ctx, "class/thermal/thermal_zone[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, zone := range zones {
+		temp, err := readMilliCelsius(filepath.Join(zone, "temp"))
+		if err != nil {
+			continue
+		}
+		label, _ := readTrimmed(filepath.Join(zone, "type"))
+		ret = append(ret, TemperatureStat{
+			SensorKey:   filepath.Base(zone),
+			Label:       label,
+			Temperature: temp,
+			Package:     -1,
+			Core:        -1,
+		})
+	}
+	return ret, nil
+}
+
+// hwmonTemperatures reads the temp*_input sensors of every hwmon device. coretemp reports one
+// device per package with "Package id N" and "Core N" labels, which are used for the mapping.
+func hwmonTemperatures(ctx context.Context) ([]TemperatureStat, error) {
+	devices, err := filepath.Glob(HostSysWithContext(ctx, "class/hwmon/hwmon[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []TemperatureStat
+	for _, dev := range devices {
+		name, _ := readTrimmed(filepath.Join(dev, "name"))
+		inputs, err := filepath.Glob(filepath.Join(dev, "temp[0-9]*_input"))
+		if err != nil {
+			return nil, err
+		}
+
+		pkg := -1
+		start := len(ret)
+		for _, input := range inputs {
+			temp, err := readMilliCelsius(input)
+			if err != nil {
+				continue
+			}
+			prefix := strings.TrimSuffix(input, "_input")
+			label, _ := readTrimmed(prefix + "_label")
+			t := TemperatureStat{
+				SensorKey:   name + "_" + filepath.Base(prefix),
+				Label:       label,
+				Temperature: temp,
+				Package:     -1,
+				Core:        -1,
+			}
+			t.High, _ = readMilliCelsius(prefix + "_max")
+			t.Critical, _ = readMilliCelsius(prefix + "_crit")
+
+			if name == "coretemp" {
+				if id, ok := labelNumber(label, "Package id "); ok {
+					pkg = id
+				} else if id, ok := labelNumber(label, "Physical id "); ok {
+					pkg = id
+				} else if id, ok := labelNumber(label, "Core "); ok {
+					t.Core = id
+				}
+			}
+			ret = append(ret, t)
+		}
+
+		if pkg >= 0 {
+			for i := start; i < len(ret); i++ {
+				ret[i].Package = pkg
+			}
+		}
+	}
+	return ret, nil
+}
+
+func labelNumber(label, prefix string) (int, bool) {
+	if !strings.HasPrefix(label, prefix) {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(label, prefix))
+	return n, err == nil
+}
+
+func readMilliCelsius(filename string) (float64, error) {
+	s, err := readTrimmed(filename)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	return v / 1000, nil
+}
@@ -0,0 +1,33 @@
+package cpuproc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_Temperatures(t *testing.T) {
+	ctx := newTestContext(t, map[string]string{
+		"sys/class/hwmon/hwmon1/name":          "coretemp\n",
+		"sys/class/hwmon/hwmon1/temp1_input":   "45000\n",
+		"sys/class/hwmon/hwmon1/temp1_label":   "Package id 1\n",
+		"sys/class/hwmon/hwmon1/temp1_crit":    "100000\n",
+		"sys/class/hwmon/hwmon1/temp2_input":   "43500\n",
+		"sys/class/hwmon/hwmon1/temp2_label":   "Core 4\n",
+		"sys/class/hwmon/hwmon1/temp2_max":     "90000\n",
+		"sys/class/thermal/thermal_zone0/type": "acpitz\n",
+		"sys/class/thermal/thermal_zone0/temp": "27800\n",
+	})
+
+	temps, err := TemperaturesWithContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TemperatureStat{
+		{SensorKey: "coretemp_temp1", Label: "Package id 1", Temperature: 45, Critical: 100, Package: 1, Core: -1},
+		{SensorKey: "coretemp_temp2", Label: "Core 4", Temperature: 43.5, High: 90, Package: 1, Core: 4},
+		{SensorKey: "thermal_zone0", Label: "acpitz", Temperature: 27.8, Package: -1, Core: -1},
+	}
+	if !reflect.DeepEqual(temps, want) {
+		t.Errorf("got %+v, want %+v", temps, want)
+	}
+}
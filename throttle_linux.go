@@ -0,0 +1,124 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ThermalThrottleStat contains the thermal throttling events of a cpu since boot, times are in
+// milliseconds. It is based on linux /sys/devices/system/cpu/cpu*/thermal_throttle (x86 only).
+type ThermalThrottleStat struct {
+	CPU          int    `json:"cpu"`
+	CoreCount    uint64 `json:"coreCount"`
+	CoreTime     uint64 `json:"coreTime"`
+	PackageCount uint64 `json:"packageCount"`
+	PackageTime  uint64 `json:"packageTime"`
+}
+
+func ThermalThrottle() ([]ThermalThrottleStat, error) {
+	return ThermalThrottleWithContext(context.Background())
+}
+
+// ThermalThrottleWithContext returns the throttle counters of every cpu, sorted by cpu id.
+func ThermalThrottleWithContext(ctx context.Context) ([]ThermalThrottleStat, error) {
+	dirs, err := filepath.Glob(HostSysWithContext(ctx, "devices/system/cpu/cpu[0-9]*/thermal_throttle"))
+	if err != nil {
+		return nil, err
+	}
+	if len(dirs) == 0 {
+		return nil, errors.New("thermal_throttle is not available")
+	}
+
+	ret := make([]ThermalThrottleStat, 0, len(dirs))
+	for _, dir := range dirs {
+		cpu, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(filepath.Dir(dir)), "cpu"))
+		if err != nil {
+			continue
+		}
+		t := ThermalThrottleStat{CPU: cpu}
+		if t.CoreCount, err = readUint(filepath.Join(dir, "core_throttle_count")); err != nil {
+			return nil, err
+		}
+		if t.PackageCount, err = readUint(filepath.Join(dir, "package_throttle_count")); err != nil {
+			return nil, err
+		}
+		// the total times were added in linux 5.17
+		t.CoreTime, _ = readUint(filepath.Join(dir, "core_throttle_total_time_ms"))
+		t.PackageTime, _ = readUint(filepath.Join(dir, "package_throttle_total_time_ms"))
+		ret = append(ret, t)
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].CPU < ret[j].CPU })
+	return ret, nil
+}
+
+// ThermalThrottleDelta returns the throttle events that happened between two samples,
+// cpus missing from before are left out.
+func ThermalThrottleDelta(before, after []ThermalThrottleStat) []ThermalThrottleStat {
+	prev := make(map[int]ThermalThrottleStat, len(before))
+	for _, t := range before {
+		prev[t.CPU] = t
+	}
+
+	ret := make([]ThermalThrottleStat, 0, len(after))
+	for _, t := range after {
+		p, ok := prev[t.CPU]
+		if !ok {
+			continue
+		}
+		ret = append(ret, ThermalThrottleStat{
+			CPU:          t.CPU,
+			CoreCount:    counterDelta(p.CoreCount, t.CoreCount),
+			CoreTime:     counterDelta(p.CoreTime, t.CoreTime),
+			PackageCount: counterDelta(p.PackageCount, t.PackageCount),
+			PackageTime:  counterDelta(p.PackageTime, t.PackageTime),
+		})
+	}
+	return ret
+}
+
+// WatchThermalThrottle polls the throttle counters every interval and calls fn with the
+// deltas of the cpus whose counters increased. It returns once the first sample is taken,
+// fn is called from a background goroutine until ctx is done.
+func WatchThermalThrottle(ctx context.Context, interval time.Duration, fn func([]ThermalThrottleStat)) error {
+	if interval <= 0 {
+		return errors.New("interval must be positive")
+	}
+	last, err := ThermalThrottleWithContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			cur, err := ThermalThrottleWithContext(ctx)
+			if err != nil {
+				continue
+			}
+			var throttled []ThermalThrottleStat
+			for _, d := range ThermalThrottleDelta(last, cur) {
+				if d.CoreCount > 0 || d.PackageCount > 0 {
+					throttled = append(throttled, d)
+				}
+			}
+			last = cur
+			if len(throttled) > 0 {
+				fn(throttled)
+			}
+		}
+	}()
+	return nil
+}
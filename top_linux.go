@@ -0,0 +1,140 @@
+package cpuproc
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// TopStat is a process and its cpu usage over an interval, 100 being one cpu as in top.
+type TopStat struct {
+	Pid     int32   `json:"pid"`
+	Name    string  `json:"name"`
+	Percent float64 `json:"percent"`
+}
+
+// procSample is the cpu time of a process read from /proc/<pid>/stat, startTime tells a
+// recycled pid apart.
+type procSample struct {
+	name      string
+	pgrp      int32
+	session   int32
+	startTime uint64
+	ticks     uint64
+	// percent is set by samplePercents
+	percent float64
+}
+
+// TopCPU returns the n processes that used the most cpu over interval, busiest first.
+func TopCPU(n int, interval time.Duration) ([]TopStat, error) {
+	return TopCPUWithContext(context.Background(), n, interval)
+}
+
+// TopCPUWithContext reads the stat file of every process, waits interval and reads them
+// again, like top -b -n1. Processes that started or exited in between are left out.
+func TopCPUWithContext(ctx context.Context, n int, interval time.Duration) ([]TopStat, error) {
+	if n <= 0 {
+		return nil, errors.New("n must be positive")
+	}
+	samples, err := samplePercents(ctx, interval)
+	if err != nil {
+		return nil, err
+	}
+	return topSamples(samples, n), nil
+}
+
+// topSamples returns the n busiest of samples, the lowest pid first on ties.
+func topSamples(samples map[int32]procSample, n int) []TopStat {
+	ret := make([]TopStat, 0, len(samples))
+	for pid, s := range samples {
+		ret = append(ret, TopStat{Pid: pid, Name: s.name, Percent: s.percent})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Percent != ret[j].Percent {
+			return ret[i].Percent > ret[j].Percent
+		}
+		return ret[i].Pid < ret[j].Pid
+	})
+	if len(ret) > n {
+		ret = ret[:n]
+	}
+	return ret
+}
+
+// samplePercents samples every process twice, interval apart, and sets their percent.
+// Processes that started or exited in between are left out.
+func samplePercents(ctx context.Context, interval time.Duration) (map[int32]procSample, error) {
+	s1, err := sampleProcs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	if err := Sleep(ctx, interval); err != nil {
+		return nil, err
+	}
+
+	s2, err := sampleProcs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return diffSamples(s1, s2, time.Since(start).Seconds()), nil
+}
+
+// diffSamples sets the percent of the processes of s2 from their ticks in s1, elapsed
+// seconds before, and drops those that are not in both.
+func diffSamples(s1, s2 map[int32]procSample, elapsed float64) map[int32]procSample {
+	for pid, cur := range s2 {
+		prev, ok := s1[pid]
+		if !ok || prev.startTime != cur.startTime {
+			delete(s2, pid)
+			continue
+		}
+		cur.percent = counterRate(prev.ticks, cur.ticks, elapsed) / ClocksPerSec * 100
+		s2[pid] = cur
+	}
+	return s2
+}
+
+// sampleProcs reads the utime and stime of every process.
+func sampleProcs(ctx context.Context) (map[int32]procSample, error) {
+	entries, err := os.ReadDir(HostProcWithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[int32]procSample, len(entries))
+	for _, e := range entries {
+		pid, err := strconv.ParseInt(e.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+		contents, err := os.ReadFile(HostProcWithContext(ctx, e.Name(), "stat"))
+		if err != nil {
+			// exited meanwhile
+			continue
+		}
+		fields := splitProcStat(contents)
+		if len(fields) < 23 {
+			continue
+		}
+		pgrp, err1 := strconv.ParseInt(fields[5], 10, 32)
+		session, err2 := strconv.ParseInt(fields[6], 10, 32)
+		utime, err3 := strconv.ParseUint(fields[14], 10, 64)
+		stime, err4 := strconv.ParseUint(fields[15], 10, 64)
+		startTime, err5 := strconv.ParseUint(fields[22], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil || err5 != nil {
+			continue
+		}
+		ret[int32(pid)] = procSample{
+			name:      fields[2],
+			pgrp:      int32(pgrp),
+			session:   int32(session),
+			startTime: startTime,
+			ticks:     utime + stime,
+		}
+	}
+	return ret, nil
+}
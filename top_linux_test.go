@@ -0,0 +1,109 @@
+package cpuproc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// procStat formats a /proc/<pid>/stat line with the given groups, cpu ticks and start time.
+func procStat(pid, name, pgrp, session, utime, stime, start string) string {
+	return pid + " (" + name + ") S 1 " + pgrp + " " + session + " 0 -1 4194560 0 0 0 0 " +
+		utime + " " + stime + " 0 0 20 0 1 0 " + start + " 1000000 200 0\n"
+}
+
+func Test_sampleProcs(t *testing.T) {
+	ctx := newTestContext(t, map[string]string{
+		"proc/1/stat":    procStat("1", "init", "1", "1", "50", "50", "1"),
+		"proc/42/stat":   procStat("42", "my app (2)", "42", "7", "300", "100", "900"),
+		"proc/43/stat":   "43 (short) S 1\n",
+		"proc/44/stat":   procStat("44", "bad", "x", "7", "1", "1", "5"),
+		"proc/self/stat": procStat("42", "my app (2)", "42", "7", "300", "100", "900"),
+		"proc/stat":      "cpu 1 2 3 4\n",
+	})
+	got, err := sampleProcs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int32]procSample{
+		1:  {name: "init", pgrp: 1, session: 1, startTime: 1, ticks: 100},
+		42: {name: "my app (2)", pgrp: 42, session: 7, startTime: 900, ticks: 400},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func Test_TopCPU(t *testing.T) {
+	s1 := map[int32]procSample{
+		1:  {name: "init", pgrp: 1, session: 1, startTime: 1, ticks: 100},
+		10: {name: "a", pgrp: 10, session: 10, startTime: 5, ticks: 0},
+		11: {name: "b", pgrp: 10, session: 10, startTime: 6, ticks: 0},
+		12: {name: "c", pgrp: 12, session: 10, startTime: 7, ticks: 0},
+		// exits
+		13: {name: "gone", pgrp: 13, session: 1, startTime: 8, ticks: 0},
+		// pid recycled
+		14: {name: "old", pgrp: 14, session: 1, startTime: 9, ticks: 0},
+	}
+	s2 := map[int32]procSample{
+		1:  {name: "init", pgrp: 1, session: 1, startTime: 1, ticks: 100},
+		10: {name: "a", pgrp: 10, session: 10, startTime: 5, ticks: 100},
+		11: {name: "b", pgrp: 10, session: 10, startTime: 6, ticks: 50},
+		12: {name: "c", pgrp: 12, session: 10, startTime: 7, ticks: 200},
+		14: {name: "new", pgrp: 14, session: 1, startTime: 99, ticks: 500},
+		// started
+		15: {name: "new", pgrp: 15, session: 1, startTime: 100, ticks: 500},
+	}
+	// 200 ticks over 2 seconds is one cpu at the default ClocksPerSec of 100
+	samples := diffSamples(s1, s2, 2)
+
+	top := topSamples(samples, 3)
+	want := []TopStat{{Pid: 12, Name: "c", Percent: 100}, {Pid: 10, Name: "a", Percent: 50}, {Pid: 11, Name: "b", Percent: 25}}
+	if !reflect.DeepEqual(top, want) {
+		t.Errorf("top: got %+v, want %+v", top, want)
+	}
+	// ties are broken by pid
+	if top := topSamples(samples, 10); len(top) != 4 || top[3].Pid != 1 || top[3].Percent != 0 {
+		t.Errorf("top 10: got %+v", top)
+	}
+
+	groups := groupSamples(samples, func(s procSample) int32 { return s.pgrp })
+	wantGroups := []GroupCPUStat{
+		{ID: 12, Pids: []int32{12}, Percent: 100},
+		{ID: 10, Pids: []int32{10, 11}, Percent: 75},
+		{ID: 1, Pids: []int32{1}, Percent: 0},
+	}
+	if !reflect.DeepEqual(groups, wantGroups) {
+		t.Errorf("process groups: got %+v, want %+v", groups, wantGroups)
+	}
+	sessions := groupSamples(samples, func(s procSample) int32 { return s.session })
+	wantSessions := []GroupCPUStat{
+		{ID: 10, Pids: []int32{10, 11, 12}, Percent: 175},
+		{ID: 1, Pids: []int32{1}, Percent: 0},
+	}
+	if !reflect.DeepEqual(sessions, wantSessions) {
+		t.Errorf("sessions: got %+v, want %+v", sessions, wantSessions)
+	}
+}
+
+func Test_TopCPUWithContext(t *testing.T) {
+	ctx := newTestContext(t, map[string]string{
+		"proc/1/stat":  procStat("1", "init", "1", "1", "50", "50", "1"),
+		"proc/42/stat": procStat("42", "app", "42", "1", "300", "100", "900"),
+	})
+	if _, err := TopCPUWithContext(ctx, 0, time.Millisecond); err == nil {
+		t.Error("expected an error for n = 0")
+	}
+	// the fake stat files don't change, nothing is busy
+	top, err := TopCPUWithContext(ctx, 1, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []TopStat{{Pid: 1, Name: "init"}}; !reflect.DeepEqual(top, want) {
+		t.Errorf("got %+v, want %+v", top, want)
+	}
+	groups, err := SessionsCPUWithContext(ctx, time.Millisecond)
+	if err != nil || len(groups) != 1 || !reflect.DeepEqual(groups[0].Pids, []int32{1, 42}) {
+		t.Errorf("got %+v, %v", groups, err)
+	}
+}
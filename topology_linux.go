@@ -0,0 +1,121 @@
+package cpuproc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// TopologyStat describes where a logical CPU sits in the machine. It is based
+// on linux /sys/devices/system/cpu/cpu*/topology.
+type TopologyStat struct {
+	CPU            int   `json:"cpu"`
+	Socket         int   `json:"socket"`
+	Core           int   `json:"core"`
+	Node           int   `json:"node"`
+	ThreadSiblings []int `json:"threadSiblings"`
+	CoreSiblings   []int `json:"coreSiblings"`
+}
+
+func Topology() ([]TopologyStat, error) {
+	return TopologyWithContext(context.Background())
+}
+
+// TopologyWithContext returns the topology of every online logical CPU, sorted by CPU id.
+func TopologyWithContext(ctx context.Context) ([]TopologyStat, error) {
+	dirs, err := filepath.Glob(HostSysWithContext(ctx, "devices/system/cpu/cpu[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]TopologyStat, 0, len(dirs))
+	for _, dir := range dirs {
+		cpu, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "cpu"))
+		if err != nil {
+			continue
+		}
+
+		topo := filepath.Join(dir, "topology")
+		// offline cpus have no topology directory
+		if !PathExists(topo) {
+			continue
+		}
+
+		t := TopologyStat{CPU: cpu}
+		if t.Socket, err = readInt(filepath.Join(topo, "physical_package_id")); err != nil {
+			return nil, err
+		}
+		if t.Core, err = readInt(filepath.Join(topo, "core_id")); err != nil {
+			return nil, err
+		}
+		if t.ThreadSiblings, err = readCPUListFile(filepath.Join(topo, "thread_siblings_list")); err != nil {
+			return nil, err
+		}
+		if t.CoreSiblings, err = readCPUListFile(filepath.Join(topo, "core_siblings_list")); err != nil {
+			return nil, err
+		}
+		t.Node = cpuNode(dir)
+
+		ret = append(ret, t)
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].CPU < ret[j].CPU })
+	return ret, nil
+}
+
+type coreID struct {
+	socket int
+	core   int
+}
+
+// physicalCoreCount returns the number of physical cores behind the cpus of set.
+// If the topology can't be read it falls back to the number of logical cpus.
+func physicalCoreCount(ctx context.Context, set *unix.CPUSet) (int, error) {
+	topo, err := TopologyWithContext(ctx)
+	if err != nil || len(topo) == 0 {
+		return set.Count(), nil
+	}
+
+	cores := make(map[coreID]struct{})
+	for _, t := range topo {
+		if set.IsSet(t.CPU) {
+			cores[coreID{socket: t.Socket, core: t.Core}] = struct{}{}
+		}
+	}
+	if len(cores) == 0 {
+		return set.Count(), nil
+	}
+	return len(cores), nil
+}
+
+// cpuNode returns the NUMA node of the cpu directory, the kernel links it as cpuN/nodeM.
+// Machines without NUMA support are reported as node 0.
+func cpuNode(dir string) int {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, "node") {
+			continue
+		}
+		if node, err := strconv.Atoi(strings.TrimPrefix(name, "node")); err == nil {
+			return node
+		}
+	}
+	return 0
+}
+
+func readCPUListFile(filename string) ([]int, error) {
+	s, err := readTrimmed(filename)
+	if err != nil {
+		return nil, err
+	}
+	return parseCPUList(s)
+}
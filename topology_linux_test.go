@@ -0,0 +1,35 @@
+package cpuproc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_Topology(t *testing.T) {
+	ctx := newTestContext(t, map[string]string{
+		"sys/devices/system/cpu/cpu0/topology/physical_package_id":  "0\n",
+		"sys/devices/system/cpu/cpu0/topology/core_id":              "0\n",
+		"sys/devices/system/cpu/cpu0/topology/thread_siblings_list": "0,2\n",
+		"sys/devices/system/cpu/cpu0/topology/core_siblings_list":   "0-3\n",
+		"sys/devices/system/cpu/cpu0/node0/.keep":                   "",
+		"sys/devices/system/cpu/cpu2/topology/physical_package_id":  "0\n",
+		"sys/devices/system/cpu/cpu2/topology/core_id":              "0\n",
+		"sys/devices/system/cpu/cpu2/topology/thread_siblings_list": "0,2\n",
+		"sys/devices/system/cpu/cpu2/topology/core_siblings_list":   "0-3\n",
+		"sys/devices/system/cpu/cpu2/node1/.keep":                   "",
+		// offline, no topology directory
+		"sys/devices/system/cpu/cpu5/online": "0\n",
+	})
+
+	topo, err := TopologyWithContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TopologyStat{
+		{CPU: 0, Socket: 0, Core: 0, Node: 0, ThreadSiblings: []int{0, 2}, CoreSiblings: []int{0, 1, 2, 3}},
+		{CPU: 2, Socket: 0, Core: 0, Node: 1, ThreadSiblings: []int{0, 2}, CoreSiblings: []int{0, 1, 2, 3}},
+	}
+	if !reflect.DeepEqual(topo, want) {
+		t.Errorf("got %+v, want %+v", topo, want)
+	}
+}
@@ -0,0 +1,50 @@
+package cpuproc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+)
+
+// enableBootTimeCache is used by BootTime and the process create times
+var enableBootTimeCache atomic.Bool
+
+// EnableBootTimeCache makes BootTime read the boot time only once, it never
+// changes unless the clock is stepped (e.g. by NTP after a suspend).
+func EnableBootTimeCache(enable bool) {
+	enableBootTimeCache.Store(enable)
+}
+
+// BootTime returns the system boot time in seconds since the epoch.
+func BootTime() (uint64, error) {
+	return BootTimeWithContext(context.Background(), enableBootTimeCache.Load())
+}
+
+// Uptime returns the number of seconds since the system booted.
+func Uptime() (uint64, error) {
+	return UptimeWithContext(context.Background())
+}
+
+func UptimeWithContext(ctx context.Context) (uint64, error) {
+	line, err := readTrimmed(HostProcWithContext(ctx, "uptime"))
+	if err != nil {
+		var info syscall.Sysinfo_t
+		if err := syscall.Sysinfo(&info); err != nil {
+			return 0, err
+		}
+		return uint64(info.Uptime), nil
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("wrong uptime format")
+	}
+	uptime, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(uptime), nil
+}
@@ -0,0 +1,78 @@
+package cpuproc
+
+import (
+	"context"
+	"strings"
+)
+
+// virtualization systems, as reported in VirtualizationStat.System
+const (
+	VirtualizationKVM         = "kvm"
+	VirtualizationXen         = "xen"
+	VirtualizationVMware      = "vmware"
+	VirtualizationVirtualBox  = "vbox"
+	VirtualizationHyperV      = "hyperv"
+	VirtualizationFirecracker = "firecracker"
+	VirtualizationWSL         = "wsl"
+	VirtualizationOpenVZ      = "openvz"
+	VirtualizationDocker      = "docker"
+	VirtualizationPodman      = "podman"
+	VirtualizationLXC         = "lxc"
+	VirtualizationNspawn      = "systemd-nspawn"
+	VirtualizationKubernetes  = "kubernetes"
+)
+
+// virtualization roles
+const (
+	VirtualizationRoleGuest = "guest"
+	VirtualizationRoleHost  = "host"
+)
+
+// VirtualizationStat tells whether the machine is virtualized or the process runs in a
+// container. System is empty on bare metal. Kubernetes is set for pods whatever the
+// container runtime, since System then names the runtime when it is known.
+type VirtualizationStat struct {
+	System     string `json:"system"`
+	Role       string `json:"role"`
+	Container  bool   `json:"container"`
+	Kubernetes bool   `json:"kubernetes"`
+}
+
+// DetectVirtualization is the typed form of VirtualizationWithContext, it recognizes
+// hypervisors from dmi, WSL, Firecracker and the container runtimes from the cgroup paths,
+// /.dockerenv, /run/.containerenv and /run/systemd/container.
+func DetectVirtualization() (*VirtualizationStat, error) {
+	return DetectVirtualizationWithContext(context.Background())
+}
+
+func DetectVirtualizationWithContext(ctx context.Context) (*VirtualizationStat, error) {
+	system, role, err := VirtualizationWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &VirtualizationStat{System: system, Role: role}
+	switch system {
+	case VirtualizationDocker, VirtualizationPodman, VirtualizationLXC, VirtualizationNspawn,
+		VirtualizationKubernetes, VirtualizationOpenVZ, "rkt", "linux-vserver":
+		ret.Container = role == VirtualizationRoleGuest
+	}
+
+	if paths, err := readProcCgroup(ctx, "self"); err == nil {
+		for _, p := range paths {
+			for _, name := range strings.Split(p, "/") {
+				if _, ok := parsePodCgroup(name); ok && strings.Contains(p, "kubepods") {
+					ret.Kubernetes = true
+				}
+			}
+		}
+	}
+	// the service account and the service env are there even with a cgroup namespace
+	if GetEnvWithContext(ctx, "KUBERNETES_SERVICE_HOST", "") != "" || PathExists(HostRootWithContext(ctx, "var/run/secrets/kubernetes.io")) {
+		ret.Kubernetes = true
+	}
+	if ret.Kubernetes {
+		ret.Container = true
+	}
+	return ret, nil
+}
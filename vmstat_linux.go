@@ -0,0 +1,89 @@
+package cpuproc
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VmstatStat contains the counters of linux /proc/vmstat keyed by name,
+// e.g. pgfault, pgmajfault, pswpin, pswpout.
+type VmstatStat map[string]uint64
+
+// AllocStall returns the direct reclaim stalls summed over all the zones,
+// newer kernels split allocstall into allocstall_dma, allocstall_normal...
+func (v VmstatStat) AllocStall() uint64 {
+	var total uint64
+	for name, value := range v {
+		if name == "allocstall" || strings.HasPrefix(name, "allocstall_") {
+			total += value
+		}
+	}
+	return total
+}
+
+func Vmstat() (VmstatStat, error) {
+	return VmstatWithContext(context.Background())
+}
+
+func VmstatWithContext(ctx context.Context) (VmstatStat, error) {
+	lines, err := ReadLines(HostProcWithContext(ctx, "vmstat"))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(VmstatStat, len(lines))
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		ret[fields[0]] = v
+	}
+	return ret, nil
+}
+
+// VmstatDelta returns the increase of the counters between two samples, counters that
+// went backwards count as 0.
+func VmstatDelta(before, after VmstatStat) VmstatStat {
+	ret := make(VmstatStat, len(after))
+	for name, value := range after {
+		ret[name] = counterDelta(before[name], value)
+	}
+	return ret
+}
+
+func VmstatRate(interval time.Duration) (map[string]float64, error) {
+	return VmstatRateWithContext(context.Background(), interval)
+}
+
+// VmstatRateWithContext returns the per second rates of the /proc/vmstat counters over interval.
+// Gauges such as nr_free_pages are included but their rates are meaningless.
+func VmstatRateWithContext(ctx context.Context, interval time.Duration) (map[string]float64, error) {
+	v1, err := VmstatWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	if err := Sleep(ctx, interval); err != nil {
+		return nil, err
+	}
+
+	v2, err := VmstatWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start).Seconds()
+
+	ret := make(map[string]float64, len(v2))
+	for name, delta := range VmstatDelta(v1, v2) {
+		ret[name] = float64(delta) / elapsed
+	}
+	return ret, nil
+}
@@ -0,0 +1,25 @@
+package cpuproc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_VmstatDelta(t *testing.T) {
+	ctx := newTestContext(t, map[string]string{
+		"proc/vmstat": "pgfault 100\npgmajfault 7\nallocstall_normal 2\nallocstall_movable 1\nbroken\nnr_free_pages x\n",
+	})
+	before, err := VmstatWithContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(before) != 4 || before.AllocStall() != 3 {
+		t.Errorf("unexpected vmstat %v", before)
+	}
+
+	after := VmstatStat{"pgfault": 150, "pgmajfault": 7, "allocstall_normal": 1, "pswpin": 4}
+	want := VmstatStat{"pgfault": 50, "pgmajfault": 0, "allocstall_normal": 0, "pswpin": 4}
+	if got := VmstatDelta(before, after); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,72 @@
+package cpuproc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	VulnerabilityNotAffected = "not affected"
+	VulnerabilityVulnerable  = "vulnerable"
+	VulnerabilityMitigated   = "mitigated"
+	VulnerabilityUnknown     = "unknown"
+)
+
+// VulnerabilityStat describes how the kernel handles a cpu vulnerability.
+// Status is one of the Vulnerability* constants, Mitigation holds the rest of the
+// kernel message ("Enhanced IBRS; IBPB: conditional" ...) and Raw the whole of it.
+// It is based on linux /sys/devices/system/cpu/vulnerabilities.
+type VulnerabilityStat struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Mitigation string `json:"mitigation"`
+	Raw        string `json:"raw"`
+}
+
+func Vulnerabilities() ([]VulnerabilityStat, error) {
+	return VulnerabilitiesWithContext(context.Background())
+}
+
+// VulnerabilitiesWithContext returns the known vulnerabilities sorted by name.
+func VulnerabilitiesWithContext(ctx context.Context) ([]VulnerabilityStat, error) {
+	dir := HostSysWithContext(ctx, "devices/system/cpu/vulnerabilities")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]VulnerabilityStat, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		raw, err := readTrimmed(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		v := VulnerabilityStat{Name: e.Name(), Raw: raw}
+		v.Status, v.Mitigation = parseVulnerability(raw)
+		ret = append(ret, v)
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret, nil
+}
+
+func parseVulnerability(raw string) (status string, mitigation string) {
+	switch {
+	case raw == "Not affected":
+		return VulnerabilityNotAffected, ""
+	case strings.HasPrefix(raw, "Mitigation:"):
+		return VulnerabilityMitigated, strings.TrimSpace(strings.TrimPrefix(raw, "Mitigation:"))
+	case strings.HasPrefix(raw, "Vulnerable"):
+		// e.g. "Vulnerable: Clear CPU buffers attempted, no microcode"
+		detail := strings.TrimPrefix(raw, "Vulnerable")
+		return VulnerabilityVulnerable, strings.TrimSpace(strings.TrimLeft(detail, ":;"))
+	default:
+		return VulnerabilityUnknown, strings.TrimSpace(strings.TrimPrefix(raw, "Unknown:"))
+	}
+}